@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"time"
 
+	"enerzyflow_backend/internal/auth"
+	"enerzyflow_backend/internal/container"
 	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/events"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/notifications"
+	"enerzyflow_backend/internal/outbox"
+	"enerzyflow_backend/internal/webhooks"
 	"enerzyflow_backend/routes"
+	"enerzyflow_backend/utils"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -18,8 +28,49 @@ func main() {
 	if err != nil {
 		log.Println("Warning: .env file not found, falling back to system env")
 	}
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		db.Connect(os.Getenv("DB_URL"))
+		if err := db.RunMigrateCommand(os.Args[2:], os.Stdout); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+
+	keys, err := utils.LoadKeySetFromEnv()
+	if err != nil {
+		log.Fatalf("failed to load JWT signing keys: %v", err)
+	}
+	utils.SetKeySet(keys)
+
+	m, err := mailer.FromEnv()
+	if err != nil {
+		log.Fatalf("failed to configure mailer: %v", err)
+	}
+	mailer.SetDefault(m)
+
 	db.Connect(os.Getenv("DB_URL"))
-	// db.Migrate()
+
+	if os.Getenv("MIGRATE_ON_START") == "true" {
+		if err := db.Migrate(); err != nil {
+			log.Fatalf("failed to apply migrations: %v", err)
+		}
+	}
+
+	app := container.New(db.DB)
+
+	auth.SetUserRepository(app.UserRepo)
+	auth.SetOTPStore(auth.NewOTPStoreFromEnv(db.DB))
+	auth.SetThrottleStore(auth.NewThrottleStoreFromEnv(db.DB))
+	auth.StartOTPCleanup(context.Background(), 10*time.Minute)
+	utils.SetSessionValidator(auth.IsSessionActive)
+	events.SetBus(events.NewBusFromEnv(db.DB))
+	events.Start(context.Background())
+	outbox.StartDispatcher(context.Background(), 5*time.Second)
+	notifications.Register()
+	webhooks.Register()
+	webhooks.StartDispatcher(context.Background(), 5*time.Second)
+	app.Orders.StartSLAMonitor(context.Background(), time.Minute)
 
     r := gin.Default()
 
@@ -29,7 +80,7 @@ func main() {
     config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
     r.Use(cors.New(config))
 
-    routes.RegisterAllRoutes(r)
+    routes.RegisterAllRoutes(r, app)
 
     if err := r.Run(":9080"); err != nil {
         log.Fatalf("failed to start server: %v", err)
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"enerzyflow_backend/internal/users"
+)
+
+func runAdmin(args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: admin create|promote <email>")
+	}
+
+	var (
+		u   *users.User
+		err error
+	)
+	switch args[0] {
+	case "create":
+		u, err = app.Users.CreateAdminService(args[1])
+	case "promote":
+		u, err = app.Users.PromoteToAdminService(args[1])
+	default:
+		return fmt.Errorf("unknown admin command %q", args[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s is an admin (user_id %s)\n", u.Email, u.UserID)
+	return nil
+}
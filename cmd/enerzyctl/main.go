@@ -0,0 +1,87 @@
+// Command enerzyctl runs operational tasks against the EnerzyFlow database:
+// bootstrapping admins, migrations, seeding, OTP re-sends, forced order
+// status changes and order exports.
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"enerzyflow_backend/internal/auth"
+	"enerzyflow_backend/internal/container"
+	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/events"
+	"enerzyflow_backend/internal/notifications"
+	"enerzyflow_backend/internal/webhooks"
+
+	"github.com/joho/godotenv"
+)
+
+const usage = `usage: enerzyctl <command> [arguments]
+
+commands:
+  admin create <email>                  create a new admin user
+  admin promote <email>                 give an existing user the admin role
+  migrate up | down [N] | status        manage the database schema
+  seed [-file seed.json]                insert reference data (idempotent)
+  otp resend [-locale hi] <email>       send a fresh login OTP
+  order force-status -as <admin email> -reason <text> [-assign <email>] <order_id> <status>
+                                        set an order status, bypassing workflow checks
+  orders export [-format csv|json] [-status s] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-out file]
+                                        export orders (admin view)
+`
+
+// app holds the repositories and services built from the database connection.
+var app *container.Container
+
+func main() {
+	log.SetFlags(0)
+	if err := godotenv.Load(".env"); err != nil {
+		log.Println("warning: .env file not found, falling back to system env")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	if cmd == "help" || cmd == "-h" || cmd == "--help" {
+		fmt.Print(usage)
+		return
+	}
+
+	db.Connect(os.Getenv("DB_URL"))
+	app = container.New(db.DB)
+	// Order changes made here must reach notifications, webhooks and the
+	// running servers' SSE streams just like changes made over HTTP.
+	events.SetBus(events.NewBusFromEnv(db.DB))
+	notifications.Register()
+	webhooks.Register()
+	auth.SetUserRepository(app.UserRepo)
+	auth.SetOTPStore(auth.NewOTPStoreFromEnv(db.DB))
+	auth.SetThrottleStore(auth.NewThrottleStoreFromEnv(db.DB))
+
+	var err error
+	switch cmd {
+	case "admin":
+		err = runAdmin(args)
+	case "migrate":
+		err = db.RunMigrateCommand(args, os.Stdout)
+	case "seed":
+		err = runSeed(args)
+	case "otp":
+		err = runOTP(args)
+	case "order":
+		err = runOrder(args)
+	case "orders":
+		err = runOrders(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatalf("enerzyctl %s: %v", cmd, err)
+	}
+}
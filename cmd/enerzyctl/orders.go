@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"enerzyflow_backend/internal/orders"
+)
+
+const exportPageSize = 500
+
+func runOrder(args []string) error {
+	if len(args) == 0 || args[0] != "force-status" {
+		return errors.New("usage: order force-status -as <admin email> -reason <text> [-assign <email>] <order_id> <status>")
+	}
+
+	fs := flag.NewFlagSet("order force-status", flag.ContinueOnError)
+	as := fs.String("as", "", "email of the admin responsible for the change")
+	reason := fs.String("reason", "", "why the status is being forced (recorded in the order history)")
+	assign := fs.String("assign", "", "email of the printing or plant user to assign when forcing into printing or plant_processing")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 || *as == "" {
+		return errors.New("usage: order force-status -as <admin email> -reason <text> [-assign <email>] <order_id> <status>")
+	}
+	orderID, status := fs.Arg(0), fs.Arg(1)
+
+	admin, err := app.UserRepo.GetUserByEmail(*as)
+	if err != nil {
+		return err
+	}
+	if admin == nil || admin.Role != "admin" {
+		return fmt.Errorf("%s is not an admin", *as)
+	}
+
+	assigneeID := ""
+	if *assign != "" {
+		assignee, err := app.UserRepo.GetUserByEmail(*assign)
+		if err != nil {
+			return err
+		}
+		if assignee == nil {
+			return fmt.Errorf("no user with email %s", *assign)
+		}
+		assigneeID = assignee.UserID
+	}
+
+	if err := app.Orders.ForceOrderStatusService(orderID, status, admin.UserID, *reason, assigneeID); err != nil {
+		return err
+	}
+
+	fmt.Printf("order %s is now %s\n", orderID, status)
+	return nil
+}
+
+func runOrders(args []string) error {
+	if len(args) == 0 || args[0] != "export" {
+		return errors.New("usage: orders export [-format csv|json] [-status s] [-from YYYY-MM-DD] [-to YYYY-MM-DD] [-out file]")
+	}
+
+	fs := flag.NewFlagSet("orders export", flag.ContinueOnError)
+	format := fs.String("format", "csv", "csv or json")
+	status := fs.String("status", "", "only orders in this status")
+	from := fs.String("from", "", "only orders created on or after this date")
+	to := fs.String("to", "", "only orders created on or before this date")
+	out := fs.String("out", "", "output file (default stdout)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *format != "csv" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	var fromTime, toTime time.Time
+	var err error
+	if *from != "" {
+		if fromTime, err = time.Parse("2006-01-02", *from); err != nil {
+			return fmt.Errorf("invalid -from: %w", err)
+		}
+	}
+	if *to != "" {
+		if toTime, err = time.Parse("2006-01-02", *to); err != nil {
+			return fmt.Errorf("invalid -to: %w", err)
+		}
+		toTime = toTime.Add(24 * time.Hour)
+	}
+
+	var selected []orders.AllOrderModel
+	for offset := 0; ; offset += exportPageSize {
+		page, total, err := app.Orders.GetAllOrdersService("admin", exportPageSize, offset, "")
+		if err != nil {
+			return err
+		}
+		for _, o := range page {
+			if *status != "" && o.Status != *status {
+				continue
+			}
+			if !fromTime.IsZero() && o.CreatedAt.Before(fromTime) {
+				continue
+			}
+			if !toTime.IsZero() && !o.CreatedAt.Before(toTime) {
+				continue
+			}
+			selected = append(selected, o)
+		}
+		if offset+len(page) >= total || len(page) == 0 {
+			break
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(selected)
+	} else {
+		err = writeOrdersCSV(w, selected)
+	}
+	if err != nil {
+		return err
+	}
+
+	if *out != "" {
+		fmt.Fprintf(os.Stderr, "exported %d orders to %s\n", len(selected), *out)
+	}
+	return nil
+}
+
+func writeOrdersCSV(w io.Writer, list []orders.AllOrderModel) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"order_id", "company_name", "user_name", "variant", "qty", "cap_color", "volume", "sku_id",
+		"status", "payment_status", "decline_reason", "invoice_url", "expected_delivery", "created_at", "updated_at",
+	}); err != nil {
+		return err
+	}
+	for _, o := range list {
+		if err := cw.Write([]string{
+			o.OrderID, o.CompanyName, o.UserName, o.Variant, strconv.Itoa(o.Qty), o.CapColor, strconv.Itoa(o.Volume), o.SKUID,
+			o.Status, o.PaymentStatus, o.DeclineReason, o.InvoiceUrl,
+			o.ExpectedDelivery.Format(time.RFC3339), o.CreatedAt.Format(time.RFC3339), o.UpdatedAt.Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"enerzyflow_backend/internal/auth"
+)
+
+func runOTP(args []string) error {
+	if len(args) == 0 || args[0] != "resend" {
+		return errors.New("usage: otp resend [-locale hi] <email>")
+	}
+
+	fs := flag.NewFlagSet("otp resend", flag.ContinueOnError)
+	locale := fs.String("locale", "", "email locale (default en)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: otp resend [-locale hi] <email>")
+	}
+	email := fs.Arg(0)
+
+	// Throttling still applies; the CLI counts as a single client.
+	if err := auth.SendLoginOTP(email, "enerzyctl", *locale); err != nil {
+		return err
+	}
+
+	fmt.Printf("OTP queued for %s; the server's outbox dispatcher will deliver it\n", email)
+	return nil
+}
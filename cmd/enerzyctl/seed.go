@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"enerzyflow_backend/internal/companies"
+	"enerzyflow_backend/internal/users"
+
+	"github.com/google/uuid"
+)
+
+//go:embed seed.json
+var defaultSeed []byte
+
+type seedData struct {
+	Users []struct {
+		Email string `json:"email"`
+		Role  string `json:"role"`
+	} `json:"users"`
+	Companies []struct {
+		OwnerEmail string            `json:"owner_email"`
+		OwnerName  string            `json:"owner_name"`
+		CompanyID  string            `json:"company_id"`
+		Name       string            `json:"name"`
+		Address    string            `json:"address"`
+		Labels     []companies.Label `json:"labels"`
+	} `json:"companies"`
+}
+
+// runSeed inserts the users, companies and labels from the seed file.
+// Existing users are left as they are, so it is safe to run repeatedly.
+func runSeed(args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	file := fs.String("file", "", "seed file (default: built-in reference data)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	raw := defaultSeed
+	if *file != "" {
+		b, err := os.ReadFile(*file)
+		if err != nil {
+			return err
+		}
+		raw = b
+	}
+
+	var data seedData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return fmt.Errorf("parse seed: %w", err)
+	}
+
+	for _, su := range data.Users {
+		if _, err := ensureUser(su.Email, su.Role); err != nil {
+			return err
+		}
+	}
+
+	for _, sc := range data.Companies {
+		owner, err := ensureUser(sc.OwnerEmail, "business_owner")
+		if err != nil {
+			return err
+		}
+		if owner.Role != "business_owner" {
+			return fmt.Errorf("%s is a %s, not a business owner", owner.Email, owner.Role)
+		}
+		if owner.Name == "" && sc.OwnerName != "" {
+			owner.Name = sc.OwnerName
+		}
+
+		companyID := sc.CompanyID
+		if existing, err := app.CompanyRepo.GetCompanyByUserID(owner.UserID); err != nil {
+			return err
+		} else if existing != nil {
+			companyID = existing.CompanyID
+		} else if companyID == "" {
+			companyID = uuid.New().String()
+		}
+
+		labels := make([]companies.Label, 0, len(sc.Labels))
+		for _, l := range sc.Labels {
+			if l.LabelID == "" {
+				return fmt.Errorf("company %s: every seeded label needs a label_id", sc.Name)
+			}
+			l.CompanyID = companyID
+			labels = append(labels, l)
+		}
+
+		if err := seedCompany(owner, &companies.Company{
+			CompanyID: companyID,
+			UserID:    owner.UserID,
+			Name:      sc.Name,
+			Address:   sc.Address,
+		}, labels); err != nil {
+			return fmt.Errorf("company %s: %w", sc.Name, err)
+		}
+		fmt.Printf("seeded company %s (%d labels)\n", sc.Name, len(labels))
+	}
+	return nil
+}
+
+func ensureUser(email, role string) (*users.User, error) {
+	if email == "" {
+		return nil, errors.New("seed user without email")
+	}
+	u, err := app.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if u != nil {
+		return u, nil
+	}
+
+	u = &users.User{UserID: uuid.New().String(), Email: email, Role: role}
+	if err := app.UserRepo.InsertUser(u); err != nil {
+		return nil, err
+	}
+	fmt.Printf("created %s user %s\n", role, email)
+	return u, nil
+}
+
+func seedCompany(owner *users.User, c *companies.Company, labels []companies.Label) error {
+	return app.UserRepo.WithTx(func(tx *sql.Tx) error {
+		if err := app.UserRepo.UpdateUserProfileTx(tx, owner); err != nil {
+			return err
+		}
+		if err := app.CompanyRepo.UpsertCompanyTx(tx, c); err != nil {
+			return err
+		}
+		_, err := app.Companies.SaveCompanyLabelsService(tx, c.CompanyID, labels)
+		return err
+	})
+}
@@ -1,11 +1,12 @@
 package auth
 
 import (
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"enerzyflow_backend/internal/users"
-	"enerzyflow_backend/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -19,21 +20,11 @@ func SendOTPHandler(c *gin.Context) {
 		return
 	}
 
-	var role string
-	u, err := users.GetUserByEmail(req.Email)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
-		return
-	}
-
-	if u != nil {
-		role = u.Role
-	} else {
-		role = "business_owner"
-	}
-
-	_, err = SendOTP(req.Email, role)
+	err := SendLoginOTP(req.Email, c.ClientIP(), req.Locale)
 	if err != nil {
+		if respondOTPError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to send OTP"})
 		return
 	}
@@ -48,7 +39,7 @@ func VerifyOTPHandler(c *gin.Context) {
 		return
 	}
 
-	u, err := users.GetUserByEmail(req.Email)
+	u, err := userRepo.GetUserByEmail(req.Email)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "db error"})
 		return
@@ -60,18 +51,20 @@ func VerifyOTPHandler(c *gin.Context) {
 	} else {
 		role = "business_owner"
 	}
-	fmt.Println("Role:", role)
-	valid, expired, err := VerifyOTP(req.Email, role, req.OTP)
+	valid, expired, err := VerifyOTP(req.Email, role, req.OTP, c.ClientIP())
 	if err != nil {
+		if respondOTPError(c, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "verification failed"})
 		return
 	}
 	if !valid {
 		if expired {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "OTP expired"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": "OTP expired", "code": ErrCodeOTPExpired})
 			return
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid OTP"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid OTP", "code": ErrCodeOTPInvalid})
 		return
 	}
 
@@ -81,7 +74,7 @@ func VerifyOTPHandler(c *gin.Context) {
 			Email:  req.Email,
 			Role:   role,
 		}
-		if err := users.InsertUser(newUser); err != nil {
+		if err := userRepo.InsertUser(newUser); err != nil {
 			if pgErr, ok := err.(*pgconn.PgError); ok && pgErr.Code == "23514" {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role value: role must be one of the allowed values"})
 				return
@@ -92,21 +85,16 @@ func VerifyOTPHandler(c *gin.Context) {
 		u = newUser
 	}
 
-	userUUID, err := uuid.Parse(u.UserID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
-		return
-	}
-
-	accessToken, err := utils.GenerateTokens(u.Email, userUUID, u.Role)
+	tokens, err := StartSession(u, c.Request.UserAgent(), c.ClientIP())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "OTP verified successfully",
-		"accessToken": accessToken,
+		"message":      "OTP verified successfully",
+		"accessToken":  tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 		"user": gin.H{
 			"user_id":     u.UserID,
 			"email":       u.Email,
@@ -116,4 +104,21 @@ func VerifyOTPHandler(c *gin.Context) {
 			"role":        u.Role,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// respondOTPError writes throttling errors as 429 with a Retry-After header.
+// It reports false when err is not an *OTPError.
+func respondOTPError(c *gin.Context, err error) bool {
+	var otpErr *OTPError
+	if !errors.As(err, &otpErr) {
+		return false
+	}
+	retryAfter := int(math.Ceil(otpErr.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(otpErr.HTTPStatus(), gin.H{
+		"error":       otpErr.Message,
+		"code":        otpErr.Code,
+		"retry_after": retryAfter,
+	})
+	return true
+}
@@ -1,7 +1,8 @@
 package auth
 
 type SendOTPRequest struct {
-	Email string `json:"email"`
+	Email  string `json:"email"`
+	Locale string `json:"locale"`
 }
 
 type VerifyOTPRequest struct {
@@ -1,159 +1,135 @@
 package auth
 
 import (
-	"fmt"
 	"log"
-	"math/rand"
-	"net/smtp"
-	"os"
-	"sync"
 	"time"
 
-	"github.com/resendlabs/resend-go"
-
-	"github.com/sendgrid/sendgrid-go"
-	"github.com/sendgrid/sendgrid-go/helpers/mail"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/users"
 )
 
-type OTPEntry struct {
-	Code      string
-	ExpiresAt time.Time
-}
-
-var otpStore = struct {
-	sync.RWMutex
-	m map[string]OTPEntry
-}{m: make(map[string]OTPEntry)}
-
-type smtpCred struct {
-	From     string
-	Password string
-	Host     string
-	Port     string
-}
-
-func getSMTPCred() smtpCred {
-    return smtpCred{
-        From:     os.Getenv("SMTP_FROM"),
-        Password: os.Getenv("SMTP_PASSWORD"),
-        Host:     os.Getenv("SMTP_HOST"),
-        Port:     os.Getenv("SMTP_PORT"),
-    }
+// sendOTPEmail sends the code straight through the mailer. It never goes
+// through the outbox, so the plaintext code is not stored anywhere; if the
+// send fails the user asks for a new code.
+func sendOTPEmail(toEmail, otp, locale string, ttl time.Duration) error {
+	msg, err := mailer.Render(mailer.TemplateOTP, locale, mailer.OTPData{
+		Code:       otp,
+		TTLMinutes: int(ttl.Minutes()),
+	})
+	if err != nil {
+		return err
+	}
+	msg.To = []string{toEmail}
+	msg.Sensitive = true
+	return mailer.Send(msg)
 }
 
+// userRepo resolves accounts during login and refresh; app.go wires the
+// Postgres repository, the in-memory default keeps tests self-contained.
+var userRepo users.UserRepository = users.NewMemoryUserRepository()
 
-
-const emailOtpTTL = 5 * time.Minute
-
-func generateEmailOTP() string {
-	rand.Seed(time.Now().UnixNano())
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+func SetUserRepository(r users.UserRepository) {
+	userRepo = r
 }
 
-func sendEmailWithCustomSMTP(to string, otp string) error {
-	cred := getSMTPCred()
-	if cred.From == "" || cred.Password == "" {
-        log.Fatal("SMTP credentials not set in environment")
-    }
-
-	subject := "Verify Your OTP"
-	body := fmt.Sprintf("Your OTP is: %s", otp)
-	msg := []byte(fmt.Sprintf(
-		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=\"UTF-8\"\r\n\r\n%s",
-		cred.From, to, subject, body,
-	))
-
-	auth := smtp.PlainAuth("", cred.From, cred.Password, cred.Host)
-	return smtp.SendMail(cred.Host+":"+cred.Port, auth, cred.From, []string{to}, msg)
-}
-
-func sendEmailWithSendGrid(toEmail, otp string) error {
-    from := mail.NewEmail("EnerzyFlow", os.Getenv("SENDGRID_FROM"))
-    to := mail.NewEmail("", toEmail)
-    subject := "Verify Your OTP"
-    plainTextContent := fmt.Sprintf("Your OTP is: %s", otp)
-    htmlContent := fmt.Sprintf("<p>Your OTP is: <b>%s</b></p>", otp)
-
-    message := mail.NewSingleEmail(from, subject, to, plainTextContent, htmlContent)
-    client := sendgrid.NewSendClient(os.Getenv("SENDGRID_API_KEY"))
-    response, err := client.Send(message)
-    if err != nil {
-        return err
-    }
-
-    if response.StatusCode >= 400 {
-        return fmt.Errorf("sendgrid error: status %d, body: %s", response.StatusCode, response.Body)
-    }
-
-    return nil
+func keyForOTP(email, role string) string {
+	return email + "|" + role
 }
 
-func sendEmailWithResend(toEmail, otp string) error {
-	client := resend.NewClient(os.Getenv("RESEND_API_KEY"))
-
-	params := &resend.SendEmailRequest{
-		From:    "EnerzyFlow <no-reply@enerzyflow.com>",
-		To:      []string{toEmail},
-		Subject: "Verify Your OTP",
-		Html:    fmt.Sprintf("<p>Your OTP is: <b>%s</b></p>", otp),
-		Text:    fmt.Sprintf("Your OTP is: %s", otp),
-	}
-
-	_, err := client.Emails.Send(params)
+// SendLoginOTP sends an OTP for the role the email is registered with; new
+// emails sign up as business owners.
+func SendLoginOTP(email, ip, locale string) error {
+	role := "business_owner"
+	u, err := userRepo.GetUserByEmail(email)
 	if err != nil {
 		return err
 	}
+	if u != nil {
+		role = u.Role
+	}
 
-	return nil
+	_, err = SendOTP(email, role, ip, locale)
+	return err
 }
 
-func keyForOTP(email, role string) string {
-	return email + "|" + role
-}
+func SendOTP(email, role, ip, locale string) (string, error) {
+	if err := reserveSend(email, ip); err != nil {
+		return "", err
+	}
 
+	policy := otpPolicyForRole(role)
+	otp, err := generateEmailOTP(policy.Length)
+	if err != nil {
+		return "", err
+	}
+	salt, err := newOTPSalt()
+	if err != nil {
+		return "", err
+	}
 
-func SendOTP(email, role string) (string, error) {
-	otp := generateEmailOTP()
-	if err := sendEmailWithResend(email, otp); err != nil {
-		log.Printf("Failed to send email to %s: %v", email, err)
+	otpKey := keyForOTP(email, role)
+	entry := OTPEntry{Salt: salt, CodeHash: hashOTP(salt, otp), ExpiresAt: time.Now().Add(policy.TTL)}
+	if err := otpStore.Save(otpKey, entry); err != nil {
 		return "", err
 	}
 
-	otpStore.Lock()
-	otpStore.m[keyForOTP(email, role)] = OTPEntry{Code: otp, ExpiresAt: time.Now().Add(emailOtpTTL)}
-	otpStore.Unlock()
+	if err := sendOTPEmail(email, otp, locale, policy.TTL); err != nil {
+		log.Printf("Failed to send OTP email to %s: %v", email, err)
+		// the user never received this code, so it must not stay valid
+		if err := otpStore.Delete(otpKey); err != nil {
+			log.Printf("Failed to discard unsent OTP for %s: %v", email, err)
+		}
+		return "", err
+	}
 
 	return otp, nil
 }
 
-// VerifyOTP checks OTP for given email + role and TTL
-func VerifyOTP(email, role, otp string) (valid bool, expired bool, err error) {
-	otpKey := keyForOTP(email, role)
+// VerifyOTP checks OTP for given email + role and TTL. Failed attempts are
+// counted per email and per IP; once a limit is hit an *OTPError is returned.
+func VerifyOTP(email, role, otp, ip string) (valid bool, expired bool, err error) {
+	if err := checkLocked(verifyEmailKey(email), verifyIPKey(ip)); err != nil {
+		return false, false, err
+	}
 
-	otpStore.RLock()
-	entry, exists := otpStore.m[otpKey]
-	otpStore.RUnlock()
+	otpKey := keyForOTP(email, role)
 
-	if !exists {
-		return false, false, nil
+	entry, err := otpStore.Get(otpKey)
+	if err != nil {
+		return false, false, err
+	}
+	if entry == nil {
+		return false, false, recordVerifyFailure(email, ip)
 	}
 
 	if time.Now().After(entry.ExpiresAt) {
-		otpStore.Lock()
-		delete(otpStore.m, otpKey)
-		otpStore.Unlock()
-		return false, true, nil
+		if err := otpStore.Delete(otpKey); err != nil {
+			return false, false, err
+		}
+		return false, true, recordVerifyFailure(email, ip)
 	}
 
-	if entry.Code != otp {
+	if !otpMatches(entry, otp) {
+		if err := recordVerifyFailure(email, ip); err != nil {
+			// a locked-out email must request a fresh code
+			_ = otpStore.Delete(otpKey)
+			return false, false, err
+		}
 		return false, false, nil
 	}
 
-	// OTP is valid; delete after verification
-	otpStore.Lock()
-	delete(otpStore.m, otpKey)
-	otpStore.Unlock()
+	// OTP is valid; consume it atomically so a concurrent verify of the
+	// same code cannot also succeed
+	consumed, err := otpStore.Consume(otpKey, entry.CodeHash, time.Now())
+	if err != nil {
+		return false, false, err
+	}
+	if !consumed {
+		return false, false, nil
+	}
+	if err := clearVerifyFailures(email); err != nil {
+		return false, false, err
+	}
 
 	return true, false, nil
 }
-
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/mailer"
+)
+
+func saveTestOTP(t *testing.T, email, role, otp string, ttl time.Duration) {
+	t.Helper()
+	entry := OTPEntry{Salt: "salt", CodeHash: hashOTP("salt", otp), ExpiresAt: time.Now().Add(ttl)}
+	if err := otpStore.Save(keyForOTP(email, role), entry); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// barrierOTPStore holds every Get until n callers have read the entry, so
+// they all race to use the same code.
+type barrierOTPStore struct {
+	*MemoryOTPStore
+	readers sync.WaitGroup
+}
+
+func (s *barrierOTPStore) Get(key string) (*OTPEntry, error) {
+	entry, err := s.MemoryOTPStore.Get(key)
+	s.readers.Done()
+	s.readers.Wait()
+	return entry, err
+}
+
+func TestVerifyOTPConsumesCodeOnce(t *testing.T) {
+	const callers = 5
+	store := &barrierOTPStore{MemoryOTPStore: NewMemoryOTPStore()}
+	store.readers.Add(callers)
+	SetOTPStore(store)
+	SetThrottleStore(NewMemoryThrottleStore())
+	saveTestOTP(t, "a@example.com", "admin", "123456", time.Minute)
+
+	var wg sync.WaitGroup
+	var successes atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			valid, _, err := VerifyOTP("a@example.com", "admin", "123456", "10.0.0.1")
+			if err != nil {
+				t.Error(err)
+			}
+			if valid {
+				successes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := successes.Load(); n != 1 {
+		t.Fatalf("%d concurrent verifies succeeded, want 1", n)
+	}
+}
+
+func TestVerifyOTP(t *testing.T) {
+	SetOTPStore(NewMemoryOTPStore())
+	SetThrottleStore(NewMemoryThrottleStore())
+
+	saveTestOTP(t, "b@example.com", "plant", "111111", time.Minute)
+	if valid, expired, err := VerifyOTP("b@example.com", "plant", "222222", "10.0.0.2"); valid || expired || err != nil {
+		t.Fatalf("wrong code: valid=%v expired=%v err=%v", valid, expired, err)
+	}
+	if valid, _, err := VerifyOTP("b@example.com", "plant", "111111", "10.0.0.2"); !valid || err != nil {
+		t.Fatalf("a wrong guess used up the code: valid=%v err=%v", valid, err)
+	}
+	if valid, _, _ := VerifyOTP("b@example.com", "plant", "111111", "10.0.0.2"); valid {
+		t.Fatal("the code was accepted twice")
+	}
+
+	saveTestOTP(t, "c@example.com", "plant", "333333", -time.Second)
+	if valid, expired, _ := VerifyOTP("c@example.com", "plant", "333333", "10.0.0.3"); valid || !expired {
+		t.Fatalf("expired code: valid=%v expired=%v", valid, expired)
+	}
+}
+
+type failingMailer struct{}
+
+func (failingMailer) Send(mailer.Message) error { return errors.New("smtp down") }
+
+func TestSendOTPDiscardsUnsentCode(t *testing.T) {
+	SetOTPStore(NewMemoryOTPStore())
+	SetThrottleStore(NewMemoryThrottleStore())
+	mailer.SetDefault(failingMailer{})
+	t.Cleanup(func() { mailer.SetDefault(mailer.NewLogMailer()) })
+
+	if _, err := SendOTP("d@example.com", "plant", "10.0.0.4", "en"); err == nil {
+		t.Fatal("SendOTP succeeded with a failing mailer")
+	}
+	if entry, err := otpStore.Get(keyForOTP("d@example.com", "plant")); err != nil || entry != nil {
+		t.Fatalf("unsent code kept: entry=%+v err=%v", entry, err)
+	}
+}
+
+// slowMailer keeps each send in flight long enough for concurrent requests
+// to overlap.
+type slowMailer struct{}
+
+func (slowMailer) Send(mailer.Message) error {
+	time.Sleep(50 * time.Millisecond)
+	return nil
+}
+
+func TestSendOTPCooldownHoldsUnderConcurrency(t *testing.T) {
+	SetOTPStore(NewMemoryOTPStore())
+	SetThrottleStore(NewMemoryThrottleStore())
+	mailer.SetDefault(slowMailer{})
+	t.Cleanup(func() { mailer.SetDefault(mailer.NewLogMailer()) })
+
+	var wg sync.WaitGroup
+	var sent, cooledDown atomic.Int32
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := SendOTP("e@example.com", "plant", "10.0.0.5", "en")
+			var otpErr *OTPError
+			switch {
+			case err == nil:
+				sent.Add(1)
+			case errors.As(err, &otpErr) && otpErr.Code == ErrCodeOTPResendCooldown:
+				cooledDown.Add(1)
+			default:
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if sent.Load() != 1 || cooledDown.Load() != 4 {
+		t.Fatalf("sent %d and cooled down %d, want 1 and 4", sent.Load(), cooledDown.Load())
+	}
+}
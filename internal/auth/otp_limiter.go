@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	otpMaxVerifyFailuresPerEmail = 5
+	otpMaxVerifyFailuresPerIP    = 20
+	otpVerifyWindow              = 15 * time.Minute
+
+	otpResendCooldown    = 60 * time.Second
+	otpMaxSendsPerEmail  = 5
+	otpMaxSendsPerIP     = 20
+	otpSendWindow        = time.Hour
+	otpLockoutBase       = time.Minute
+	otpLockoutMax        = time.Hour
+	otpThrottleRetention = 24 * time.Hour
+)
+
+// Error codes returned to clients of /auth/send-otp and /auth/verify-otp.
+const (
+	ErrCodeOTPInvalid        = "otp_invalid"
+	ErrCodeOTPExpired        = "otp_expired"
+	ErrCodeOTPLocked         = "otp_locked"
+	ErrCodeOTPResendCooldown = "otp_resend_cooldown"
+	ErrCodeOTPSendLimit      = "otp_send_limit"
+)
+
+// OTPError is returned when a request is refused by the throttling policy.
+type OTPError struct {
+	Code       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *OTPError) Error() string {
+	return e.Message
+}
+
+func (e *OTPError) HTTPStatus() int {
+	return http.StatusTooManyRequests
+}
+
+// ThrottleLimit allows at most Max events for Key within Window.
+type ThrottleLimit struct {
+	Key    string
+	Window time.Duration
+	Max    int
+}
+
+// ThrottleStore counts OTP events and keeps lockouts so that limits hold
+// across restarts and backend instances.
+type ThrottleStore interface {
+	// Hit records an event for key and returns the number of events within window.
+	Hit(key string, window time.Duration) (int, error)
+	// TryHit checks limits and, when none is reached, records one event for
+	// each of their keys, as a single step that concurrent callers cannot
+	// interleave. It returns the index of the first limit reached, or -1
+	// once the events are recorded.
+	TryHit(limits ...ThrottleLimit) (int, error)
+	Count(key string, window time.Duration) (int, error)
+	Reset(key string) error
+	// Lock returns the current lockout for key; until is zero when not locked.
+	Lock(key string) (until time.Time, level int, err error)
+	SetLock(key string, until time.Time, level int) error
+	ClearLock(key string) error
+	Purge(before time.Time) error
+}
+
+var throttleStore ThrottleStore = NewMemoryThrottleStore()
+
+func SetThrottleStore(s ThrottleStore) {
+	throttleStore = s
+}
+
+// NewThrottleStoreFromEnv follows OTP_STORE so that codes and limits share a backend.
+func NewThrottleStoreFromEnv(conn *sql.DB) ThrottleStore {
+	if strings.EqualFold(os.Getenv("OTP_STORE"), "memory") {
+		return NewMemoryThrottleStore()
+	}
+	return NewPostgresThrottleStore(conn)
+}
+
+func verifyEmailKey(email string) string { return "otp:verify:email:" + strings.ToLower(email) }
+func verifyIPKey(ip string) string       { return "otp:verify:ip:" + ip }
+func sendEmailKey(email string) string   { return "otp:send:email:" + strings.ToLower(email) }
+func sendIPKey(ip string) string         { return "otp:send:ip:" + ip }
+
+func lockoutDuration(level int) time.Duration {
+	d := otpLockoutBase
+	for i := 0; i < level && d < otpLockoutMax; i++ {
+		d *= 2
+	}
+	if d > otpLockoutMax {
+		d = otpLockoutMax
+	}
+	return d
+}
+
+func checkLocked(keys ...string) error {
+	now := time.Now()
+	for _, k := range keys {
+		until, _, err := throttleStore.Lock(k)
+		if err != nil {
+			return err
+		}
+		if until.After(now) {
+			return &OTPError{
+				Code:       ErrCodeOTPLocked,
+				Message:    "too many failed attempts, try again later",
+				RetryAfter: until.Sub(now),
+			}
+		}
+	}
+	return nil
+}
+
+// lockOut applies the next exponential lockout step to key.
+func lockOut(key string) (time.Duration, error) {
+	_, level, err := throttleStore.Lock(key)
+	if err != nil {
+		return 0, err
+	}
+	d := lockoutDuration(level)
+	if err := throttleStore.SetLock(key, time.Now().Add(d), level+1); err != nil {
+		return 0, err
+	}
+	if err := throttleStore.Reset(key); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+// throttleKeys returns the distinct keys of limits in sorted order.
+func throttleKeys(limits []ThrottleLimit) []string {
+	seen := make(map[string]bool, len(limits))
+	var keys []string
+	for _, l := range limits {
+		if !seen[l.Key] {
+			seen[l.Key] = true
+			keys = append(keys, l.Key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reserveSend enforces the resend cooldown and the hourly send caps and
+// counts the send in the same step, before the code is mailed out, so that
+// concurrent requests cannot all slip past the limits. A send that then
+// fails still counts.
+func reserveSend(email, ip string) error {
+	limits := []ThrottleLimit{
+		{Key: sendEmailKey(email), Window: otpResendCooldown, Max: 1},
+		{Key: sendEmailKey(email), Window: otpSendWindow, Max: otpMaxSendsPerEmail},
+	}
+	if ip != "" {
+		limits = append(limits, ThrottleLimit{Key: sendIPKey(ip), Window: otpSendWindow, Max: otpMaxSendsPerIP})
+	}
+
+	reached, err := throttleStore.TryHit(limits...)
+	if err != nil {
+		return err
+	}
+	switch reached {
+	case -1:
+		return nil
+	case 0:
+		return &OTPError{
+			Code:       ErrCodeOTPResendCooldown,
+			Message:    "please wait before requesting another OTP",
+			RetryAfter: otpResendCooldown,
+		}
+	case 1:
+		return &OTPError{
+			Code:       ErrCodeOTPSendLimit,
+			Message:    fmt.Sprintf("OTP limit reached: at most %d per hour", otpMaxSendsPerEmail),
+			RetryAfter: otpSendWindow,
+		}
+	default:
+		return &OTPError{
+			Code:       ErrCodeOTPSendLimit,
+			Message:    "too many OTP requests from this address",
+			RetryAfter: otpSendWindow,
+		}
+	}
+}
+
+// recordVerifyFailure counts a wrong or expired code and locks the email or
+// IP out once its limit is reached. It returns a non-nil *OTPError when a
+// lockout was applied.
+func recordVerifyFailure(email, ip string) error {
+	n, err := throttleStore.Hit(verifyEmailKey(email), otpVerifyWindow)
+	if err != nil {
+		return err
+	}
+	if n >= otpMaxVerifyFailuresPerEmail {
+		d, err := lockOut(verifyEmailKey(email))
+		if err != nil {
+			return err
+		}
+		return &OTPError{Code: ErrCodeOTPLocked, Message: "too many failed attempts, try again later", RetryAfter: d}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	n, err = throttleStore.Hit(verifyIPKey(ip), otpVerifyWindow)
+	if err != nil {
+		return err
+	}
+	if n >= otpMaxVerifyFailuresPerIP {
+		d, err := lockOut(verifyIPKey(ip))
+		if err != nil {
+			return err
+		}
+		return &OTPError{Code: ErrCodeOTPLocked, Message: "too many failed attempts, try again later", RetryAfter: d}
+	}
+	return nil
+}
+
+func clearVerifyFailures(email string) error {
+	if err := throttleStore.Reset(verifyEmailKey(email)); err != nil {
+		return err
+	}
+	return throttleStore.ClearLock(verifyEmailKey(email))
+}
+
+type lockState struct {
+	until time.Time
+	level int
+}
+
+type MemoryThrottleStore struct {
+	mu     sync.Mutex
+	events map[string][]time.Time
+	locks  map[string]lockState
+}
+
+func NewMemoryThrottleStore() *MemoryThrottleStore {
+	return &MemoryThrottleStore{
+		events: make(map[string][]time.Time),
+		locks:  make(map[string]lockState),
+	}
+}
+
+func (s *MemoryThrottleStore) Hit(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events[key] = append(s.events[key], time.Now())
+	return s.countLocked(key, window), nil
+}
+
+func (s *MemoryThrottleStore) TryHit(limits ...ThrottleLimit) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, l := range limits {
+		if s.countLocked(l.Key, l.Window) >= l.Max {
+			return i, nil
+		}
+	}
+	now := time.Now()
+	for _, k := range throttleKeys(limits) {
+		s.events[k] = append(s.events[k], now)
+	}
+	return -1, nil
+}
+
+func (s *MemoryThrottleStore) Count(key string, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.countLocked(key, window), nil
+}
+
+func (s *MemoryThrottleStore) countLocked(key string, window time.Duration) int {
+	since := time.Now().Add(-window)
+	n := 0
+	for _, t := range s.events[key] {
+		if t.After(since) {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *MemoryThrottleStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.events, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryThrottleStore) Lock(key string) (time.Time, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l := s.locks[key]
+	return l.until, l.level, nil
+}
+
+func (s *MemoryThrottleStore) SetLock(key string, until time.Time, level int) error {
+	s.mu.Lock()
+	s.locks[key] = lockState{until: until, level: level}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryThrottleStore) ClearLock(key string) error {
+	s.mu.Lock()
+	delete(s.locks, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryThrottleStore) Purge(before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, ts := range s.events {
+		kept := ts[:0]
+		for _, t := range ts {
+			if t.After(before) {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.events, k)
+		} else {
+			s.events[k] = kept
+		}
+	}
+	for k, l := range s.locks {
+		if l.until.Before(before) {
+			delete(s.locks, k)
+		}
+	}
+	return nil
+}
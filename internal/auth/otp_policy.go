@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const otpSaltBytes = 16
+
+// OTPPolicy controls the shape and lifetime of codes issued to a role.
+type OTPPolicy struct {
+	Length int
+	TTL    time.Duration
+}
+
+var defaultOTPPolicy = OTPPolicy{Length: 6, TTL: 5 * time.Minute}
+
+var roleOTPPolicies = map[string]OTPPolicy{
+	"admin": {Length: 8, TTL: 3 * time.Minute},
+}
+
+// otpPolicyForRole returns the built-in policy for role, overridden by
+// OTP_LENGTH_<ROLE> and OTP_TTL_<ROLE> (e.g. OTP_TTL_ADMIN=2m) when set.
+func otpPolicyForRole(role string) OTPPolicy {
+	p, ok := roleOTPPolicies[role]
+	if !ok {
+		p = defaultOTPPolicy
+	}
+
+	suffix := strings.ToUpper(role)
+	if v := os.Getenv("OTP_LENGTH_" + suffix); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 4 && n <= 10 {
+			p.Length = n
+		}
+	}
+	if v := os.Getenv("OTP_TTL_" + suffix); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			p.TTL = d
+		}
+	}
+	return p
+}
+
+// generateEmailOTP returns a uniformly distributed numeric code of the given
+// length drawn from crypto/rand.
+func generateEmailOTP(length int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(length)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", length, n), nil
+}
+
+func newOTPSalt() (string, error) {
+	b := make([]byte, otpSaltBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashOTP(salt, otp string) string {
+	mac := hmac.New(sha256.New, []byte(salt))
+	mac.Write([]byte(otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// otpMatches compares otp against the stored salted hash in constant time.
+func otpMatches(entry *OTPEntry, otp string) bool {
+	return hmac.Equal([]byte(hashOTP(entry.Salt, otp)), []byte(entry.CodeHash))
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// PostgresOTPStore keeps codes in the otp_codes table so that pending logins
+// survive restarts and are shared between backend instances.
+type PostgresOTPStore struct {
+	db *sql.DB
+}
+
+func NewPostgresOTPStore(conn *sql.DB) *PostgresOTPStore {
+	return &PostgresOTPStore{db: conn}
+}
+
+func (s *PostgresOTPStore) Save(key string, entry OTPEntry) error {
+	_, err := s.db.Exec(`
+		INSERT INTO otp_codes (otp_key, salt, code_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (otp_key) DO UPDATE
+		SET salt = EXCLUDED.salt,
+		    code_hash = EXCLUDED.code_hash,
+		    expires_at = EXCLUDED.expires_at,
+		    created_at = NOW()
+	`, key, entry.Salt, entry.CodeHash, entry.ExpiresAt)
+	return err
+}
+
+func (s *PostgresOTPStore) Get(key string) (*OTPEntry, error) {
+	var e OTPEntry
+	err := s.db.QueryRow(`SELECT salt, code_hash, expires_at FROM otp_codes WHERE otp_key = $1`, key).
+		Scan(&e.Salt, &e.CodeHash, &e.ExpiresAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
+
+func (s *PostgresOTPStore) Consume(key, codeHash string, now time.Time) (bool, error) {
+	var deleted string
+	err := s.db.QueryRow(`
+		DELETE FROM otp_codes
+		WHERE otp_key = $1 AND code_hash = $2 AND expires_at >= $3
+		RETURNING otp_key
+	`, key, codeHash, now).Scan(&deleted)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PostgresOTPStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM otp_codes WHERE otp_key = $1`, key)
+	return err
+}
+
+func (s *PostgresOTPStore) DeleteExpired(now time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM otp_codes WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// StartOTPCleanup periodically purges expired codes and stale throttle
+// records until ctx is cancelled.
+func StartOTPCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n, err := otpStore.DeleteExpired(time.Now())
+				if err != nil {
+					log.Printf("otp cleanup failed: %v", err)
+					continue
+				}
+				if n > 0 {
+					log.Printf("otp cleanup removed %d expired codes", n)
+				}
+				if err := throttleStore.Purge(time.Now().Add(-otpThrottleRetention)); err != nil {
+					log.Printf("otp throttle cleanup failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// PostgresThrottleStore backs the OTP limits with the otp_throttle_events and
+// otp_lockouts tables.
+type PostgresThrottleStore struct {
+	db *sql.DB
+}
+
+func NewPostgresThrottleStore(conn *sql.DB) *PostgresThrottleStore {
+	return &PostgresThrottleStore{db: conn}
+}
+
+func (s *PostgresThrottleStore) Hit(key string, window time.Duration) (int, error) {
+	if _, err := s.db.Exec(`INSERT INTO otp_throttle_events (throttle_key, occurred_at) VALUES ($1, NOW())`, key); err != nil {
+		return 0, err
+	}
+	return s.Count(key, window)
+}
+
+func (s *PostgresThrottleStore) TryHit(limits ...ThrottleLimit) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return -1, err
+	}
+	defer tx.Rollback()
+
+	// Callers on the same keys queue here, so each one counts the events
+	// recorded by those before it.
+	keys := throttleKeys(limits)
+	for _, k := range keys {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1))`, k); err != nil {
+			return -1, err
+		}
+	}
+
+	now := time.Now()
+	for i, l := range limits {
+		var n int
+		err := tx.QueryRow(`
+			SELECT COUNT(*) FROM otp_throttle_events
+			WHERE throttle_key = $1 AND occurred_at > $2
+		`, l.Key, now.Add(-l.Window)).Scan(&n)
+		if err != nil {
+			return -1, err
+		}
+		if n >= l.Max {
+			return i, nil
+		}
+	}
+	for _, k := range keys {
+		if _, err := tx.Exec(`INSERT INTO otp_throttle_events (throttle_key, occurred_at) VALUES ($1, NOW())`, k); err != nil {
+			return -1, err
+		}
+	}
+	return -1, tx.Commit()
+}
+
+func (s *PostgresThrottleStore) Count(key string, window time.Duration) (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM otp_throttle_events
+		WHERE throttle_key = $1 AND occurred_at > $2
+	`, key, time.Now().Add(-window)).Scan(&n)
+	return n, err
+}
+
+func (s *PostgresThrottleStore) Reset(key string) error {
+	_, err := s.db.Exec(`DELETE FROM otp_throttle_events WHERE throttle_key = $1`, key)
+	return err
+}
+
+func (s *PostgresThrottleStore) Lock(key string) (time.Time, int, error) {
+	var until time.Time
+	var level int
+	err := s.db.QueryRow(`SELECT locked_until, level FROM otp_lockouts WHERE throttle_key = $1`, key).Scan(&until, &level)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return time.Time{}, 0, nil
+		}
+		return time.Time{}, 0, err
+	}
+	return until, level, nil
+}
+
+func (s *PostgresThrottleStore) SetLock(key string, until time.Time, level int) error {
+	_, err := s.db.Exec(`
+		INSERT INTO otp_lockouts (throttle_key, locked_until, level, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (throttle_key) DO UPDATE
+		SET locked_until = EXCLUDED.locked_until,
+		    level = EXCLUDED.level,
+		    updated_at = NOW()
+	`, key, until, level)
+	return err
+}
+
+func (s *PostgresThrottleStore) ClearLock(key string) error {
+	_, err := s.db.Exec(`DELETE FROM otp_lockouts WHERE throttle_key = $1`, key)
+	return err
+}
+
+func (s *PostgresThrottleStore) Purge(before time.Time) error {
+	if _, err := s.db.Exec(`DELETE FROM otp_throttle_events WHERE occurred_at < $1`, before); err != nil {
+		return err
+	}
+	_, err := s.db.Exec(`DELETE FROM otp_lockouts WHERE locked_until < $1`, before)
+	return err
+}
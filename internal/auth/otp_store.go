@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"database/sql"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+type OTPEntry struct {
+	Salt      string
+	CodeHash  string
+	ExpiresAt time.Time
+}
+
+// OTPStore persists pending OTP codes keyed by keyForOTP(email, role).
+// Implementations only ever see the salted hash of a code.
+type OTPStore interface {
+	Save(key string, entry OTPEntry) error
+	Get(key string) (*OTPEntry, error)
+	// Consume deletes the entry under key if it still holds codeHash and
+	// has not expired, and reports whether it did. Of two callers racing
+	// with the same code, only one gets true.
+	Consume(key, codeHash string, now time.Time) (bool, error)
+	Delete(key string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+var otpStore OTPStore = NewMemoryOTPStore()
+
+func SetOTPStore(s OTPStore) {
+	otpStore = s
+}
+
+// NewOTPStoreFromEnv picks the store from OTP_STORE ("postgres" by default,
+// "memory" for tests and single-instance development).
+func NewOTPStoreFromEnv(conn *sql.DB) OTPStore {
+	if strings.EqualFold(os.Getenv("OTP_STORE"), "memory") {
+		return NewMemoryOTPStore()
+	}
+	return NewPostgresOTPStore(conn)
+}
+
+type MemoryOTPStore struct {
+	mu sync.RWMutex
+	m  map[string]OTPEntry
+}
+
+func NewMemoryOTPStore() *MemoryOTPStore {
+	return &MemoryOTPStore{m: make(map[string]OTPEntry)}
+}
+
+func (s *MemoryOTPStore) Save(key string, entry OTPEntry) error {
+	s.mu.Lock()
+	s.m[key] = entry
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryOTPStore) Get(key string) (*OTPEntry, error) {
+	s.mu.RLock()
+	entry, ok := s.m[key]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+func (s *MemoryOTPStore) Consume(key, codeHash string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.m[key]
+	if !ok || entry.CodeHash != codeHash || now.After(entry.ExpiresAt) {
+		return false, nil
+	}
+	delete(s.m, key)
+	return true, nil
+}
+
+func (s *MemoryOTPStore) Delete(key string) error {
+	s.mu.Lock()
+	delete(s.m, key)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *MemoryOTPStore) DeleteExpired(now time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var n int64
+	for k, e := range s.m {
+		if now.After(e.ExpiresAt) {
+			delete(s.m, k)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,36 @@
+package auth
+
+import "time"
+
+type Session struct {
+	SessionID           string     `json:"session_id"`
+	UserID              string     `json:"user_id"`
+	RefreshTokenHash    string     `json:"-"`
+	PreviousRefreshHash string     `json:"-"`
+	UserAgent           string     `json:"user_agent"`
+	IP                  string     `json:"ip"`
+	CreatedAt           time.Time  `json:"created_at"`
+	LastUsedAt          time.Time  `json:"last_used_at"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+	RevokedAt           *time.Time `json:"revoked_at,omitempty"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type SessionResponse struct {
+	SessionID  string    `json:"session_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	SessionID    string
+}
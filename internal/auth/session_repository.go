@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+	"time"
+)
+
+func CreateSession(s *Session) error {
+	_, err := db.DB.Exec(`
+		INSERT INTO sessions (session_id, user_id, refresh_token_hash, user_agent, ip, created_at, last_used_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, s.SessionID, s.UserID, s.RefreshTokenHash, s.UserAgent, s.IP, s.CreatedAt, s.LastUsedAt, s.ExpiresAt)
+	return err
+}
+
+func GetSessionByID(sessionID string) (*Session, error) {
+	row := db.DB.QueryRow(`
+		SELECT session_id, user_id, refresh_token_hash, previous_refresh_hash, user_agent, ip, created_at, last_used_at, expires_at, revoked_at
+		FROM sessions
+		WHERE session_id = $1
+	`, sessionID)
+
+	var s Session
+	if err := row.Scan(&s.SessionID, &s.UserID, &s.RefreshTokenHash, &s.PreviousRefreshHash, &s.UserAgent, &s.IP,
+		&s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt, &s.RevokedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &s, nil
+}
+
+// RotateSessionToken swaps the refresh token hash only if the caller presented
+// the current one, so two concurrent refreshes cannot both succeed. The
+// replaced hash is kept to recognise a replay of the old token.
+func RotateSessionToken(sessionID, oldHash, newHash string, expiresAt time.Time, userAgent, ip string) (bool, error) {
+	res, err := db.DB.Exec(`
+		UPDATE sessions
+		SET previous_refresh_hash = refresh_token_hash, refresh_token_hash = $1, expires_at = $2, last_used_at = NOW(), user_agent = $3, ip = $4
+		WHERE session_id = $5 AND refresh_token_hash = $6 AND revoked_at IS NULL
+	`, newHash, expiresAt, userAgent, ip, sessionID, oldHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func RevokeSession(sessionID, userID string) (bool, error) {
+	res, err := db.DB.Exec(`
+		UPDATE sessions SET revoked_at = NOW()
+		WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`, sessionID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func RevokeSessionByID(sessionID string) error {
+	_, err := db.DB.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE session_id = $1 AND revoked_at IS NULL`, sessionID)
+	return err
+}
+
+func ListActiveSessionsByUser(userID string) ([]Session, error) {
+	rows, err := db.DB.Query(`
+		SELECT session_id, user_id, user_agent, ip, created_at, last_used_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		ORDER BY last_used_at DESC
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.SessionID, &s.UserID, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func IsSessionActive(sessionID string) (bool, error) {
+	var active bool
+	err := db.DB.QueryRow(`
+		SELECT EXISTS(
+			SELECT 1 FROM sessions
+			WHERE session_id = $1 AND revoked_at IS NULL AND expires_at > NOW()
+		)
+	`, sessionID).Scan(&active)
+	return active, err
+}
@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"enerzyflow_backend/internal/users"
+	"enerzyflow_backend/utils"
+
+	"github.com/google/uuid"
+)
+
+const defaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+func refreshTokenTTL() time.Duration {
+	if v := os.Getenv("REFRESH_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultRefreshTokenTTL
+}
+
+// newRefreshToken returns "<session_id>.<secret>" and the hash of the secret
+// that is persisted; the plaintext token is only ever held by the client.
+func newRefreshToken(sessionID string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret := base64.RawURLEncoding.EncodeToString(b)
+	return sessionID + "." + secret, hashRefreshSecret(secret), nil
+}
+
+func hashRefreshSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitRefreshToken(token string) (sessionID, secret string, ok bool) {
+	sessionID, secret, ok = strings.Cut(token, ".")
+	if !ok || sessionID == "" || secret == "" {
+		return "", "", false
+	}
+	return sessionID, secret, true
+}
+
+// StartSession opens a new session for u and returns its first token pair.
+func StartSession(u *users.User, userAgent, ip string) (*TokenPair, error) {
+	userUUID, err := uuid.Parse(u.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	sessionID := uuid.New().String()
+	refresh, hash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	s := &Session{
+		SessionID:        sessionID,
+		UserID:           u.UserID,
+		RefreshTokenHash: hash,
+		UserAgent:        userAgent,
+		IP:               ip,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(refreshTokenTTL()),
+	}
+	if err := CreateSession(s); err != nil {
+		return nil, err
+	}
+
+	access, err := utils.GenerateTokens(u.Email, userUUID, u.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, SessionID: sessionID}, nil
+}
+
+// RefreshSession rotates the refresh token and issues a new access token.
+// Presenting the refresh token that was just rotated out is treated as token
+// theft and revokes the whole session. The session ID is public (it is the
+// sid claim of every access token), so any other mismatch is only rejected.
+func RefreshSession(refreshToken, userAgent, ip string) (*TokenPair, error) {
+	sessionID, secret, ok := splitRefreshToken(refreshToken)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	s, err := GetSessionByID(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil || s.RevokedAt != nil || time.Now().After(s.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	presented := hashRefreshSecret(secret)
+	if !hmac.Equal([]byte(presented), []byte(s.RefreshTokenHash)) {
+		if s.PreviousRefreshHash != "" && hmac.Equal([]byte(presented), []byte(s.PreviousRefreshHash)) {
+			log.Printf("refresh token reuse detected for session %s, revoking", sessionID)
+			if err := RevokeSessionByID(sessionID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	u, err := userRepo.GetUserByID(s.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	userUUID, err := uuid.Parse(u.UserID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	refresh, hash, err := newRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	rotated, err := RotateSessionToken(sessionID, presented, hash, time.Now().Add(refreshTokenTTL()), userAgent, ip)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	access, err := utils.GenerateTokens(u.Email, userUUID, u.Role, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, SessionID: sessionID}, nil
+}
+
+func LogoutService(userID, sessionID string) error {
+	if sessionID == "" {
+		return ErrSessionNotFound
+	}
+	_, err := RevokeSession(sessionID, userID)
+	return err
+}
+
+func ListSessionsService(userID, currentSessionID string) ([]SessionResponse, error) {
+	sessions, err := ListActiveSessionsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]SessionResponse, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, SessionResponse{
+			SessionID:  s.SessionID,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.SessionID == currentSessionID,
+		})
+	}
+	return out, nil
+}
+
+func RevokeSessionService(userID, sessionID string) error {
+	revoked, err := RevokeSession(sessionID, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+	return nil
+}
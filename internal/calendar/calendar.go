@@ -0,0 +1,102 @@
+// Package calendar counts business days: admin-managed holidays plus the
+// working weekdays and cutoff time of the default calendar or of one
+// printing or plant user.
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"enerzyflow_backend/utils"
+)
+
+// DateLayout is the format of holiday dates.
+const DateLayout = "2006-01-02"
+
+// builtinCalendar applies until an admin saves a default calendar.
+var builtinCalendar = WorkCalendar{
+	CalendarID:  DefaultCalendarID,
+	WorkingDays: []string{"mon", "tue", "wed", "thu", "fri", "sat"},
+	Cutoff:      "17:00",
+}
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+func parseWeekday(name string) (time.Weekday, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if len(name) > 3 {
+		name = name[:3]
+	}
+	for i, n := range weekdayNames {
+		if n == name {
+			return time.Weekday(i), true
+		}
+	}
+	return 0, false
+}
+
+func parseCutoff(cutoff string) (time.Duration, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(cutoff))
+	if err != nil {
+		return 0, fmt.Errorf("cutoff must be HH:MM, got '%s'", cutoff)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Schedule answers business-day questions for one calendar.
+type Schedule struct {
+	working  [7]bool
+	cutoff   time.Duration
+	holidays map[string]bool
+}
+
+// NewSchedule builds the schedule of c with holidays.
+func NewSchedule(c WorkCalendar, holidays []Holiday) (*Schedule, error) {
+	s := &Schedule{holidays: map[string]bool{}}
+	for _, name := range c.WorkingDays {
+		d, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday '%s'", name)
+		}
+		s.working[d] = true
+	}
+	if s.working == [7]bool{} {
+		return nil, fmt.Errorf("calendar %s has no working days", c.CalendarID)
+	}
+	cutoff, err := parseCutoff(c.Cutoff)
+	if err != nil {
+		return nil, err
+	}
+	s.cutoff = cutoff
+	for _, h := range holidays {
+		s.holidays[h.Date] = true
+	}
+	return s, nil
+}
+
+// IsWorkingDay reports whether t falls on a working day in IST.
+func (s *Schedule) IsWorkingDay(t time.Time) bool {
+	t = t.In(utils.IST)
+	return s.working[t.Weekday()] && !s.holidays[t.Format(DateLayout)]
+}
+
+// AddBusinessDays returns the cutoff time on the days-th working day after
+// from. Work received after the cutoff counts from the next day: with a
+// 17:00 cutoff, two days from Monday 10:00 is Wednesday 17:00 and two days
+// from Monday 18:00 is Thursday 17:00.
+func (s *Schedule) AddBusinessDays(from time.Time, days int) time.Time {
+	t := from.In(utils.IST)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, utils.IST)
+	if t.After(day.Add(s.cutoff)) {
+		day = day.AddDate(0, 0, 1)
+	}
+	// Holidays are finite, so this ends; the bound guards a bad table.
+	for n, i := 0, 0; n < days && i < 3660; i++ {
+		day = day.AddDate(0, 0, 1)
+		if s.IsWorkingDay(day) {
+			n++
+		}
+	}
+	return day.Add(s.cutoff)
+}
@@ -0,0 +1,117 @@
+package calendar
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CalendarHandler struct {
+	svc *CalendarService
+}
+
+func NewCalendarHandler(svc *CalendarService) *CalendarHandler {
+	return &CalendarHandler{svc: svc}
+}
+
+func adminID(c *gin.Context) string {
+	if v, ok := c.Get("user_id"); ok {
+		if id, ok := v.(uuid.UUID); ok {
+			return id.String()
+		}
+	}
+	return ""
+}
+
+func (h *CalendarHandler) ListHolidaysHandler(c *gin.Context) {
+	year := 0
+	if v := c.Query("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "year must be a positive number"})
+			return
+		}
+		year = y
+	}
+
+	holidays, err := h.svc.ListHolidaysService(year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"holidays": holidays})
+}
+
+func (h *CalendarHandler) AddHolidayHandler(c *gin.Context) {
+	var req AddHolidayRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	holiday, err := h.svc.AddHolidayService(req, adminID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"holiday": holiday})
+}
+
+func (h *CalendarHandler) DeleteHolidayHandler(c *gin.Context) {
+	if err := h.svc.DeleteHolidayService(c.Param("date")); err != nil {
+		switch {
+		case errors.Is(err, ErrHolidayNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case strings.HasPrefix(err.Error(), "date must"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "holiday deleted"})
+}
+
+func (h *CalendarHandler) ListCalendarsHandler(c *gin.Context) {
+	calendars, err := h.svc.ListCalendarsService()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"calendars": calendars})
+}
+
+func (h *CalendarHandler) SaveCalendarHandler(c *gin.Context) {
+	var req SaveCalendarRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	cal, err := h.svc.SaveCalendarService(c.Param("id"), req, adminID(c))
+	if err != nil {
+		if err.Error() == "user not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"calendar": cal})
+}
+
+func (h *CalendarHandler) DeleteCalendarHandler(c *gin.Context) {
+	if err := h.svc.DeleteCalendarService(c.Param("id")); err != nil {
+		if errors.Is(err, ErrCalendarNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "calendar deleted"})
+}
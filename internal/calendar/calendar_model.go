@@ -0,0 +1,35 @@
+package calendar
+
+import "time"
+
+// DefaultCalendarID is the calendar of everyone without one of their own.
+const DefaultCalendarID = "default"
+
+// Holiday is a date nobody works, whatever their calendar says.
+type Holiday struct {
+	Date      string    `json:"date"`
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WorkCalendar sets the weekdays that are worked and the daily cutoff
+// (HH:MM, IST) after which new work counts from the next day. CalendarID
+// is DefaultCalendarID or the user ID of a printing or plant user.
+type WorkCalendar struct {
+	CalendarID  string    `json:"calendar_id"`
+	WorkingDays []string  `json:"working_days"`
+	Cutoff      string    `json:"cutoff"`
+	UpdatedBy   string    `json:"updated_by,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type AddHolidayRequest struct {
+	Date string `json:"date" binding:"required"`
+	Name string `json:"name" binding:"required"`
+}
+
+type SaveCalendarRequest struct {
+	WorkingDays []string `json:"working_days" binding:"required"`
+	Cutoff      string   `json:"cutoff" binding:"required"`
+}
@@ -0,0 +1,139 @@
+package calendar
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type CalendarRepository interface {
+	ListHolidays() ([]Holiday, error)
+	// SaveHoliday adds h, or renames the holiday already on its date.
+	SaveHoliday(h Holiday) error
+	DeleteHoliday(date string) (bool, error)
+	// GetCalendar returns nil when calendarID has no saved calendar.
+	GetCalendar(calendarID string) (*WorkCalendar, error)
+	ListCalendars() ([]WorkCalendar, error)
+	SaveCalendar(c WorkCalendar) error
+	DeleteCalendar(calendarID string) (bool, error)
+}
+
+type PostgresCalendarRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCalendarRepository(conn *sql.DB) *PostgresCalendarRepository {
+	return &PostgresCalendarRepository{db: conn}
+}
+
+func (r *PostgresCalendarRepository) ListHolidays() ([]Holiday, error) {
+	rows, err := r.db.Query(`
+		SELECT holiday_date, name, created_by, created_at
+		FROM holidays
+		ORDER BY holiday_date
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []Holiday
+	for rows.Next() {
+		var (
+			h    Holiday
+			date time.Time
+		)
+		if err := rows.Scan(&date, &h.Name, &h.CreatedBy, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.Date = date.Format(DateLayout)
+		list = append(list, h)
+	}
+	return list, rows.Err()
+}
+
+func (r *PostgresCalendarRepository) SaveHoliday(h Holiday) error {
+	_, err := r.db.Exec(`
+		INSERT INTO holidays (holiday_date, name, created_by, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (holiday_date) DO UPDATE SET name = EXCLUDED.name
+	`, h.Date, h.Name, h.CreatedBy, h.CreatedAt)
+	return err
+}
+
+func (r *PostgresCalendarRepository) DeleteHoliday(date string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM holidays WHERE holiday_date = $1`, date)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func scanCalendar(scan func(dest ...interface{}) error) (*WorkCalendar, error) {
+	var (
+		c    WorkCalendar
+		days string
+	)
+	if err := scan(&c.CalendarID, &days, &c.Cutoff, &c.UpdatedBy, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	c.WorkingDays = strings.Split(days, ",")
+	return &c, nil
+}
+
+func (r *PostgresCalendarRepository) GetCalendar(calendarID string) (*WorkCalendar, error) {
+	c, err := scanCalendar(r.db.QueryRow(`
+		SELECT calendar_id, working_days, cutoff, updated_by, updated_at
+		FROM work_calendars
+		WHERE calendar_id = $1
+	`, calendarID).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (r *PostgresCalendarRepository) ListCalendars() ([]WorkCalendar, error) {
+	rows, err := r.db.Query(`
+		SELECT calendar_id, working_days, cutoff, updated_by, updated_at
+		FROM work_calendars
+		ORDER BY calendar_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WorkCalendar
+	for rows.Next() {
+		c, err := scanCalendar(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *c)
+	}
+	return list, rows.Err()
+}
+
+func (r *PostgresCalendarRepository) SaveCalendar(c WorkCalendar) error {
+	_, err := r.db.Exec(`
+		INSERT INTO work_calendars (calendar_id, working_days, cutoff, updated_by, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (calendar_id) DO UPDATE
+		SET working_days = EXCLUDED.working_days,
+		    cutoff = EXCLUDED.cutoff,
+		    updated_by = EXCLUDED.updated_by,
+		    updated_at = EXCLUDED.updated_at
+	`, c.CalendarID, strings.Join(c.WorkingDays, ","), c.Cutoff, c.UpdatedBy, c.UpdatedAt)
+	return err
+}
+
+func (r *PostgresCalendarRepository) DeleteCalendar(calendarID string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM work_calendars WHERE calendar_id = $1`, calendarID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,93 @@
+package calendar
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryCalendarRepository is an in-memory CalendarRepository for tests.
+type MemoryCalendarRepository struct {
+	mu        sync.Mutex
+	holidays  map[string]Holiday
+	calendars map[string]WorkCalendar
+}
+
+func NewMemoryCalendarRepository() *MemoryCalendarRepository {
+	return &MemoryCalendarRepository{
+		holidays:  map[string]Holiday{},
+		calendars: map[string]WorkCalendar{},
+	}
+}
+
+func (r *MemoryCalendarRepository) ListHolidays() ([]Holiday, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Holiday, 0, len(r.holidays))
+	for _, h := range r.holidays {
+		list = append(list, h)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Date < list[j].Date })
+	return list, nil
+}
+
+func (r *MemoryCalendarRepository) SaveHoliday(h Holiday) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.holidays[h.Date]; ok {
+		existing.Name = h.Name
+		h = existing
+	}
+	r.holidays[h.Date] = h
+	return nil
+}
+
+func (r *MemoryCalendarRepository) DeleteHoliday(date string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.holidays[date]
+	delete(r.holidays, date)
+	return ok, nil
+}
+
+func (r *MemoryCalendarRepository) GetCalendar(calendarID string) (*WorkCalendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.calendars[calendarID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *MemoryCalendarRepository) ListCalendars() ([]WorkCalendar, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]WorkCalendar, 0, len(r.calendars))
+	for _, c := range r.calendars {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CalendarID < list[j].CalendarID })
+	return list, nil
+}
+
+func (r *MemoryCalendarRepository) SaveCalendar(c WorkCalendar) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calendars[c.CalendarID] = c
+	return nil
+}
+
+func (r *MemoryCalendarRepository) DeleteCalendar(calendarID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.calendars[calendarID]
+	delete(r.calendars, calendarID)
+	return ok, nil
+}
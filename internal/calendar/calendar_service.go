@@ -0,0 +1,192 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"enerzyflow_backend/internal/users"
+	"enerzyflow_backend/utils"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrHolidayNotFound  = errors.New("holiday not found")
+	ErrCalendarNotFound = errors.New("calendar not found")
+)
+
+type CalendarService struct {
+	repo  CalendarRepository
+	users users.UserRepository
+}
+
+func NewCalendarService(repo CalendarRepository, userRepo users.UserRepository) *CalendarService {
+	return &CalendarService{repo: repo, users: userRepo}
+}
+
+// Schedule returns the schedule of calendarID, which falls back to the
+// default calendar when it has none of its own.
+func (s *CalendarService) Schedule(calendarID string) (*Schedule, error) {
+	c, err := s.calendar(calendarID)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := s.repo.ListHolidays()
+	if err != nil {
+		return nil, err
+	}
+	return NewSchedule(*c, holidays)
+}
+
+func (s *CalendarService) calendar(calendarID string) (*WorkCalendar, error) {
+	for _, id := range []string{calendarID, DefaultCalendarID} {
+		if id == "" {
+			continue
+		}
+		c, err := s.repo.GetCalendar(id)
+		if err != nil || c != nil {
+			return c, err
+		}
+	}
+	c := builtinCalendar
+	return &c, nil
+}
+
+// ListHolidaysService lists the holidays of year, or all of them when year
+// is zero.
+func (s *CalendarService) ListHolidaysService(year int) ([]Holiday, error) {
+	all, err := s.repo.ListHolidays()
+	if err != nil {
+		return nil, err
+	}
+	list := []Holiday{}
+	for _, h := range all {
+		if year == 0 || strings.HasPrefix(h.Date, fmt.Sprintf("%04d-", year)) {
+			list = append(list, h)
+		}
+	}
+	return list, nil
+}
+
+func (s *CalendarService) AddHolidayService(req AddHolidayRequest, adminID string) (*Holiday, error) {
+	date, err := time.Parse(DateLayout, strings.TrimSpace(req.Date))
+	if err != nil {
+		return nil, fmt.Errorf("date must be YYYY-MM-DD, got '%s'", req.Date)
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+
+	h := Holiday{
+		Date:      date.Format(DateLayout),
+		Name:      name,
+		CreatedBy: adminID,
+		CreatedAt: utils.NowInIST(),
+	}
+	if err := s.repo.SaveHoliday(h); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+func (s *CalendarService) DeleteHolidayService(date string) error {
+	if _, err := time.Parse(DateLayout, date); err != nil {
+		return fmt.Errorf("date must be YYYY-MM-DD, got '%s'", date)
+	}
+	ok, err := s.repo.DeleteHoliday(date)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrHolidayNotFound
+	}
+	return nil
+}
+
+// ListCalendarsService lists the saved calendars, with the default one
+// first even when it has not been saved.
+func (s *CalendarService) ListCalendarsService() ([]WorkCalendar, error) {
+	saved, err := s.repo.ListCalendars()
+	if err != nil {
+		return nil, err
+	}
+	list := []WorkCalendar{builtinCalendar}
+	for _, c := range saved {
+		if c.CalendarID == DefaultCalendarID {
+			list[0] = c
+			continue
+		}
+		list = append(list, c)
+	}
+	return list, nil
+}
+
+// SaveCalendarService sets the working days and cutoff of the default
+// calendar or of a printing or plant user.
+func (s *CalendarService) SaveCalendarService(calendarID string, req SaveCalendarRequest, adminID string) (*WorkCalendar, error) {
+	if calendarID != DefaultCalendarID {
+		if _, err := uuid.Parse(calendarID); err != nil {
+			return nil, errors.New("user not found")
+		}
+		u, err := s.users.GetUserByID(calendarID)
+		if err != nil {
+			return nil, err
+		}
+		if u == nil {
+			return nil, errors.New("user not found")
+		}
+		if u.Role != "printing" && u.Role != "plant" {
+			return nil, errors.New("calendars can only be set for printing and plant users")
+		}
+	}
+
+	var working [7]bool
+	for _, name := range req.WorkingDays {
+		d, ok := parseWeekday(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown weekday '%s'", name)
+		}
+		working[d] = true
+	}
+	var days []string
+	for _, d := range []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday} {
+		if working[d] {
+			days = append(days, weekdayNames[d])
+		}
+	}
+	if len(days) == 0 {
+		return nil, errors.New("at least one working day is required")
+	}
+	cutoff, err := parseCutoff(req.Cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	c := WorkCalendar{
+		CalendarID:  calendarID,
+		WorkingDays: days,
+		Cutoff:      fmt.Sprintf("%02d:%02d", int(cutoff.Hours()), int(cutoff.Minutes())%60),
+		UpdatedBy:   adminID,
+		UpdatedAt:   utils.NowInIST(),
+	}
+	if err := s.repo.SaveCalendar(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteCalendarService removes a user's calendar so the default applies
+// again; deleting the default one restores the built-in calendar.
+func (s *CalendarService) DeleteCalendarService(calendarID string) error {
+	ok, err := s.repo.DeleteCalendar(calendarID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCalendarNotFound
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"enerzyflow_backend/utils"
+)
+
+func TestAddBusinessDays(t *testing.T) {
+	weekdays := WorkCalendar{CalendarID: "weekdays", WorkingDays: []string{"Monday", "tue", "wed", "thu", "fri"}, Cutoff: "09:30"}
+
+	// 2026-03-02 is a Monday.
+	tests := []struct {
+		name     string
+		cal      WorkCalendar
+		holidays []string
+		from     time.Time
+		days     int
+		want     time.Time
+	}{
+		{"before cutoff", builtinCalendar, nil, time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST), 2, time.Date(2026, 3, 4, 17, 0, 0, 0, utils.IST)},
+		{"after cutoff", builtinCalendar, nil, time.Date(2026, 3, 2, 18, 0, 0, 0, utils.IST), 2, time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST)},
+		{"at cutoff counts the same day", builtinCalendar, nil, time.Date(2026, 3, 2, 17, 0, 0, 0, utils.IST), 1, time.Date(2026, 3, 3, 17, 0, 0, 0, utils.IST)},
+		{"other time zone", builtinCalendar, nil, time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC), 1, time.Date(2026, 3, 4, 17, 0, 0, 0, utils.IST)},
+		{"sunday start", builtinCalendar, nil, time.Date(2026, 3, 8, 10, 0, 0, 0, utils.IST), 1, time.Date(2026, 3, 9, 17, 0, 0, 0, utils.IST)},
+		{"saturday after cutoff", builtinCalendar, nil, time.Date(2026, 3, 7, 18, 0, 0, 0, utils.IST), 1, time.Date(2026, 3, 9, 17, 0, 0, 0, utils.IST)},
+		{"holiday run", builtinCalendar, []string{"2026-03-03", "2026-03-04"}, time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST), 2, time.Date(2026, 3, 6, 17, 0, 0, 0, utils.IST)},
+		{"holidays around a sunday", builtinCalendar, []string{"2026-03-06", "2026-03-07", "2026-03-09"}, time.Date(2026, 3, 5, 10, 0, 0, 0, utils.IST), 1, time.Date(2026, 3, 10, 17, 0, 0, 0, utils.IST)},
+		{"zero days before cutoff", builtinCalendar, nil, time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST), 0, time.Date(2026, 3, 2, 17, 0, 0, 0, utils.IST)},
+		{"zero days after cutoff", builtinCalendar, nil, time.Date(2026, 3, 2, 18, 0, 0, 0, utils.IST), 0, time.Date(2026, 3, 3, 17, 0, 0, 0, utils.IST)},
+		{"five-day week", weekdays, nil, time.Date(2026, 3, 6, 9, 0, 0, 0, utils.IST), 1, time.Date(2026, 3, 9, 9, 30, 0, 0, utils.IST)},
+		{"five-day week after friday cutoff", weekdays, nil, time.Date(2026, 3, 6, 9, 45, 0, 0, utils.IST), 1, time.Date(2026, 3, 9, 9, 30, 0, 0, utils.IST)},
+		{"five-day week after thursday cutoff", weekdays, nil, time.Date(2026, 3, 5, 9, 45, 0, 0, utils.IST), 1, time.Date(2026, 3, 9, 9, 30, 0, 0, utils.IST)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var holidays []Holiday
+			for _, d := range tt.holidays {
+				holidays = append(holidays, Holiday{Date: d})
+			}
+			s, err := NewSchedule(tt.cal, holidays)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.AddBusinessDays(tt.from, tt.days); !got.Equal(tt.want) {
+				t.Errorf("AddBusinessDays(%v, %d) = %v, want %v", tt.from, tt.days, got.In(utils.IST), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewScheduleRejectsBadCalendars(t *testing.T) {
+	for _, c := range []WorkCalendar{
+		{CalendarID: "none", Cutoff: "17:00"},
+		{CalendarID: "typo", WorkingDays: []string{"mon", "funday"}, Cutoff: "17:00"},
+		{CalendarID: "cutoff", WorkingDays: []string{"mon"}, Cutoff: "5pm"},
+	} {
+		if _, err := NewSchedule(c, nil); err == nil {
+			t.Errorf("NewSchedule(%s) accepted %+v", c.CalendarID, c)
+		}
+	}
+}
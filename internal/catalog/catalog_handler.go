@@ -0,0 +1,102 @@
+package catalog
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CatalogHandler struct {
+	svc *CatalogService
+}
+
+func NewCatalogHandler(svc *CatalogService) *CatalogHandler {
+	return &CatalogHandler{svc: svc}
+}
+
+// ListActiveSKUsHandler lists what can be ordered.
+func (h *CatalogHandler) ListActiveSKUsHandler(c *gin.Context) {
+	skus, err := h.svc.ListSKUsService(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"skus": skus})
+}
+
+// ListSKUsHandler lists every SKU, inactive ones included.
+func (h *CatalogHandler) ListSKUsHandler(c *gin.Context) {
+	skus, err := h.svc.ListSKUsService(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"skus": skus})
+}
+
+func (h *CatalogHandler) GetSKUHandler(c *gin.Context) {
+	sku, err := h.svc.GetSKUService(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, ErrSKUNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku": sku})
+}
+
+func (h *CatalogHandler) CreateSKUHandler(c *gin.Context) {
+	var req CreateSKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	sku, err := h.svc.CreateSKUService(req)
+	if err != nil {
+		if errors.Is(err, ErrSKUExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"sku": sku})
+}
+
+func (h *CatalogHandler) UpdateSKUHandler(c *gin.Context) {
+	var req UpdateSKURequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	sku, err := h.svc.UpdateSKUService(c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, ErrSKUNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"sku": sku})
+}
+
+func (h *CatalogHandler) DeleteSKUHandler(c *gin.Context) {
+	if err := h.svc.DeleteSKUService(c.Param("id")); err != nil {
+		switch {
+		case errors.Is(err, ErrSKUNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, ErrSKUInUse):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "sku deleted"})
+}
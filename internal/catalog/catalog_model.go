@@ -0,0 +1,33 @@
+package catalog
+
+import "time"
+
+// SKU is one orderable product: a bottle variant in a volume with a cap
+// color. Orders of fewer than MOQ bottles, or of an inactive SKU, are
+// rejected. The combination is fixed once created; SKUID is derived from
+// it, e.g. "classic-500ml-blue".
+type SKU struct {
+	SKUID     string    `json:"sku_id"`
+	Variant   string    `json:"variant"`
+	VolumeML  int       `json:"volume_ml"`
+	CapColor  string    `json:"cap_color"`
+	MOQ       int       `json:"moq"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateSKURequest struct {
+	Variant  string `json:"variant" binding:"required"`
+	VolumeML int    `json:"volume_ml" binding:"required,min=1"`
+	CapColor string `json:"cap_color" binding:"required"`
+	MOQ      int    `json:"moq" binding:"omitempty,min=1"`
+	Active   *bool  `json:"active"`
+}
+
+// UpdateSKURequest changes the MOQ or active flag; fields left out keep
+// their value.
+type UpdateSKURequest struct {
+	MOQ    *int  `json:"moq" binding:"omitempty,min=1"`
+	Active *bool `json:"active"`
+}
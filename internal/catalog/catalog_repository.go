@@ -0,0 +1,119 @@
+package catalog
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrSKUExists = errors.New("a sku with this variant, volume and cap color already exists")
+	ErrSKUInUse  = errors.New("sku is used by orders; deactivate it instead")
+)
+
+type CatalogRepository interface {
+	ListSKUs(activeOnly bool) ([]SKU, error)
+	// GetSKU and FindSKU return nil when there is no such SKU.
+	GetSKU(skuID string) (*SKU, error)
+	FindSKU(variant string, volumeML int, capColor string) (*SKU, error)
+	// CreateSKU returns ErrSKUExists when the combination is taken.
+	CreateSKU(s SKU) error
+	UpdateSKU(s SKU) error
+	// DeleteSKU returns ErrSKUInUse when orders reference the SKU.
+	DeleteSKU(skuID string) (bool, error)
+}
+
+type PostgresCatalogRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCatalogRepository(conn *sql.DB) *PostgresCatalogRepository {
+	return &PostgresCatalogRepository{db: conn}
+}
+
+const skuColumns = `sku_id, variant, volume_ml, cap_color, moq, active, created_at, updated_at`
+
+func scanSKU(scan func(dest ...interface{}) error) (*SKU, error) {
+	var s SKU
+	if err := scan(&s.SKUID, &s.Variant, &s.VolumeML, &s.CapColor, &s.MOQ, &s.Active, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *PostgresCatalogRepository) ListSKUs(activeOnly bool) ([]SKU, error) {
+	rows, err := r.db.Query(`
+		SELECT `+skuColumns+`
+		FROM skus
+		WHERE active OR NOT $1
+		ORDER BY variant, volume_ml, cap_color
+	`, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []SKU
+	for rows.Next() {
+		s, err := scanSKU(rows.Scan)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *s)
+	}
+	return list, rows.Err()
+}
+
+func (r *PostgresCatalogRepository) GetSKU(skuID string) (*SKU, error) {
+	s, err := scanSKU(r.db.QueryRow(`SELECT `+skuColumns+` FROM skus WHERE sku_id = $1`, skuID).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *PostgresCatalogRepository) FindSKU(variant string, volumeML int, capColor string) (*SKU, error) {
+	s, err := scanSKU(r.db.QueryRow(`
+		SELECT `+skuColumns+`
+		FROM skus
+		WHERE variant = $1 AND volume_ml = $2 AND cap_color = $3
+	`, variant, volumeML, capColor).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func (r *PostgresCatalogRepository) CreateSKU(s SKU) error {
+	_, err := r.db.Exec(`
+		INSERT INTO skus (`+skuColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, s.SKUID, s.Variant, s.VolumeML, s.CapColor, s.MOQ, s.Active, s.CreatedAt, s.UpdatedAt)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrSKUExists
+	}
+	return err
+}
+
+func (r *PostgresCatalogRepository) UpdateSKU(s SKU) error {
+	_, err := r.db.Exec(`
+		UPDATE skus SET moq = $2, active = $3, updated_at = $4
+		WHERE sku_id = $1
+	`, s.SKUID, s.MOQ, s.Active, s.UpdatedAt)
+	return err
+}
+
+func (r *PostgresCatalogRepository) DeleteSKU(skuID string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM skus WHERE sku_id = $1`, skuID)
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+		return false, ErrSKUInUse
+	}
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
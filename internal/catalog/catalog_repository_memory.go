@@ -0,0 +1,96 @@
+package catalog
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryCatalogRepository is an in-memory CatalogRepository for tests. It
+// does not know about orders, so DeleteSKU never returns ErrSKUInUse.
+type MemoryCatalogRepository struct {
+	mu   sync.Mutex
+	skus map[string]SKU
+}
+
+func NewMemoryCatalogRepository() *MemoryCatalogRepository {
+	return &MemoryCatalogRepository{skus: map[string]SKU{}}
+}
+
+func (r *MemoryCatalogRepository) ListSKUs(activeOnly bool) ([]SKU, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]SKU, 0, len(r.skus))
+	for _, s := range r.skus {
+		if activeOnly && !s.Active {
+			continue
+		}
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Variant != b.Variant {
+			return a.Variant < b.Variant
+		}
+		if a.VolumeML != b.VolumeML {
+			return a.VolumeML < b.VolumeML
+		}
+		return a.CapColor < b.CapColor
+	})
+	return list, nil
+}
+
+func (r *MemoryCatalogRepository) GetSKU(skuID string) (*SKU, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.skus[skuID]
+	if !ok {
+		return nil, nil
+	}
+	return &s, nil
+}
+
+func (r *MemoryCatalogRepository) FindSKU(variant string, volumeML int, capColor string) (*SKU, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, s := range r.skus {
+		if s.Variant == variant && s.VolumeML == volumeML && s.CapColor == capColor {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryCatalogRepository) CreateSKU(s SKU) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.skus {
+		if existing.SKUID == s.SKUID || (existing.Variant == s.Variant && existing.VolumeML == s.VolumeML && existing.CapColor == s.CapColor) {
+			return ErrSKUExists
+		}
+	}
+	r.skus[s.SKUID] = s
+	return nil
+}
+
+func (r *MemoryCatalogRepository) UpdateSKU(s SKU) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.skus[s.SKUID]; ok {
+		r.skus[s.SKUID] = s
+	}
+	return nil
+}
+
+func (r *MemoryCatalogRepository) DeleteSKU(skuID string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, ok := r.skus[skuID]
+	delete(r.skus, skuID)
+	return ok, nil
+}
@@ -0,0 +1,164 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"enerzyflow_backend/utils"
+)
+
+var ErrSKUNotFound = errors.New("sku not found")
+
+type CatalogService struct {
+	repo CatalogRepository
+}
+
+func NewCatalogService(repo CatalogRepository) *CatalogService {
+	return &CatalogService{repo: repo}
+}
+
+var nonSlug = regexp.MustCompile(`[^a-z0-9]+`)
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func slug(s string) string {
+	return strings.Trim(nonSlug.ReplaceAllString(normalize(s), "-"), "-")
+}
+
+// SKUID derives the ID of a variant, volume and cap color combination.
+func SKUID(variant string, volumeML int, capColor string) string {
+	return fmt.Sprintf("%s-%dml-%s", slug(variant), volumeML, slug(capColor))
+}
+
+func (s *CatalogService) ListSKUsService(activeOnly bool) ([]SKU, error) {
+	list, err := s.repo.ListSKUs(activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []SKU{}
+	}
+	return list, nil
+}
+
+func (s *CatalogService) GetSKUService(skuID string) (*SKU, error) {
+	sku, err := s.repo.GetSKU(skuID)
+	if err != nil {
+		return nil, err
+	}
+	if sku == nil {
+		return nil, ErrSKUNotFound
+	}
+	return sku, nil
+}
+
+func (s *CatalogService) CreateSKUService(req CreateSKURequest) (*SKU, error) {
+	variant, capColor := normalize(req.Variant), normalize(req.CapColor)
+	if slug(variant) == "" || slug(capColor) == "" {
+		return nil, errors.New("variant and cap_color must contain letters or digits")
+	}
+	if req.VolumeML < 1 {
+		return nil, errors.New("volume_ml must be positive")
+	}
+
+	now := utils.NowInIST()
+	sku := SKU{
+		SKUID:     SKUID(variant, req.VolumeML, capColor),
+		Variant:   variant,
+		VolumeML:  req.VolumeML,
+		CapColor:  capColor,
+		MOQ:       1,
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if req.MOQ > 0 {
+		sku.MOQ = req.MOQ
+	}
+	if req.Active != nil {
+		sku.Active = *req.Active
+	}
+	if err := s.repo.CreateSKU(sku); err != nil {
+		return nil, err
+	}
+	return &sku, nil
+}
+
+func (s *CatalogService) UpdateSKUService(skuID string, req UpdateSKURequest) (*SKU, error) {
+	sku, err := s.GetSKUService(skuID)
+	if err != nil {
+		return nil, err
+	}
+	if req.MOQ != nil {
+		if *req.MOQ < 1 {
+			return nil, errors.New("moq must be at least 1")
+		}
+		sku.MOQ = *req.MOQ
+	}
+	if req.Active != nil {
+		sku.Active = *req.Active
+	}
+	sku.UpdatedAt = utils.NowInIST()
+	if err := s.repo.UpdateSKU(*sku); err != nil {
+		return nil, err
+	}
+	return sku, nil
+}
+
+func (s *CatalogService) DeleteSKUService(skuID string) error {
+	ok, err := s.repo.DeleteSKU(skuID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSKUNotFound
+	}
+	return nil
+}
+
+// OrderableSKU resolves what an order asks for to an active SKU that
+// allows qty bottles. skuID wins when it is set; any variant, volume or
+// cap color sent with it must agree with the SKU.
+func (s *CatalogService) OrderableSKU(skuID, variant string, volumeML int, capColor string, qty int) (*SKU, error) {
+	var (
+		sku *SKU
+		err error
+	)
+	if skuID != "" {
+		sku, err = s.repo.GetSKU(skuID)
+		if err != nil {
+			return nil, err
+		}
+		if sku == nil {
+			return nil, fmt.Errorf("unknown sku '%s'", skuID)
+		}
+		if (variant != "" && normalize(variant) != sku.Variant) ||
+			(volumeML != 0 && volumeML != sku.VolumeML) ||
+			(capColor != "" && normalize(capColor) != sku.CapColor) {
+			return nil, fmt.Errorf("variant, volume and cap_color do not match sku '%s'", skuID)
+		}
+	} else {
+		if variant == "" || volumeML == 0 || capColor == "" {
+			return nil, errors.New("sku_id, or variant, volume and cap_color, are required")
+		}
+		sku, err = s.repo.FindSKU(normalize(variant), volumeML, normalize(capColor))
+		if err != nil {
+			return nil, err
+		}
+		if sku == nil {
+			return nil, fmt.Errorf("no product is offered as %s, %d ml with a %s cap", variant, volumeML, capColor)
+		}
+	}
+
+	if !sku.Active {
+		return nil, fmt.Errorf("sku '%s' is not available", sku.SKUID)
+	}
+	if qty < sku.MOQ {
+		return nil, fmt.Errorf("sku '%s' has a minimum order quantity of %d", sku.SKUID, sku.MOQ)
+	}
+	return sku, nil
+}
@@ -2,10 +2,30 @@ package companies
 
 import (
 	"database/sql"
-	"enerzyflow_backend/internal/db"
 )
 
-func UpsertCompanyTx(tx *sql.Tx, c *Company) error {
+// CompanyRepository is the storage behind companies, outlets and labels.
+// The *Tx methods take part in a transaction owned by the caller; in-memory
+// implementations may receive a nil tx.
+type CompanyRepository interface {
+	GetCompanyByUserID(userID string) (*Company, error)
+	GetLabelByIDAndCompanyID(labelID, companyID string) (*Label, error)
+	ListCompanyOutlets(companyID string) ([]CompanyOutlet, error)
+	GetLabelsByCompanyID(companyID string) ([]Label, error)
+	UpsertCompanyTx(tx *sql.Tx, c *Company) error
+	ReplaceCompanyOutletsTx(tx *sql.Tx, companyID string, outlets []CompanyOutlet) error
+	ReplaceCompanyLabelsTx(tx *sql.Tx, companyID string, labelsToSave []Label) ([]BlockedLabel, error)
+}
+
+type PostgresCompanyRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresCompanyRepository(conn *sql.DB) *PostgresCompanyRepository {
+	return &PostgresCompanyRepository{db: conn}
+}
+
+func (r *PostgresCompanyRepository) UpsertCompanyTx(tx *sql.Tx, c *Company) error {
 	res, err := tx.Exec(`UPDATE companies SET name = $1, address = $2, logo = $3, updated_at = CURRENT_TIMESTAMP WHERE user_id = $4`, c.Name, c.Address, c.Logo, c.UserID)
 	if err != nil {
 		return err
@@ -21,8 +41,8 @@ func UpsertCompanyTx(tx *sql.Tx, c *Company) error {
 	return nil
 }
 
-func GetLabelByIDAndCompanyID(labelID, companyID string) (*Label, error) {
-	row := db.DB.QueryRow(`SELECT label_id, company_id, name, label_url FROM labels WHERE label_id = $1 AND company_id = $2`, labelID, companyID)
+func (r *PostgresCompanyRepository) GetLabelByIDAndCompanyID(labelID, companyID string) (*Label, error) {
+	row := r.db.QueryRow(`SELECT label_id, company_id, name, label_url FROM labels WHERE label_id = $1 AND company_id = $2`, labelID, companyID)
 	var l Label
 	if err := row.Scan(&l.LabelID, &l.CompanyID, &l.Name, &l.URL); err != nil {
 		if err == sql.ErrNoRows {
@@ -33,7 +53,7 @@ func GetLabelByIDAndCompanyID(labelID, companyID string) (*Label, error) {
 	return &l, nil
 }
 
-func ReplaceCompanyOutletsTx(tx *sql.Tx, companyID string, outlets []CompanyOutlet) error {
+func (r *PostgresCompanyRepository) ReplaceCompanyOutletsTx(tx *sql.Tx, companyID string, outlets []CompanyOutlet) error {
 	if _, err := tx.Exec(`DELETE FROM company_outlets WHERE company_id = $1`, companyID); err != nil {
 		return err
 	}
@@ -45,8 +65,8 @@ func ReplaceCompanyOutletsTx(tx *sql.Tx, companyID string, outlets []CompanyOutl
 	return nil
 }
 
-func GetCompanyByUserID(userID string) (*Company, error) {
-	row := db.DB.QueryRow(`SELECT company_id, user_id, name, address, logo FROM companies WHERE user_id = $1`, userID)
+func (r *PostgresCompanyRepository) GetCompanyByUserID(userID string) (*Company, error) {
+	row := r.db.QueryRow(`SELECT company_id, user_id, name, address, logo FROM companies WHERE user_id = $1`, userID)
 	c := &Company{}
 	if err := row.Scan(&c.CompanyID, &c.UserID, &c.Name, &c.Address, &c.Logo); err != nil {
 		if err == sql.ErrNoRows {
@@ -57,8 +77,8 @@ func GetCompanyByUserID(userID string) (*Company, error) {
 	return c, nil
 }
 
-func ListCompanyOutlets(companyID string) ([]CompanyOutlet, error) {
-	rows, err := db.DB.Query(`SELECT id, company_id, name, address FROM company_outlets WHERE company_id = $1`, companyID)
+func (r *PostgresCompanyRepository) ListCompanyOutlets(companyID string) ([]CompanyOutlet, error) {
+	rows, err := r.db.Query(`SELECT id, company_id, name, address FROM company_outlets WHERE company_id = $1`, companyID)
 	if err != nil {
 		return nil, err
 	}
@@ -75,8 +95,8 @@ func ListCompanyOutlets(companyID string) ([]CompanyOutlet, error) {
 	return result, rows.Err()
 }
 
-func GetLabelsByCompanyID(companyID string) ([]Label, error) {
-	rows, err := db.DB.Query(`SELECT label_id, company_id, name, label_url FROM labels WHERE company_id = $1 ORDER BY created_at DESC`, companyID)
+func (r *PostgresCompanyRepository) GetLabelsByCompanyID(companyID string) ([]Label, error) {
+	rows, err := r.db.Query(`SELECT label_id, company_id, name, label_url FROM labels WHERE company_id = $1 ORDER BY created_at DESC`, companyID)
 	if err != nil {
 		return nil, err
 	}
@@ -93,7 +113,7 @@ func GetLabelsByCompanyID(companyID string) ([]Label, error) {
 	return out, rows.Err()
 }
 
-func ReplaceCompanyLabelsTx(tx *sql.Tx, companyID string, labelsToSave []Label) ([]BlockedLabel, error) {
+func (r *PostgresCompanyRepository) ReplaceCompanyLabelsTx(tx *sql.Tx, companyID string, labelsToSave []Label) ([]BlockedLabel, error) {
 	var blocked []BlockedLabel
     rows, err := tx.Query(`SELECT label_id FROM labels WHERE company_id = $1`, companyID)
     if err != nil {
@@ -0,0 +1,109 @@
+package companies
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// MemoryCompanyRepository is an in-memory CompanyRepository for tests.
+// Transactions are ignored. Labels listed in LabelsInUse are treated as
+// referenced by orders and are never deleted.
+type MemoryCompanyRepository struct {
+	mu          sync.Mutex
+	companies   map[string]Company // by user_id
+	outlets     map[string][]CompanyOutlet
+	labels      map[string][]Label
+	LabelsInUse map[string]bool
+}
+
+func NewMemoryCompanyRepository() *MemoryCompanyRepository {
+	return &MemoryCompanyRepository{
+		companies:   map[string]Company{},
+		outlets:     map[string][]CompanyOutlet{},
+		labels:      map[string][]Label{},
+		LabelsInUse: map[string]bool{},
+	}
+}
+
+func (r *MemoryCompanyRepository) GetCompanyByUserID(userID string) (*Company, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.companies[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &c, nil
+}
+
+func (r *MemoryCompanyRepository) GetLabelByIDAndCompanyID(labelID, companyID string) (*Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, l := range r.labels[companyID] {
+		if l.LabelID == labelID {
+			return &l, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryCompanyRepository) ListCompanyOutlets(companyID string) ([]CompanyOutlet, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]CompanyOutlet(nil), r.outlets[companyID]...), nil
+}
+
+func (r *MemoryCompanyRepository) GetLabelsByCompanyID(companyID string) ([]Label, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]Label(nil), r.labels[companyID]...), nil
+}
+
+func (r *MemoryCompanyRepository) UpsertCompanyTx(_ *sql.Tx, c *Company) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.companies[c.UserID] = *c
+	return nil
+}
+
+func (r *MemoryCompanyRepository) ReplaceCompanyOutletsTx(_ *sql.Tx, companyID string, outlets []CompanyOutlet) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.outlets[companyID] = append([]CompanyOutlet(nil), outlets...)
+	return nil
+}
+
+func (r *MemoryCompanyRepository) ReplaceCompanyLabelsTx(_ *sql.Tx, companyID string, labelsToSave []Label) ([]BlockedLabel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	incoming := map[string]bool{}
+	for _, l := range labelsToSave {
+		incoming[l.LabelID] = true
+	}
+
+	var (
+		kept    []Label
+		blocked []BlockedLabel
+	)
+	for _, old := range r.labels[companyID] {
+		if incoming[old.LabelID] {
+			continue
+		}
+		if r.LabelsInUse[old.LabelID] {
+			kept = append(kept, old)
+			blocked = append(blocked, BlockedLabel{LabelID: old.LabelID, Name: old.Name})
+		}
+	}
+	for _, l := range labelsToSave {
+		l.CompanyID = companyID
+		kept = append(kept, l)
+	}
+	r.labels[companyID] = kept
+	return blocked, nil
+}
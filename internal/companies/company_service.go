@@ -5,7 +5,15 @@ import (
 	"errors"
 )
 
-func SaveCompanyOutletsService(tx *sql.Tx, companyID string, outlets []CompanyOutlet) error {
+type CompanyService struct {
+	repo CompanyRepository
+}
+
+func NewCompanyService(repo CompanyRepository) *CompanyService {
+	return &CompanyService{repo: repo}
+}
+
+func (s *CompanyService) SaveCompanyOutletsService(tx *sql.Tx, companyID string, outlets []CompanyOutlet) error {
 	if companyID == "" {
 		return errors.New("company_id cannot be empty")
 	}
@@ -22,10 +30,10 @@ func SaveCompanyOutletsService(tx *sql.Tx, companyID string, outlets []CompanyOu
 		}
 	}
 
-	return ReplaceCompanyOutletsTx(tx, companyID, outlets)
+	return s.repo.ReplaceCompanyOutletsTx(tx, companyID, outlets)
 }
 
-func SaveCompanyLabelsService(tx *sql.Tx, companyID string, labels []Label) ([]BlockedLabel, error) {
+func (s *CompanyService) SaveCompanyLabelsService(tx *sql.Tx, companyID string, labels []Label) ([]BlockedLabel, error) {
 	if companyID == "" {
 		return nil, errors.New("company_id cannot be empty")
 	}
@@ -42,5 +50,5 @@ func SaveCompanyLabelsService(tx *sql.Tx, companyID string, labels []Label) ([]B
 		}
 	}
 
-	return ReplaceCompanyLabelsTx(tx, companyID, labels)
-}
\ No newline at end of file
+	return s.repo.ReplaceCompanyLabelsTx(tx, companyID, labels)
+}
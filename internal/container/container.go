@@ -0,0 +1,74 @@
+// Package container wires repositories, services and handlers together so
+// that the HTTP server, the admin CLI and tests share one construction path.
+package container
+
+import (
+	"database/sql"
+
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/internal/catalog"
+	"enerzyflow_backend/internal/companies"
+	"enerzyflow_backend/internal/orders"
+	"enerzyflow_backend/internal/users"
+)
+
+type Container struct {
+	UserRepo     users.UserRepository
+	CompanyRepo  companies.CompanyRepository
+	OrderRepo    orders.OrderRepository
+	CalendarRepo calendar.CalendarRepository
+	CatalogRepo  catalog.CatalogRepository
+
+	Users     *users.UserService
+	Companies *companies.CompanyService
+	Orders    *orders.OrderService
+	Calendar  *calendar.CalendarService
+	Catalog   *catalog.CatalogService
+
+	UserHandler     *users.UserHandler
+	OrderHandler    *orders.OrderHandler
+	CalendarHandler *calendar.CalendarHandler
+	CatalogHandler  *catalog.CatalogHandler
+}
+
+// New builds a container backed by Postgres.
+func New(conn *sql.DB) *Container {
+	return NewWithRepositories(
+		users.NewPostgresUserRepository(conn),
+		companies.NewPostgresCompanyRepository(conn),
+		orders.NewPostgresOrderRepository(conn),
+		calendar.NewPostgresCalendarRepository(conn),
+		catalog.NewPostgresCatalogRepository(conn),
+	)
+}
+
+// NewMemory builds a container backed by the in-memory repositories.
+func NewMemory() *Container {
+	return NewWithRepositories(
+		users.NewMemoryUserRepository(),
+		companies.NewMemoryCompanyRepository(),
+		orders.NewMemoryOrderRepository(),
+		calendar.NewMemoryCalendarRepository(),
+		catalog.NewMemoryCatalogRepository(),
+	)
+}
+
+func NewWithRepositories(userRepo users.UserRepository, companyRepo companies.CompanyRepository, orderRepo orders.OrderRepository, calendarRepo calendar.CalendarRepository, catalogRepo catalog.CatalogRepository) *Container {
+	c := &Container{
+		UserRepo:     userRepo,
+		CompanyRepo:  companyRepo,
+		OrderRepo:    orderRepo,
+		CalendarRepo: calendarRepo,
+		CatalogRepo:  catalogRepo,
+	}
+	c.Users = users.NewUserService(userRepo, companyRepo)
+	c.Companies = companies.NewCompanyService(companyRepo)
+	c.Calendar = calendar.NewCalendarService(calendarRepo, userRepo)
+	c.Catalog = catalog.NewCatalogService(catalogRepo)
+	c.Orders = orders.NewOrderService(orderRepo, companyRepo, userRepo, c.Calendar, c.Catalog)
+	c.UserHandler = users.NewUserHandler(c.Users)
+	c.OrderHandler = orders.NewOrderHandler(c.Orders)
+	c.CalendarHandler = calendar.NewCalendarHandler(c.Calendar)
+	c.CatalogHandler = catalog.NewCatalogHandler(c.Catalog)
+	return c
+}
@@ -0,0 +1,268 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the pg_advisory_lock key held while migrating, so
+// replicas starting together do not apply the same migration twice.
+const migrationLockID = 727_001
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// LoadMigrations reads the embedded NNNN_name.up.sql / NNNN_name.down.sql
+// pairs, ordered by version.
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		file := e.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(file, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(file, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(file, "."+direction+".sql")
+		num, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("migration %s: expected NNNN_name.%s.sql", file, direction)
+		}
+		version, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", file, err)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", file))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, name)
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// withMigrationLock runs fn on a single connection holding the migration
+// advisory lock.
+func withMigrationLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID); err != nil {
+			log.Printf("migrate: release lock: %v", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+		)
+	`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]time.Time{}
+	for rows.Next() {
+		var v int64
+		var at time.Time
+		if err := rows.Scan(&v, &at); err != nil {
+			return nil, err
+		}
+		applied[v] = at
+	}
+	return applied, rows.Err()
+}
+
+// runMigration executes one step and records it in schema_migrations in the
+// same transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, m Migration, up bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if up {
+		if _, err = tx.ExecContext(ctx, m.Up); err != nil {
+			return fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.Version, m.Name)
+	} else {
+		if m.Down == "" {
+			err = fmt.Errorf("migration %d_%s has no down file", m.Version, m.Name)
+			return err
+		}
+		if _, err = tx.ExecContext(ctx, m.Down); err != nil {
+			return fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Migrate applies every pending migration.
+func Migrate() error {
+	_, err := MigrateUp(context.Background())
+	return err
+}
+
+// MigrateUp applies pending migrations in order and returns the ones applied.
+func MigrateUp(ctx context.Context) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := applied[m.Version]; ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m, true); err != nil {
+				return err
+			}
+			log.Printf("migrate: applied %d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrateDown rolls back the latest steps applied migrations and returns
+// them, newest first.
+func MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			m := migrations[i]
+			if _, ok := applied[m.Version]; !ok {
+				continue
+			}
+			if err := runMigration(ctx, conn, m, false); err != nil {
+				return err
+			}
+			log.Printf("migrate: rolled back %d_%s", m.Version, m.Name)
+			done = append(done, m)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// MigrationStatuses lists every known migration and when it was applied.
+func MigrationStatuses(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var out []MigrationStatus
+	err = withMigrationLock(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			s := MigrationStatus{Version: m.Version, Name: m.Name}
+			if at, ok := applied[m.Version]; ok {
+				s.AppliedAt = &at
+			}
+			out = append(out, s)
+		}
+		return nil
+	})
+	return out, err
+}
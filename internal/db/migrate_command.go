@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RunMigrateCommand implements the `migrate up`, `migrate down [N]` and
+// `migrate status` subcommands shared by the server binary and enerzyctl.
+func RunMigrateCommand(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [N] | status")
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := MigrateUp(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Fprintln(out, "database is up to date")
+		}
+		return nil
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+			steps = n
+		}
+		rolledBack, err := MigrateDown(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(rolledBack) == 0 {
+			fmt.Fprintln(out, "nothing to roll back")
+		}
+		return nil
+
+	case "status":
+		statuses, err := MigrationStatuses(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d  %-24s %s\n", s.Version, s.Name, state)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS order_comments;
+DROP TABLE IF EXISTS order_label_details;
+DROP TABLE IF EXISTS order_assignments;
+DROP TABLE IF EXISTS order_status_history;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS labels;
+DROP TABLE IF EXISTS company_outlets;
+DROP TABLE IF EXISTS companies;
+DROP TABLE IF EXISTS users;
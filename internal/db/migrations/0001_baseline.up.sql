@@ -0,0 +1,117 @@
+-- Baseline schema as it existed before migrations were introduced. Every
+-- statement is idempotent so this can be applied to an existing database.
+
+CREATE TABLE IF NOT EXISTS users (
+    id           BIGSERIAL,
+    user_id      UUID PRIMARY KEY,
+    email        TEXT NOT NULL UNIQUE,
+    name         TEXT NOT NULL DEFAULT '',
+    phone        TEXT,
+    designation  TEXT NOT NULL DEFAULT '',
+    role         TEXT NOT NULL CHECK (role IN ('business_owner', 'printing', 'plant', 'admin')),
+    profile_url  TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users (role);
+CREATE INDEX IF NOT EXISTS idx_users_phone ON users (phone);
+
+CREATE TABLE IF NOT EXISTS companies (
+    company_id  UUID PRIMARY KEY,
+    user_id     UUID NOT NULL UNIQUE REFERENCES users (user_id) ON DELETE CASCADE,
+    name        TEXT NOT NULL DEFAULT '',
+    address     TEXT NOT NULL DEFAULT '',
+    logo        TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS company_outlets (
+    id          TEXT PRIMARY KEY,
+    company_id  UUID NOT NULL REFERENCES companies (company_id) ON DELETE CASCADE,
+    name        TEXT NOT NULL DEFAULT '',
+    address     TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_company_outlets_company ON company_outlets (company_id);
+
+CREATE TABLE IF NOT EXISTS labels (
+    label_id    TEXT PRIMARY KEY,
+    company_id  UUID NOT NULL REFERENCES companies (company_id) ON DELETE CASCADE,
+    name        TEXT NOT NULL DEFAULT '',
+    label_url   TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_labels_company ON labels (company_id);
+
+CREATE TABLE IF NOT EXISTS orders (
+    order_id                UUID PRIMARY KEY,
+    user_id                 UUID NOT NULL REFERENCES users (user_id),
+    label_id                TEXT NOT NULL REFERENCES labels (label_id),
+    variant                 TEXT NOT NULL,
+    qty                     INTEGER NOT NULL CHECK (qty > 0),
+    cap_color               TEXT NOT NULL,
+    volume                  INTEGER NOT NULL CHECK (volume > 0),
+    status                  TEXT NOT NULL DEFAULT 'placed',
+    payment_status          TEXT NOT NULL DEFAULT 'payment_pending',
+    payment_screenshot_url  TEXT NOT NULL DEFAULT '',
+    invoice_url             TEXT NOT NULL DEFAULT '',
+    pi_url                  TEXT NOT NULL DEFAULT '',
+    decline_reason          TEXT NOT NULL DEFAULT '',
+    expected_delivery_date  TIMESTAMPTZ NOT NULL,
+    created_at              TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at              TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user ON orders (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders (status);
+CREATE INDEX IF NOT EXISTS idx_orders_payment_status ON orders (payment_status);
+
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    status      TEXT NOT NULL,
+    changed_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    changed_by  TEXT NOT NULL DEFAULT '',
+    reason      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order ON order_status_history (order_id, changed_at);
+
+CREATE TABLE IF NOT EXISTS order_assignments (
+    id            BIGSERIAL PRIMARY KEY,
+    order_id      UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    user_id       UUID NOT NULL REFERENCES users (user_id),
+    role          TEXT NOT NULL CHECK (role IN ('printing', 'plant')),
+    assigned_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deadline      TIMESTAMPTZ NOT NULL,
+    completed_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_assignments_order ON order_assignments (order_id, role);
+CREATE INDEX IF NOT EXISTS idx_order_assignments_user ON order_assignments (user_id);
+
+CREATE TABLE IF NOT EXISTS order_label_details (
+    id                BIGSERIAL PRIMARY KEY,
+    order_id          UUID NOT NULL UNIQUE REFERENCES orders (order_id) ON DELETE CASCADE,
+    no_of_sheets      INTEGER NOT NULL,
+    cutting_type      TEXT NOT NULL,
+    labels_per_sheet  INTEGER NOT NULL,
+    description       TEXT NOT NULL DEFAULT '',
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS order_comments (
+    id          BIGSERIAL PRIMARY KEY,
+    order_id    UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users (user_id),
+    role        TEXT NOT NULL,
+    comment     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_comments_order ON order_comments (order_id, created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS otp_codes (
+    otp_key     TEXT PRIMARY KEY,
+    code_hash   TEXT NOT NULL,
+    expires_at  TIMESTAMPTZ NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_codes_expires ON otp_codes (expires_at);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS otp_throttle_events (
+    id            BIGSERIAL PRIMARY KEY,
+    throttle_key  TEXT NOT NULL,
+    occurred_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_throttle_events_key ON otp_throttle_events (throttle_key, occurred_at);
+
+CREATE TABLE IF NOT EXISTS otp_lockouts (
+    throttle_key  TEXT PRIMARY KEY,
+    locked_until  TIMESTAMPTZ NOT NULL,
+    level         INTEGER NOT NULL,
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,3 @@
+DELETE FROM otp_codes;
+
+ALTER TABLE otp_codes DROP COLUMN IF EXISTS salt;
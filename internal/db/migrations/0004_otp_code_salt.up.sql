@@ -0,0 +1,5 @@
+-- Pending codes were hashed without a salt and cannot be checked any more.
+-- They expire within minutes, so drop them instead of backfilling a salt.
+DELETE FROM otp_codes;
+
+ALTER TABLE otp_codes ADD COLUMN IF NOT EXISTS salt TEXT NOT NULL;
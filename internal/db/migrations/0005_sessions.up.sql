@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS sessions (
+    session_id          TEXT PRIMARY KEY,
+    user_id             UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    refresh_token_hash  TEXT NOT NULL,
+    user_agent          TEXT NOT NULL DEFAULT '',
+    ip                  TEXT NOT NULL DEFAULT '',
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at          TIMESTAMPTZ NOT NULL,
+    revoked_at          TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions (user_id);
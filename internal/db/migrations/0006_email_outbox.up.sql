@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS email_outbox (
+    id               BIGSERIAL PRIMARY KEY,
+    to_address       TEXT NOT NULL,
+    subject          TEXT NOT NULL,
+    html_body        TEXT NOT NULL DEFAULT '',
+    text_body        TEXT NOT NULL DEFAULT '',
+    sensitive        BOOLEAN NOT NULL DEFAULT FALSE,
+    status           TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'dead')),
+    attempts         INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until     TIMESTAMPTZ,
+    last_error       TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at          TIMESTAMPTZ,
+    updated_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox (next_attempt_at) WHERE status IN ('pending', 'sending');
+CREATE INDEX IF NOT EXISTS idx_email_outbox_status ON email_outbox (status, created_at DESC);
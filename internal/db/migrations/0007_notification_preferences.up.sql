@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS notification_settings (
+    user_id     UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    locale      TEXT NOT NULL DEFAULT 'en',
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id         UUID NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    event           TEXT NOT NULL,
+    email_enabled   BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, event)
+);
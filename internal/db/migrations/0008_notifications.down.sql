@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS notifications;
+
+ALTER TABLE notification_preferences DROP COLUMN IF EXISTS in_app_enabled;
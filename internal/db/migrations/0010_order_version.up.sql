@@ -0,0 +1,3 @@
+-- Optimistic concurrency token for orders. Every write to an order bumps
+-- it; PUT /orders/:id/status compares it against If-Match.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
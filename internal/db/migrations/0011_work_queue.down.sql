@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS order_pins;
+DROP TABLE IF EXISTS order_claims;
+ALTER TABLE orders DROP COLUMN IF EXISTS payment_verified_at;
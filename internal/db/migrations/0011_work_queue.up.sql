@@ -0,0 +1,32 @@
+-- Work queue for the printing and plant pools. A claim is a lease on the
+-- next eligible order that lapses unless the holder accepts the order
+-- before lease_expires_at; a pin reserves an order for one user.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS payment_verified_at TIMESTAMPTZ;
+
+UPDATE orders o
+SET payment_verified_at = (
+    SELECT MAX(h.changed_at)
+    FROM order_status_history h
+    WHERE h.order_id = o.order_id AND h.status = 'payment_verified'
+)
+WHERE o.payment_status = 'payment_verified' AND o.payment_verified_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS order_claims (
+    order_id          UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    role              TEXT NOT NULL CHECK (role IN ('printing', 'plant')),
+    user_id           UUID NOT NULL REFERENCES users (user_id),
+    claimed_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    lease_expires_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (order_id, role)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_claims_user ON order_claims (user_id, role);
+
+CREATE TABLE IF NOT EXISTS order_pins (
+    order_id   UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    role       TEXT NOT NULL CHECK (role IN ('printing', 'plant')),
+    user_id    UUID NOT NULL REFERENCES users (user_id),
+    pinned_by  TEXT NOT NULL DEFAULT '',
+    pinned_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (order_id, role)
+);
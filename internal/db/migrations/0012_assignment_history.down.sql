@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS order_assignment_history;
+DROP INDEX IF EXISTS idx_order_assignments_current;
+DELETE FROM order_assignments WHERE released_at IS NOT NULL;
+ALTER TABLE order_assignments DROP COLUMN IF EXISTS released_at;
@@ -0,0 +1,38 @@
+-- Assignments can be handed to another user or returned to the pool. The
+-- replaced row keeps its data and gets released_at; the current assignee
+-- of an order for a role is the row without it.
+ALTER TABLE order_assignments ADD COLUMN IF NOT EXISTS released_at TIMESTAMPTZ;
+
+UPDATE order_assignments a
+SET released_at = NOW()
+WHERE a.released_at IS NULL
+  AND EXISTS (
+      SELECT 1 FROM order_assignments b
+      WHERE b.order_id = a.order_id AND b.role = a.role AND b.released_at IS NULL AND b.id > a.id
+  );
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_order_assignments_current ON order_assignments (order_id, role) WHERE released_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS order_assignment_history (
+    id            BIGSERIAL PRIMARY KEY,
+    order_id      UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    role          TEXT NOT NULL CHECK (role IN ('printing', 'plant')),
+    action        TEXT NOT NULL CHECK (action IN ('assigned', 'reassigned', 'unassigned', 'deadline_extended', 'completed')),
+    from_user_id  UUID REFERENCES users (user_id),
+    to_user_id    UUID REFERENCES users (user_id),
+    deadline      TIMESTAMPTZ,
+    reason        TEXT NOT NULL DEFAULT '',
+    changed_by    TEXT NOT NULL DEFAULT '',
+    changed_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_assignment_history_order ON order_assignment_history (order_id, changed_at);
+
+INSERT INTO order_assignment_history (order_id, role, action, to_user_id, deadline, changed_by, changed_at)
+SELECT order_id, role, 'assigned', user_id, deadline, user_id::text, assigned_at
+FROM order_assignments;
+
+INSERT INTO order_assignment_history (order_id, role, action, from_user_id, deadline, changed_by, changed_at)
+SELECT order_id, role, 'completed', user_id, deadline, user_id::text, completed_at
+FROM order_assignments
+WHERE completed_at IS NOT NULL;
@@ -0,0 +1,14 @@
+-- Escalations the SLA monitor has sent to admins. The unique key includes
+-- the due time, so work whose deadline is extended is escalated again if
+-- it falls behind the new one.
+CREATE TABLE IF NOT EXISTS sla_escalations (
+    id            BIGSERIAL PRIMARY KEY,
+    order_id      UUID NOT NULL REFERENCES orders (order_id) ON DELETE CASCADE,
+    kind          TEXT NOT NULL CHECK (kind IN ('assignment_deadline', 'expected_delivery')),
+    role          TEXT NOT NULL DEFAULT '',
+    level         TEXT NOT NULL CHECK (level IN ('at_risk', 'breached')),
+    assignee_id   TEXT NOT NULL DEFAULT '',
+    due_at        TIMESTAMPTZ NOT NULL,
+    escalated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (order_id, kind, role, level, due_at)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS work_calendars;
+DROP TABLE IF EXISTS holidays;
@@ -0,0 +1,18 @@
+-- Business-day calendar. Holidays apply to every calendar. A work
+-- calendar sets the working weekdays and daily cutoff (HH:MM, IST) of the
+-- default schedule (calendar_id 'default') or of one printing or plant
+-- user; without a row the built-in Monday to Saturday, 17:00 applies.
+CREATE TABLE IF NOT EXISTS holidays (
+    holiday_date  DATE PRIMARY KEY,
+    name          TEXT NOT NULL,
+    created_by    TEXT NOT NULL DEFAULT '',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS work_calendars (
+    calendar_id   TEXT PRIMARY KEY,
+    working_days  TEXT NOT NULL,
+    cutoff        TEXT NOT NULL,
+    updated_by    TEXT NOT NULL DEFAULT '',
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,9 @@
+-- Daily capacity declared by printing users (label sheets) and plant users
+-- (bottles). Delivery quotes add up the capacity of each role; a row whose
+-- role no longer matches the user is ignored.
+CREATE TABLE IF NOT EXISTS work_capacities (
+    user_id         UUID PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    role            TEXT NOT NULL CHECK (role IN ('printing', 'plant')),
+    daily_capacity  INTEGER NOT NULL CHECK (daily_capacity >= 0),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
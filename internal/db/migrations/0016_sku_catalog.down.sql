@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_orders_sku;
+ALTER TABLE orders DROP COLUMN IF EXISTS sku_id;
+DROP TABLE IF EXISTS skus;
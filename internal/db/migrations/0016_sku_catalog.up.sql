@@ -0,0 +1,48 @@
+-- Product catalog. A SKU is a bottle variant in a volume with a cap color;
+-- variant and cap_color are stored lowercased and sku_id is derived from
+-- the combination (e.g. 'classic-500ml-blue').
+CREATE TABLE IF NOT EXISTS skus (
+    sku_id      TEXT PRIMARY KEY,
+    variant     TEXT NOT NULL,
+    volume_ml   INTEGER NOT NULL CHECK (volume_ml > 0),
+    cap_color   TEXT NOT NULL,
+    moq         INTEGER NOT NULL DEFAULT 1 CHECK (moq >= 1),
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (variant, volume_ml, cap_color)
+);
+
+-- Orders placed before the catalog keep a NULL sku_id unless their
+-- combination is backfilled below.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS sku_id TEXT REFERENCES skus (sku_id);
+CREATE INDEX IF NOT EXISTS idx_orders_sku ON orders (sku_id);
+
+-- Every combination already ordered becomes an active SKU, so existing
+-- products stay orderable until an admin reviews the catalog. Volume was
+-- not validated before, so orders without a positive volume are left
+-- unlinked. Combinations that differ only in punctuation or spacing
+-- ('a b' and 'a-b') share an ID; they become one SKU and all of their
+-- orders are linked to it.
+CREATE TEMP TABLE legacy_skus ON COMMIT DROP AS
+SELECT
+    order_id,
+    trim(both '-' from regexp_replace(lower(trim(variant)), '[^a-z0-9]+', '-', 'g'))
+        || '-' || volume || 'ml-' ||
+        trim(both '-' from regexp_replace(lower(trim(cap_color)), '[^a-z0-9]+', '-', 'g')) AS sku_id,
+    lower(trim(variant)) AS variant,
+    volume,
+    lower(trim(cap_color)) AS cap_color
+FROM orders
+WHERE volume > 0;
+
+INSERT INTO skus (sku_id, variant, volume_ml, cap_color)
+SELECT DISTINCT ON (sku_id) sku_id, variant, volume, cap_color
+FROM legacy_skus
+ORDER BY sku_id, variant, cap_color
+ON CONFLICT DO NOTHING;
+
+UPDATE orders o SET sku_id = l.sku_id
+FROM legacy_skus l
+JOIN skus s ON s.sku_id = l.sku_id
+WHERE o.order_id = l.order_id;
@@ -0,0 +1 @@
+-- The dropped bodies cannot be restored.
@@ -0,0 +1,10 @@
+-- OTP emails used to be queued in the outbox. Drop the codes still held by
+-- unsent or dead-lettered rows; they have long expired and can never be
+-- resent.
+UPDATE email_outbox
+SET html_body = '', text_body = '',
+    status = CASE WHEN status IN ('pending', 'sending') THEN 'dead' ELSE status END,
+    last_error = CASE WHEN status IN ('pending', 'sending') THEN 'sensitive body dropped' ELSE last_error END,
+    locked_until = NULL,
+    updated_at = NOW()
+WHERE sensitive AND (html_body <> '' OR text_body <> '');
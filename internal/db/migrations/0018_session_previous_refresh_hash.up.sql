@@ -0,0 +1,3 @@
+-- The hash a session's refresh token had before its last rotation. Only a
+-- replay of that token revokes the session; the session ID alone is public.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS previous_refresh_hash TEXT NOT NULL DEFAULT '';
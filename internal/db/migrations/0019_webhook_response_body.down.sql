@@ -0,0 +1,2 @@
+-- The dropped bodies cannot be restored.
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS response_body TEXT;
@@ -0,0 +1,3 @@
+-- Response bodies are no longer kept: they could echo back whatever the
+-- endpoint was able to reach.
+ALTER TABLE webhook_deliveries DROP COLUMN IF EXISTS response_body;
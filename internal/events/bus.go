@@ -0,0 +1,94 @@
+package events
+
+import (
+	"log"
+	"sync"
+)
+
+// Event is one message on the bus. Payload is JSON so an event can travel
+// through an external transport (e.g. Postgres NOTIFY) unchanged.
+type Event struct {
+	Topic   string
+	Payload []byte
+}
+
+// Bus fans events out to subscribers. Implementations must be safe for
+// concurrent use.
+type Bus interface {
+	Publish(ev Event) error
+	Subscribe(buffer int) *Subscription
+}
+
+// Subscription receives events on C until Close is called.
+type Subscription struct {
+	C      <-chan Event
+	cancel func()
+	once   sync.Once
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(s.cancel)
+}
+
+var defaultBus Bus = NewMemoryBus()
+
+// SetBus replaces the process-wide bus. Call it during startup, before any
+// subscriber is created.
+func SetBus(b Bus) {
+	defaultBus = b
+}
+
+func Default() Bus {
+	return defaultBus
+}
+
+func Publish(ev Event) error {
+	return defaultBus.Publish(ev)
+}
+
+func Subscribe(buffer int) *Subscription {
+	return defaultBus.Subscribe(buffer)
+}
+
+// MemoryBus delivers events to subscribers in this process only.
+type MemoryBus struct {
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{subs: make(map[chan Event]struct{})}
+}
+
+// Publish never blocks: a subscriber whose buffer is full misses the event.
+func (b *MemoryBus) Publish(ev Event) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+			log.Printf("events: dropped %s for slow subscriber", ev.Topic)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return &Subscription{
+		C: ch,
+		cancel: func() {
+			b.mu.Lock()
+			delete(b.subs, ch)
+			b.mu.Unlock()
+			close(ch)
+		},
+	}
+}
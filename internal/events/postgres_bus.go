@@ -0,0 +1,172 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	defaultChannel = "enerzyflow_events"
+
+	// MaxPayload is the largest event payload that fits in a NOTIFY message
+	// (8000 bytes) once wrapped in its envelope.
+	MaxPayload = 7000
+
+	listenRetryMin = time.Second
+	listenRetryMax = 30 * time.Second
+)
+
+var ErrPayloadTooLarge = errors.New("events: payload too large for NOTIFY")
+
+// TxPublisher is implemented by buses that can publish as part of a
+// database transaction, so the event is only seen if the transaction commits.
+type TxPublisher interface {
+	PublishTx(tx *sql.Tx, ev Event) error
+}
+
+type envelope struct {
+	Topic   string          `json:"topic"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// PostgresBus publishes events with pg_notify and runs a LISTEN connection
+// that hands every notification, including this instance's own, to local
+// subscribers. Every replica therefore sees the same stream of events.
+type PostgresBus struct {
+	db        *sql.DB
+	listenURL string
+	channel   string
+	local     *MemoryBus
+}
+
+// NewPostgresBus publishes through conn and listens on a dedicated
+// connection opened from listenURL. LISTEN needs a session, so listenURL
+// must not point at a transaction-mode pooler.
+func NewPostgresBus(conn *sql.DB, listenURL string) *PostgresBus {
+	return &PostgresBus{
+		db:        conn,
+		listenURL: listenURL,
+		channel:   defaultChannel,
+		local:     NewMemoryBus(),
+	}
+}
+
+// NewBusFromEnv picks the bus from EVENT_BUS: "memory" keeps events inside
+// this process, anything else uses Postgres LISTEN/NOTIFY. The listener
+// connects to DB_LISTEN_URL, falling back to DB_URL.
+func NewBusFromEnv(conn *sql.DB) Bus {
+	if strings.EqualFold(os.Getenv("EVENT_BUS"), "memory") {
+		return NewMemoryBus()
+	}
+	listenURL := os.Getenv("DB_LISTEN_URL")
+	if listenURL == "" {
+		listenURL = os.Getenv("DB_URL")
+	}
+	return NewPostgresBus(conn, listenURL)
+}
+
+func (b *PostgresBus) encode(ev Event) (string, error) {
+	if len(ev.Payload) > MaxPayload {
+		return "", ErrPayloadTooLarge
+	}
+	raw, err := json.Marshal(envelope{Topic: ev.Topic, Payload: ev.Payload})
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
+func (b *PostgresBus) Publish(ev Event) error {
+	msg, err := b.encode(ev)
+	if err != nil {
+		return err
+	}
+	_, err = b.db.Exec(`SELECT pg_notify($1, $2)`, b.channel, msg)
+	return err
+}
+
+// PublishTx queues the notification in tx; Postgres delivers it on commit
+// and drops it on rollback.
+func (b *PostgresBus) PublishTx(tx *sql.Tx, ev Event) error {
+	msg, err := b.encode(ev)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`SELECT pg_notify($1, $2)`, b.channel, msg)
+	return err
+}
+
+func (b *PostgresBus) Subscribe(buffer int) *Subscription {
+	return b.local.Subscribe(buffer)
+}
+
+// Start runs the listener until ctx is cancelled, reconnecting with backoff
+// when the connection drops. The backoff starts over once LISTEN succeeds.
+// Events published while disconnected are missed.
+func (b *PostgresBus) Start(ctx context.Context) {
+	go func() {
+		delay := listenRetryMin
+		for {
+			err := b.listen(ctx, func() { delay = listenRetryMin })
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("events: listener disconnected: %v; retrying in %s", err, delay)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay *= 2
+			if delay > listenRetryMax {
+				delay = listenRetryMax
+			}
+		}
+	}()
+}
+
+// listen calls listening once the LISTEN is in place and then relays
+// notifications until the connection fails.
+func (b *PostgresBus) listen(ctx context.Context, listening func()) error {
+	conn, err := pgx.Connect(ctx, b.listenURL)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+		return err
+	}
+	log.Printf("events: listening on %s", b.channel)
+	listening()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var env envelope
+		if err := json.Unmarshal([]byte(n.Payload), &env); err != nil {
+			log.Printf("events: ignoring malformed notification: %v", err)
+			continue
+		}
+		_ = b.local.Publish(Event{Topic: env.Topic, Payload: env.Payload})
+	}
+}
+
+// Start launches the background work of the configured bus, if it has any.
+func Start(ctx context.Context) {
+	if s, ok := defaultBus.(interface{ Start(context.Context) }); ok {
+		s.Start(ctx)
+	}
+}
@@ -0,0 +1,150 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/resendlabs/resend-go"
+	"github.com/sendgrid/sendgrid-go"
+	sgmail "github.com/sendgrid/sendgrid-go/helpers/mail"
+)
+
+type SMTPConfig struct {
+	From     string
+	Password string
+	Host     string
+	Port     string
+}
+
+type SMTPMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) (*SMTPMailer, error) {
+	if cfg.From == "" || cfg.Password == "" || cfg.Host == "" || cfg.Port == "" {
+		return nil, errors.New("mailer: SMTP_FROM, SMTP_PASSWORD, SMTP_HOST and SMTP_PORT must be set")
+	}
+	return &SMTPMailer{cfg: cfg}, nil
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	raw, err := buildMIME(m.cfg.From, msg)
+	if err != nil {
+		return err
+	}
+	auth := smtp.PlainAuth("", m.cfg.From, m.cfg.Password, m.cfg.Host)
+	return smtp.SendMail(m.cfg.Host+":"+m.cfg.Port, auth, m.cfg.From, msg.To, raw)
+}
+
+type SendGridMailer struct {
+	client *sendgrid.Client
+	from   string
+}
+
+func NewSendGridMailer(apiKey, from string) (*SendGridMailer, error) {
+	if apiKey == "" || from == "" {
+		return nil, errors.New("mailer: SENDGRID_API_KEY and SENDGRID_FROM must be set")
+	}
+	return &SendGridMailer{client: sendgrid.NewSendClient(apiKey), from: from}, nil
+}
+
+func (m *SendGridMailer) Send(msg Message) error {
+	message := sgmail.NewV3Mail()
+	message.SetFrom(sgmail.NewEmail("EnerzyFlow", m.from))
+	message.Subject = msg.Subject
+
+	p := sgmail.NewPersonalization()
+	for _, to := range msg.To {
+		p.AddTos(sgmail.NewEmail("", to))
+	}
+	message.AddPersonalizations(p)
+	if msg.Text != "" {
+		message.AddContent(sgmail.NewContent("text/plain", msg.Text))
+	}
+	if msg.HTML != "" {
+		message.AddContent(sgmail.NewContent("text/html", msg.HTML))
+	}
+
+	response, err := m.client.Send(message)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode >= 400 {
+		return fmt.Errorf("sendgrid error: status %d, body: %s", response.StatusCode, response.Body)
+	}
+	return nil
+}
+
+type ResendMailer struct {
+	client *resend.Client
+	from   string
+}
+
+func NewResendMailer(apiKey, from string) (*ResendMailer, error) {
+	if apiKey == "" {
+		return nil, errors.New("mailer: RESEND_API_KEY must be set")
+	}
+	return &ResendMailer{client: resend.NewClient(apiKey), from: from}, nil
+}
+
+func (m *ResendMailer) Send(msg Message) error {
+	_, err := m.client.Emails.Send(&resend.SendEmailRequest{
+		From:    m.from,
+		To:      msg.To,
+		Subject: msg.Subject,
+		Html:    msg.HTML,
+		Text:    msg.Text,
+	})
+	return err
+}
+
+// FileMailer writes every message as an .eml file, for local development and
+// tests where nothing should leave the machine.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("mailer: create %s: %w", dir, err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	raw, err := buildMIME(m.from, msg)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s_%s_%s.eml", time.Now().Format("20060102T150405"), safeFilePart(msg.To[0]), uuid.New().String()[:8])
+	return os.WriteFile(filepath.Join(m.dir, name), raw, 0o644)
+}
+
+func safeFilePart(addr string) string {
+	if a, err := mail.ParseAddress(addr); err == nil {
+		addr = a.Address
+	}
+	return strings.NewReplacer("@", "_at_", "/", "_", "\\", "_", " ", "_").Replace(addr)
+}
+
+// LogMailer only logs recipients and subject; it is the fallback when no
+// backend has been configured.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	log.Printf("mailer: to=%s subject=%q", strings.Join(msg.To, ","), msg.Subject)
+	return nil
+}
@@ -0,0 +1,114 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultFrom = "EnerzyFlow <no-reply@enerzyflow.com>"
+
+type Message struct {
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+	// Sensitive bodies (e.g. OTP codes) must not be kept after delivery.
+	Sensitive bool
+}
+
+// Mailer delivers a single message. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+var defaultMailer Mailer = NewLogMailer()
+
+func SetDefault(m Mailer) {
+	defaultMailer = m
+}
+
+// Send delivers msg with the mailer configured at startup.
+func Send(msg Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("mailer: message has no recipients")
+	}
+	return defaultMailer.Send(msg)
+}
+
+func fromAddress() string {
+	if v := os.Getenv("MAIL_FROM"); v != "" {
+		return v
+	}
+	return defaultFrom
+}
+
+// FromEnv builds the mailer selected by MAIL_BACKEND: resend (default),
+// sendgrid, smtp, file or log.
+func FromEnv() (Mailer, error) {
+	backend := strings.ToLower(os.Getenv("MAIL_BACKEND"))
+	switch backend {
+	case "", "resend":
+		return NewResendMailer(os.Getenv("RESEND_API_KEY"), fromAddress())
+	case "sendgrid":
+		return NewSendGridMailer(os.Getenv("SENDGRID_API_KEY"), os.Getenv("SENDGRID_FROM"))
+	case "smtp":
+		return NewSMTPMailer(SMTPConfig{
+			From:     os.Getenv("SMTP_FROM"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     os.Getenv("SMTP_PORT"),
+		})
+	case "file":
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = "tmp/mail"
+		}
+		return NewFileMailer(dir, fromAddress())
+	case "log":
+		return NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("mailer: unknown MAIL_BACKEND %q", backend)
+	}
+}
+
+// buildMIME renders msg as a multipart/alternative RFC 5322 message.
+func buildMIME(from string, msg Message) ([]byte, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	if msg.Text != "" {
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {`text/plain; charset="UTF-8"`}})
+		if err != nil {
+			return nil, err
+		}
+		part.Write([]byte(msg.Text))
+	}
+	if msg.HTML != "" {
+		part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {`text/html; charset="UTF-8"`}})
+		if err != nil {
+			return nil, err
+		}
+		part.Write([]byte(msg.HTML))
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "From: %s\r\n", from)
+	fmt.Fprintf(&out, "To: %s\r\n", strings.Join(msg.To, ", "))
+	fmt.Fprintf(&out, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", msg.Subject))
+	fmt.Fprintf(&out, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&out, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&out, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", w.Boundary())
+	out.Write(body.Bytes())
+	return out.Bytes(), nil
+}
@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func ListTemplatesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"templates": TemplateNames(),
+		"locales":   Locales(),
+	})
+}
+
+// PreviewTemplateHandler renders a template with sample data. Use
+// ?locale=hi to pick a translation and ?format=text|subject for the
+// plain-text alternative or the subject line.
+func PreviewTemplateHandler(c *gin.Context) {
+	name := c.Param("name")
+	data, ok := SampleData(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "template not found"})
+		return
+	}
+
+	msg, err := Render(name, c.DefaultQuery("locale", DefaultLocale), data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch c.DefaultQuery("format", "html") {
+	case "html":
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(msg.HTML))
+	case "text":
+		c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(msg.Text))
+	case "subject":
+		c.JSON(http.StatusOK, gin.H{"subject": msg.Subject})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be html, text or subject"})
+	}
+}
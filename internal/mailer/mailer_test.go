@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"bytes"
+	"mime"
+	"net/mail"
+	"testing"
+)
+
+func TestBuildMIMEEncodesSubject(t *testing.T) {
+	for _, subject := range []string{"Your order was dispatched", "आपका ऑर्डर भेज दिया गया है"} {
+		raw, err := buildMIME(defaultFrom, Message{To: []string{"a@example.com"}, Subject: subject, Text: "hi"})
+		if err != nil {
+			t.Fatalf("buildMIME: %v", err)
+		}
+		msg, err := mail.ReadMessage(bytes.NewReader(raw))
+		if err != nil {
+			t.Fatalf("read message: %v", err)
+		}
+		header := msg.Header.Get("Subject")
+		for i := 0; i < len(header); i++ {
+			if header[i] >= 0x80 {
+				t.Fatalf("subject header %q is not ASCII", header)
+			}
+		}
+		got, err := new(mime.WordDecoder).DecodeHeader(header)
+		if err != nil {
+			t.Fatalf("decode subject: %v", err)
+		}
+		if got != subject {
+			t.Errorf("subject = %q, want %q", got, subject)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+const (
+	TemplateOTP             = "otp"
+	TemplateEnquiry         = "enquiry"
+	TemplateOrderStatus     = "order_status"
+	TemplatePaymentResult   = "payment_result"
+	TemplateInvoiceReady    = "invoice_ready"
+	TemplateNewWork         = "new_work"
+	TemplatePaymentUploaded = "payment_uploaded"
+	TemplateSLAEscalation   = "sla_escalation"
+)
+
+const DefaultLocale = "en"
+
+var templateNames = []string{
+	TemplateOTP, TemplateEnquiry, TemplateOrderStatus, TemplatePaymentResult, TemplateInvoiceReady,
+	TemplateNewWork, TemplatePaymentUploaded, TemplateSLAEscalation,
+}
+
+var supportedLocales = []string{"en", "hi"}
+
+type OTPData struct {
+	Code       string
+	TTLMinutes int
+}
+
+type EnquiryData struct {
+	Name             string
+	Phone            string
+	City             string
+	LogisticSupports string
+}
+
+type OrderStatusData struct {
+	Name             string
+	OrderID          string
+	Status           string
+	Reason           string
+	ExpectedDelivery time.Time
+}
+
+type PaymentResultData struct {
+	Name     string
+	OrderID  string
+	Verified bool
+	Reason   string
+}
+
+type InvoiceReadyData struct {
+	Name       string
+	OrderID    string
+	InvoiceURL string
+	PiURL      string
+}
+
+type NewWorkData struct {
+	Name    string
+	OrderID string
+	Stage   string
+	Qty     int
+	Variant string
+}
+
+type PaymentUploadedData struct {
+	Name      string
+	OrderID   string
+	OwnerName string
+}
+
+// SLAEscalationData is sent to admins. Kind is "assignment_deadline", with
+// Stage set to printing or plant, or "expected_delivery".
+type SLAEscalationData struct {
+	Name     string
+	OrderID  string
+	Kind     string
+	Stage    string
+	Breached bool
+	DueAt    time.Time
+}
+
+var statusLabels = map[string]map[string]string{
+	"en": {
+		"placed":           "Placed",
+		"printing":         "Printing",
+		"ready_for_plant":  "Ready for Plant",
+		"plant_processing": "In Production",
+		"dispatched":       "Dispatched",
+		"completed":        "Completed",
+		"declined":         "Declined",
+		"payment_uploaded": "Payment Uploaded",
+		"payment_verified": "Payment Verified",
+		"payment_rejected": "Payment Rejected",
+	},
+	"hi": {
+		"placed":           "प्राप्त",
+		"printing":         "प्रिंटिंग में",
+		"ready_for_plant":  "प्लांट के लिए तैयार",
+		"plant_processing": "उत्पादन में",
+		"dispatched":       "भेजा गया",
+		"completed":        "पूर्ण",
+		"declined":         "अस्वीकृत",
+		"payment_uploaded": "भुगतान अपलोड किया गया",
+		"payment_verified": "भुगतान सत्यापित",
+		"payment_rejected": "भुगतान अस्वीकृत",
+	},
+}
+
+type localizedTemplate struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// registry holds every template parsed once at startup, keyed by locale/name.
+var registry = mustLoadTemplates()
+
+func templateFuncs(locale string) map[string]interface{} {
+	return map[string]interface{}{
+		"statusLabel": func(status string) string {
+			if l, ok := statusLabels[locale][status]; ok {
+				return l
+			}
+			return status
+		},
+		"shortID": func(id string) string {
+			if len(id) > 8 {
+				id = id[:8]
+			}
+			return "#" + strings.ToUpper(id)
+		},
+	}
+}
+
+func mustLoadTemplates() map[string]localizedTemplate {
+	out := make(map[string]localizedTemplate)
+	for _, locale := range supportedLocales {
+		funcs := templateFuncs(locale)
+		for _, name := range templateNames {
+			h := htmltemplate.Must(htmltemplate.New("layout.html").Funcs(funcs).ParseFS(templateFS,
+				"templates/layout.html",
+				"templates/"+locale+"/footer.html",
+				"templates/"+locale+"/"+name+".html",
+			))
+			t := texttemplate.Must(texttemplate.New("layout.txt").Funcs(funcs).ParseFS(templateFS,
+				"templates/layout.txt",
+				"templates/"+locale+"/footer.txt",
+				"templates/"+locale+"/"+name+".txt",
+			))
+			out[locale+"/"+name] = localizedTemplate{html: h, text: t}
+		}
+	}
+	return out
+}
+
+// NormalizeLocale maps a requested locale such as "hi-IN" to a supported one,
+// falling back to English.
+func NormalizeLocale(locale string) string {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		locale = locale[:i]
+	}
+	for _, l := range supportedLocales {
+		if l == locale {
+			return l
+		}
+	}
+	return DefaultLocale
+}
+
+func TemplateNames() []string {
+	names := append([]string(nil), templateNames...)
+	sort.Strings(names)
+	return names
+}
+
+func Locales() []string {
+	return append([]string(nil), supportedLocales...)
+}
+
+// Render executes the named template in locale and returns a message with
+// subject, HTML and plain-text bodies filled in. All user-supplied values
+// are escaped by html/template.
+func Render(name, locale string, data interface{}) (Message, error) {
+	locale = NormalizeLocale(locale)
+	tpl, ok := registry[locale+"/"+name]
+	if !ok {
+		return Message{}, fmt.Errorf("mailer: unknown template %q", name)
+	}
+
+	layoutData := struct {
+		Locale string
+		Data   interface{}
+	}{Locale: locale, Data: data}
+
+	var subject, text bytes.Buffer
+	var html bytes.Buffer
+	if err := tpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, err
+	}
+	if err := tpl.text.ExecuteTemplate(&text, "layout", layoutData); err != nil {
+		return Message{}, err
+	}
+	if err := tpl.html.ExecuteTemplate(&html, "layout", layoutData); err != nil {
+		return Message{}, err
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+// SampleData returns representative data for previewing a template.
+func SampleData(name string) (interface{}, bool) {
+	expected := time.Date(2025, time.November, 14, 0, 0, 0, 0, time.UTC)
+	switch name {
+	case TemplateOTP:
+		return OTPData{Code: "482913", TTLMinutes: 5}, true
+	case TemplateEnquiry:
+		return EnquiryData{Name: "Asha Verma", Phone: "+91 98765 43210", City: "Pune", LogisticSupports: "Own transport"}, true
+	case TemplateOrderStatus:
+		return OrderStatusData{Name: "Asha", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", Status: "dispatched", ExpectedDelivery: expected}, true
+	case TemplatePaymentResult:
+		return PaymentResultData{Name: "Asha", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", Verified: false, Reason: "Amount does not match the proforma invoice"}, true
+	case TemplateInvoiceReady:
+		return InvoiceReadyData{Name: "Asha", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", InvoiceURL: "https://example.com/invoice.pdf", PiURL: "https://example.com/pi.pdf"}, true
+	case TemplateNewWork:
+		return NewWorkData{Name: "Ravi", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", Stage: "printing", Qty: 2400, Variant: "classic"}, true
+	case TemplatePaymentUploaded:
+		return PaymentUploadedData{Name: "Admin", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", OwnerName: "Asha Verma"}, true
+	case TemplateSLAEscalation:
+		return SLAEscalationData{Name: "Admin", OrderID: "3f2b9c1e-7a4d-4c1b-9a55-2d1e8f0c6b7a", Kind: "assignment_deadline", Stage: "printing", Breached: true, DueAt: expected}, true
+	default:
+		return nil, false
+	}
+}
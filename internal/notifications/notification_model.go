@@ -0,0 +1,81 @@
+package notifications
+
+import "time"
+
+// Preference events a user can opt out of. Which ones apply depends on role.
+const (
+	PrefOrderStatus     = "order_status"     // business owner: order status changes
+	PrefPaymentResult   = "payment_result"   // business owner: payment verified / rejected
+	PrefInvoiceReady    = "invoice_ready"    // business owner: invoice or proforma uploaded
+	PrefNewWork         = "new_work"         // printing and plant pools
+	PrefPaymentUploaded = "payment_uploaded" // admins
+	PrefOrderComment    = "order_comment"    // admins and assignees
+	PrefSLAEscalation   = "sla_escalation"   // admins
+)
+
+var preferencesByRole = map[string][]string{
+	"business_owner": {PrefOrderStatus, PrefPaymentResult, PrefInvoiceReady},
+	"printing":       {PrefNewWork, PrefOrderComment},
+	"plant":          {PrefNewWork, PrefOrderComment},
+	"admin":          {PrefPaymentUploaded, PrefOrderComment, PrefSLAEscalation},
+}
+
+// Notification types stored in the in-app inbox.
+const (
+	TypeStatusChanged       = "order_status_changed"
+	TypePaymentVerified     = "payment_verified"
+	TypePaymentRejected     = "payment_rejected"
+	TypePaymentUploaded     = "payment_uploaded"
+	TypeInvoiceReady        = "invoice_ready"
+	TypeNewWork             = "new_work"
+	TypeCommentAdded        = "order_comment"
+	TypeSLAEscalation       = "sla_escalation"
+	TypeDeliveryRescheduled = "delivery_rescheduled"
+)
+
+type Recipient struct {
+	UserID string
+	Email  string
+	Name   string
+	Locale string
+}
+
+type orderContext struct {
+	Owner            Recipient
+	ExpectedDelivery time.Time
+	Qty              int
+	Variant          string
+}
+
+type Notification struct {
+	ID        int64      `json:"id"`
+	UserID    string     `json:"user_id"`
+	Type      string     `json:"type"`
+	Title     string     `json:"title"`
+	Body      string     `json:"body"`
+	OrderID   string     `json:"order_id,omitempty"`
+	Link      string     `json:"link,omitempty"`
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type NotificationListResponse struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int            `json:"total"`
+	Unread        int            `json:"unread"`
+}
+
+type EventPreference struct {
+	Email bool `json:"email"`
+	InApp bool `json:"in_app"`
+}
+
+type PreferencesResponse struct {
+	Locale string                     `json:"locale"`
+	Events map[string]EventPreference `json:"events"`
+}
+
+type UpdatePreferencesRequest struct {
+	Locale string                     `json:"locale"`
+	Events map[string]EventPreference `json:"events"`
+}
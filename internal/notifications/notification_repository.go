@@ -0,0 +1,200 @@
+package notifications
+
+import (
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+)
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// getOrderContextTx loads the order owner (with their locale) and the order
+// fields used in notification emails.
+func getOrderContextTx(tx *sql.Tx, orderID string) (*orderContext, error) {
+	var oc orderContext
+	var expected sql.NullTime
+	err := tx.QueryRow(`
+		SELECT u.user_id, u.email, COALESCE(u.name, ''), COALESCE(ns.locale, 'en'),
+		       o.expected_delivery_date, o.qty, o.variant
+		FROM orders o
+		INNER JOIN users u ON o.user_id = u.user_id
+		LEFT JOIN notification_settings ns ON ns.user_id = u.user_id
+		WHERE o.order_id = $1
+	`, orderID).Scan(&oc.Owner.UserID, &oc.Owner.Email, &oc.Owner.Name, &oc.Owner.Locale,
+		&expected, &oc.Qty, &oc.Variant)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if expected.Valid {
+		oc.ExpectedDelivery = expected.Time
+	}
+	return &oc, nil
+}
+
+func listRecipientsByRole(q queryer, role string) ([]Recipient, error) {
+	rows, err := q.Query(`
+		SELECT u.user_id, u.email, COALESCE(u.name, ''), COALESCE(ns.locale, 'en')
+		FROM users u
+		LEFT JOIN notification_settings ns ON ns.user_id = u.user_id
+		WHERE u.role = $1
+	`, role)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecipients(rows)
+}
+
+// listOrderAssignees returns the printing and plant users currently assigned
+// to an order.
+func listOrderAssignees(q queryer, orderID string) ([]Recipient, error) {
+	rows, err := q.Query(`
+		SELECT DISTINCT u.user_id, u.email, COALESCE(u.name, ''), COALESCE(ns.locale, 'en')
+		FROM order_assignments oa
+		INNER JOIN users u ON oa.user_id = u.user_id
+		LEFT JOIN notification_settings ns ON ns.user_id = u.user_id
+		WHERE oa.order_id = $1 AND oa.released_at IS NULL
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	return scanRecipients(rows)
+}
+
+func scanRecipients(rows *sql.Rows) ([]Recipient, error) {
+	defer rows.Close()
+
+	var out []Recipient
+	for rows.Next() {
+		var r Recipient
+		if err := rows.Scan(&r.UserID, &r.Email, &r.Name, &r.Locale); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// channelPreferences returns whether userID wants pref by email and in-app;
+// both default to on.
+func channelPreferences(q queryer, userID, pref string) (email bool, inApp bool, err error) {
+	err = q.QueryRow(`
+		SELECT COALESCE(MAX(email_enabled::int), 1) = 1, COALESCE(MAX(in_app_enabled::int), 1) = 1
+		FROM notification_preferences
+		WHERE user_id = $1 AND event = $2
+	`, userID, pref).Scan(&email, &inApp)
+	return email, inApp, err
+}
+
+func GetPreferences(userID string) (string, map[string]EventPreference, error) {
+	locale := "en"
+	err := db.DB.QueryRow(`SELECT locale FROM notification_settings WHERE user_id = $1`, userID).Scan(&locale)
+	if err != nil && err != sql.ErrNoRows {
+		return "", nil, err
+	}
+
+	rows, err := db.DB.Query(`SELECT event, email_enabled, in_app_enabled FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]EventPreference)
+	for rows.Next() {
+		var event string
+		var p EventPreference
+		if err := rows.Scan(&event, &p.Email, &p.InApp); err != nil {
+			return "", nil, err
+		}
+		prefs[event] = p
+	}
+	return locale, prefs, rows.Err()
+}
+
+func SavePreferencesTx(tx *sql.Tx, userID, locale string, prefs map[string]EventPreference) error {
+	if _, err := tx.Exec(`
+		INSERT INTO notification_settings (user_id, locale, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (user_id) DO UPDATE SET locale = EXCLUDED.locale, updated_at = NOW()
+	`, userID, locale); err != nil {
+		return err
+	}
+	for event, p := range prefs {
+		if _, err := tx.Exec(`
+			INSERT INTO notification_preferences (user_id, event, email_enabled, in_app_enabled, updated_at)
+			VALUES ($1, $2, $3, $4, NOW())
+			ON CONFLICT (user_id, event) DO UPDATE
+			SET email_enabled = EXCLUDED.email_enabled, in_app_enabled = EXCLUDED.in_app_enabled, updated_at = NOW()
+		`, userID, event, p.Email, p.InApp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertNotificationTx(tx *sql.Tx, n Notification) error {
+	_, err := tx.Exec(`
+		INSERT INTO notifications (user_id, type, title, body, order_id, link, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, ''), NOW())
+	`, n.UserID, n.Type, n.Title, n.Body, n.OrderID, n.Link)
+	return err
+}
+
+func ListNotifications(userID string, unreadOnly bool, limit, offset int) ([]Notification, int, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, user_id, type, title, body, COALESCE(order_id::text, ''), COALESCE(link, ''), read_at, created_at,
+		       COUNT(*) OVER() AS total_count
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR read_at IS NULL)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		out   []Notification
+		total int
+	)
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.Title, &n.Body, &n.OrderID, &n.Link,
+			&n.ReadAt, &n.CreatedAt, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, n)
+	}
+	return out, total, rows.Err()
+}
+
+func CountUnread(userID string) (int, error) {
+	var n int
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
+
+func MarkRead(id int64, userID string) (bool, error) {
+	res, err := db.DB.Exec(`
+		UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`, id, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func MarkAllRead(userID string) (int64, error) {
+	res, err := db.DB.Exec(`UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`, userID)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,430 @@
+package notifications
+
+import (
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/orders"
+	"enerzyflow_backend/internal/outbox"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// Register subscribes the notification subsystem to order events. Call once
+// at startup.
+func Register() {
+	orders.RegisterEventHook(OnOrderEvent)
+	// emails queued by OnOrderEvent become visible when the change commits
+	orders.RegisterCommitHook(outbox.Wake)
+}
+
+// OnOrderEvent runs inside the status-history transaction and writes the
+// inbox entries and outbox emails for it, so nothing is delivered for a
+// rolled-back change.
+func OnOrderEvent(tx *sql.Tx, ev orders.OrderEvent) error {
+	switch ev.Type {
+	case orders.EventStatusChanged:
+		if err := notifyOwnerStatus(tx, ev); err != nil {
+			return err
+		}
+		if ev.Status == "ready_for_plant" {
+			return notifyPool(tx, ev, "plant")
+		}
+
+	case orders.EventPaymentUpdated:
+		switch ev.Status {
+		case "payment_verified":
+			if err := notifyOwnerPayment(tx, ev, true); err != nil {
+				return err
+			}
+			return notifyPool(tx, ev, "printing")
+		case "payment_rejected":
+			return notifyOwnerPayment(tx, ev, false)
+		case "payment_uploaded":
+			return notifyAdminsPaymentUploaded(tx, ev)
+		}
+
+	case orders.EventInvoiceUploaded:
+		return notifyOwnerInvoice(tx, ev)
+
+	case orders.EventCommentAdded:
+		return notifyComment(tx, ev)
+
+	case orders.EventSLAEscalated:
+		return notifyAdminsSLA(tx, ev)
+
+	case orders.EventDeliveryRescheduled:
+		return notifyOwnerDeliveryMoved(tx, ev)
+	}
+	return nil
+}
+
+// email describes an optional email to send alongside an inbox entry.
+type email struct {
+	template string
+	data     interface{}
+}
+
+// deliver writes the inbox entry and, when mail is non-nil, queues the email,
+// each subject to the recipient's channel preferences for pref.
+func deliver(tx *sql.Tx, r Recipient, pref string, n Notification, mail *email) error {
+	emailOn, inAppOn, err := channelPreferences(tx, r.UserID, pref)
+	if err != nil {
+		return err
+	}
+	if inAppOn {
+		n.UserID = r.UserID
+		if n.OrderID != "" {
+			n.Link = orderLink(n.OrderID)
+		}
+		if err := insertNotificationTx(tx, n); err != nil {
+			return err
+		}
+	}
+	if mail == nil || !emailOn {
+		return nil
+	}
+	msg, err := mailer.Render(mail.template, r.Locale, mail.data)
+	if err != nil {
+		return err
+	}
+	msg.To = []string{r.Email}
+	return outbox.EnqueueTx(tx, msg)
+}
+
+func orderLink(orderID string) string {
+	return "/orders/" + orderID
+}
+
+func shortOrderID(orderID string) string {
+	if len(orderID) > 8 {
+		return orderID[:8]
+	}
+	return orderID
+}
+
+func statusText(status string) string {
+	return strings.ReplaceAll(status, "_", " ")
+}
+
+// notifyOwnerStatus puts every status change in the owner's inbox; only
+// dispatch and decline are also emailed.
+func notifyOwnerStatus(tx *sql.Tx, ev orders.OrderEvent) error {
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+
+	n := Notification{
+		Type:    TypeStatusChanged,
+		Title:   fmt.Sprintf("Order %s is now %s", shortOrderID(ev.OrderID), statusText(ev.Status)),
+		Body:    ev.Reason,
+		OrderID: ev.OrderID,
+	}
+	var mail *email
+	if ev.Status == "dispatched" || ev.Status == "declined" {
+		mail = &email{mailer.TemplateOrderStatus, mailer.OrderStatusData{
+			Name:             oc.Owner.Name,
+			OrderID:          ev.OrderID,
+			Status:           ev.Status,
+			Reason:           ev.Reason,
+			ExpectedDelivery: oc.ExpectedDelivery,
+		}}
+	}
+	return deliver(tx, oc.Owner, PrefOrderStatus, n, mail)
+}
+
+func notifyOwnerPayment(tx *sql.Tx, ev orders.OrderEvent, verified bool) error {
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+
+	n := Notification{
+		Type:    TypePaymentVerified,
+		Title:   fmt.Sprintf("Payment verified for order %s", shortOrderID(ev.OrderID)),
+		OrderID: ev.OrderID,
+	}
+	if !verified {
+		n.Type = TypePaymentRejected
+		n.Title = fmt.Sprintf("Payment rejected for order %s", shortOrderID(ev.OrderID))
+		n.Body = ev.Reason
+	}
+	return deliver(tx, oc.Owner, PrefPaymentResult, n, &email{mailer.TemplatePaymentResult, mailer.PaymentResultData{
+		Name:     oc.Owner.Name,
+		OrderID:  ev.OrderID,
+		Verified: verified,
+		Reason:   ev.Reason,
+	}})
+}
+
+// notifyOwnerInvoice tells the owner that an invoice or proforma invoice
+// was uploaded, with links to the documents.
+func notifyOwnerInvoice(tx *sql.Tx, ev orders.OrderEvent) error {
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+
+	n := Notification{
+		Type:    TypeInvoiceReady,
+		Title:   fmt.Sprintf("Invoice ready for order %s", shortOrderID(ev.OrderID)),
+		OrderID: ev.OrderID,
+	}
+	return deliver(tx, oc.Owner, PrefInvoiceReady, n, &email{mailer.TemplateInvoiceReady, mailer.InvoiceReadyData{
+		Name:       oc.Owner.Name,
+		OrderID:    ev.OrderID,
+		InvoiceURL: ev.InvoiceURL,
+		PiURL:      ev.PiURL,
+	}})
+}
+
+// notifyPool tells every printing or plant user that an order entered their
+// queue.
+func notifyPool(tx *sql.Tx, ev orders.OrderEvent, role string) error {
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+	recipients, err := listRecipientsByRole(tx, role)
+	if err != nil {
+		return err
+	}
+	for _, r := range recipients {
+		n := Notification{
+			Type:    TypeNewWork,
+			Title:   fmt.Sprintf("New %s work: order %s", role, shortOrderID(ev.OrderID)),
+			Body:    fmt.Sprintf("%d x %s", oc.Qty, oc.Variant),
+			OrderID: ev.OrderID,
+		}
+		if err := deliver(tx, r, PrefNewWork, n, &email{mailer.TemplateNewWork, mailer.NewWorkData{
+			Name:    r.Name,
+			OrderID: ev.OrderID,
+			Stage:   role,
+			Qty:     oc.Qty,
+			Variant: oc.Variant,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func notifyAdminsPaymentUploaded(tx *sql.Tx, ev orders.OrderEvent) error {
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+	admins, err := listRecipientsByRole(tx, "admin")
+	if err != nil {
+		return err
+	}
+	for _, r := range admins {
+		n := Notification{
+			Type:    TypePaymentUploaded,
+			Title:   fmt.Sprintf("Payment uploaded for order %s", shortOrderID(ev.OrderID)),
+			Body:    fmt.Sprintf("%s uploaded a payment screenshot", oc.Owner.Name),
+			OrderID: ev.OrderID,
+		}
+		if err := deliver(tx, r, PrefPaymentUploaded, n, &email{mailer.TemplatePaymentUploaded, mailer.PaymentUploadedData{
+			Name:      r.Name,
+			OrderID:   ev.OrderID,
+			OwnerName: oc.Owner.Name,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyOwnerDeliveryMoved puts a stalled order's new expected delivery
+// date in the owner's inbox.
+func notifyOwnerDeliveryMoved(tx *sql.Tx, ev orders.OrderEvent) error {
+	if ev.ExpectedDelivery == nil {
+		return nil
+	}
+	oc, err := getOrderContextTx(tx, ev.OrderID)
+	if err != nil || oc == nil {
+		return err
+	}
+
+	n := Notification{
+		Type:    TypeDeliveryRescheduled,
+		Title:   fmt.Sprintf("Order %s: new expected delivery %s", shortOrderID(ev.OrderID), ev.ExpectedDelivery.Format("02 Jan 2006")),
+		Body:    "Your order is taking longer than planned.",
+		OrderID: ev.OrderID,
+	}
+	return deliver(tx, oc.Owner, PrefOrderStatus, n, nil)
+}
+
+// notifyAdminsSLA tells admins that an assignment deadline or expected
+// delivery date is at risk or has been missed.
+func notifyAdminsSLA(tx *sql.Tx, ev orders.OrderEvent) error {
+	if ev.Deadline == nil {
+		return nil
+	}
+	admins, err := listRecipientsByRole(tx, "admin")
+	if err != nil {
+		return err
+	}
+
+	what := "expected delivery"
+	if ev.SLAKind == orders.SLAKindAssignment {
+		what = ev.ActorRole + " deadline"
+	}
+	state := "at risk"
+	if ev.Action == orders.SLALevelBreached {
+		state = "missed"
+	}
+	for _, r := range admins {
+		n := Notification{
+			Type:    TypeSLAEscalation,
+			Title:   fmt.Sprintf("Order %s: %s %s", shortOrderID(ev.OrderID), what, state),
+			Body:    fmt.Sprintf("Due %s", ev.Deadline.Format("02 Jan 2006 15:04")),
+			OrderID: ev.OrderID,
+		}
+		if err := deliver(tx, r, PrefSLAEscalation, n, &email{mailer.TemplateSLAEscalation, mailer.SLAEscalationData{
+			Name:     r.Name,
+			OrderID:  ev.OrderID,
+			Kind:     ev.SLAKind,
+			Stage:    ev.ActorRole,
+			Breached: ev.Action == orders.SLALevelBreached,
+			DueAt:    *ev.Deadline,
+		}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyComment puts new comments in the inbox of admins and of the users
+// assigned to the order, skipping the author. Comments are not emailed.
+// Printing and plant users can only read their own comments, so assignees
+// are told there is a comment but not what it says.
+func notifyComment(tx *sql.Tx, ev orders.OrderEvent) error {
+	admins, err := listRecipientsByRole(tx, "admin")
+	if err != nil {
+		return err
+	}
+	assignees, err := listOrderAssignees(tx, ev.OrderID)
+	if err != nil {
+		return err
+	}
+
+	n := Notification{
+		Type:    TypeCommentAdded,
+		Title:   fmt.Sprintf("New comment on order %s", shortOrderID(ev.OrderID)),
+		Body:    ev.Comment,
+		OrderID: ev.OrderID,
+	}
+	seen := map[string]bool{ev.ChangedBy: true}
+	for _, r := range admins {
+		if seen[r.UserID] {
+			continue
+		}
+		seen[r.UserID] = true
+		if err := deliver(tx, r, PrefOrderComment, n, nil); err != nil {
+			return err
+		}
+	}
+
+	n.Body = ""
+	for _, r := range assignees {
+		if seen[r.UserID] {
+			continue
+		}
+		seen[r.UserID] = true
+		if err := deliver(tx, r, PrefOrderComment, n, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ListNotificationsService(userID string, unreadOnly bool, limit, offset int) (*NotificationListResponse, error) {
+	items, total, err := ListNotifications(userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	unread, err := CountUnread(userID)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []Notification{}
+	}
+	return &NotificationListResponse{Notifications: items, Total: total, Unread: unread}, nil
+}
+
+func MarkReadService(id int64, userID string) error {
+	ok, err := MarkRead(id, userID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+func MarkAllReadService(userID string) (int64, error) {
+	return MarkAllRead(userID)
+}
+
+func GetPreferencesService(userID, role string) (*PreferencesResponse, error) {
+	locale, stored, err := GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &PreferencesResponse{Locale: locale, Events: map[string]EventPreference{}}
+	for _, event := range preferencesByRole[role] {
+		p, ok := stored[event]
+		if !ok {
+			p = EventPreference{Email: true, InApp: true}
+		}
+		resp.Events[event] = p
+	}
+	return resp, nil
+}
+
+func UpdatePreferencesService(userID, role string, req UpdatePreferencesRequest) (*PreferencesResponse, error) {
+	allowed := map[string]bool{}
+	for _, event := range preferencesByRole[role] {
+		allowed[event] = true
+	}
+
+	prefs := make(map[string]EventPreference, len(req.Events))
+	for event, p := range req.Events {
+		if !allowed[event] {
+			return nil, fmt.Errorf("unknown notification event '%s' for role %s", event, role)
+		}
+		prefs[event] = p
+	}
+
+	current, _, err := GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	locale := current
+	if req.Locale != "" {
+		locale = mailer.NormalizeLocale(req.Locale)
+	}
+
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	if err := SavePreferencesTx(tx, userID, locale, prefs); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return GetPreferencesService(userID, role)
+}
@@ -0,0 +1,165 @@
+package orders
+
+import (
+	"errors"
+	"time"
+
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/utils"
+)
+
+// expectedDeliveryDays is the business-day promise given to a new order
+// while neither printing nor plant has declared a capacity.
+const expectedDeliveryDays = 10
+
+// dispatchDays is the business days between the plant finishing an order
+// and its delivery.
+const dispatchDays = 2
+
+// defaultLabelsPerSheet is assumed for orders whose label details are not
+// saved yet.
+const defaultLabelsPerSheet = 12
+
+var capacityUnits = map[string]string{"printing": "sheets", "plant": "bottles"}
+
+func sheetsFor(qty, labelsPerSheet int) int {
+	if labelsPerSheet <= 0 {
+		labelsPerSheet = defaultLabelsPerSheet
+	}
+	return (qty + labelsPerSheet - 1) / labelsPerSheet
+}
+
+func daysFor(units, dailyCapacity int) int {
+	return (units + dailyCapacity - 1) / dailyCapacity
+}
+
+// GetWorkCapacityService returns the capacity userID has declared, or a
+// zero capacity if they have not.
+func (s *OrderService) GetWorkCapacityService(userID, role string) (*WorkCapacity, error) {
+	list, err := s.ListWorkCapacitiesService()
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		if c.UserID == userID {
+			return &c, nil
+		}
+	}
+	return &WorkCapacity{UserID: userID, Role: role, Unit: capacityUnits[role]}, nil
+}
+
+// SaveWorkCapacityService declares how many sheets (printing) or bottles
+// (plant) userID handles per business day. Zero withdraws the declaration.
+func (s *OrderService) SaveWorkCapacityService(userID, role string, dailyCapacity int) (*WorkCapacity, error) {
+	if !isWorkRole(role) {
+		return nil, errors.New("only printing and plant users can declare capacity")
+	}
+	if dailyCapacity < 0 {
+		return nil, errors.New("daily_capacity cannot be negative")
+	}
+
+	c := WorkCapacity{
+		UserID:        userID,
+		Role:          role,
+		DailyCapacity: dailyCapacity,
+		Unit:          capacityUnits[role],
+		UpdatedAt:     utils.NowInIST(),
+	}
+	if err := s.repo.SaveWorkCapacity(c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (s *OrderService) ListWorkCapacitiesService() ([]WorkCapacity, error) {
+	list, err := s.repo.ListWorkCapacities()
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Unit = capacityUnits[list[i].Role]
+	}
+	if list == nil {
+		list = []WorkCapacity{}
+	}
+	return list, nil
+}
+
+// QuoteDeliveryService estimates when an order that is not placed yet would
+// be delivered if it were placed now.
+func (s *OrderService) QuoteDeliveryService(req QuoteRequest) (*DeliveryQuote, error) {
+	sheets := req.NoOfSheets
+	if sheets == 0 {
+		sheets = sheetsFor(req.Qty, req.LabelsPerSheet)
+	}
+	return s.estimateDelivery(req.Qty, sheets, utils.NowInIST())
+}
+
+// estimateDelivery queues an order of qty bottles and sheets label sheets
+// behind the in-flight work, first come first served. Printing works
+// through the sheets of verified orders it has not finished; the plant
+// works through the bottles of every verified in-flight order but cannot
+// start this one before its labels are printed. A role without declared
+// capacity takes its fixed stage deadline instead.
+func (s *OrderService) estimateDelivery(qty, sheets int, now time.Time) (*DeliveryQuote, error) {
+	orders, err := s.repo.ListInFlightOrders()
+	if err != nil {
+		return nil, err
+	}
+	capacities, err := s.repo.ListWorkCapacities()
+	if err != nil {
+		return nil, err
+	}
+	sched, err := s.calendar.Schedule(calendar.DefaultCalendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	printing := StageEstimate{Role: "printing", Unit: capacityUnits["printing"], Quantity: sheets}
+	plant := StageEstimate{Role: "plant", Unit: capacityUnits["plant"], Quantity: qty}
+	for _, c := range capacities {
+		switch c.Role {
+		case "printing":
+			printing.DailyCapacity += c.DailyCapacity
+		case "plant":
+			plant.DailyCapacity += c.DailyCapacity
+		}
+	}
+	for _, o := range orders {
+		if o.Status == "placed" && o.PaymentStatus != "payment_verified" {
+			continue
+		}
+		if o.Status == "placed" || o.Status == "printing" {
+			if o.Sheets > 0 {
+				printing.Backlog += o.Sheets
+			} else {
+				printing.Backlog += sheetsFor(o.Qty, 0)
+			}
+		}
+		plant.Backlog += o.Qty
+	}
+
+	if printing.DailyCapacity > 0 {
+		printing.ReadyAfterDays = daysFor(printing.Backlog+printing.Quantity, printing.DailyCapacity)
+	} else {
+		printing.ReadyAfterDays = stageDays("printing")
+	}
+	if plant.DailyCapacity > 0 {
+		plant.ReadyAfterDays = max(
+			printing.ReadyAfterDays+daysFor(plant.Quantity, plant.DailyCapacity),
+			daysFor(plant.Backlog+plant.Quantity, plant.DailyCapacity),
+		)
+	} else {
+		plant.ReadyAfterDays = printing.ReadyAfterDays + stageDays("plant")
+	}
+
+	days := plant.ReadyAfterDays + dispatchDays
+	if printing.DailyCapacity == 0 && plant.DailyCapacity == 0 {
+		days = expectedDeliveryDays
+	}
+	return &DeliveryQuote{
+		ExpectedDelivery: sched.AddBusinessDays(now, days),
+		BusinessDays:     days,
+		Stages:           []StageEstimate{printing, plant},
+	}, nil
+}
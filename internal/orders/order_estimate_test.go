@@ -0,0 +1,158 @@
+package orders
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/utils"
+)
+
+type inFlightFixture struct {
+	id       string
+	status   string
+	verified bool
+	qty      int
+	sheets   int
+}
+
+func newEstimateService(t *testing.T, inFlight []inFlightFixture, capacities map[string]int) *OrderService {
+	t.Helper()
+	repo := NewMemoryOrderRepository()
+	for _, f := range inFlight {
+		if err := repo.CreateOrder(&Order{OrderID: f.id, Qty: f.qty}, "owner"); err != nil {
+			t.Fatal(err)
+		}
+		if f.verified {
+			if err := repo.UpdatePaymentStatus(f.id, "payment_verified", "admin", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if f.status != "placed" {
+			if err := repo.UpdateOrderStatus(f.id, f.status, "admin", ""); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if f.sheets > 0 {
+			if err := repo.SaveOrderLabelDetails(OrderLabelDetails{OrderID: f.id, NoOfSheets: f.sheets, LabelsPerSheet: 12}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for userID, daily := range capacities {
+		role := "printing"
+		if strings.HasPrefix(userID, "plant") {
+			role = "plant"
+		}
+		if err := repo.SaveWorkCapacity(WorkCapacity{UserID: userID, Role: role, DailyCapacity: daily}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cal := calendar.NewCalendarService(calendar.NewMemoryCalendarRepository(), nil)
+	return NewOrderService(repo, nil, nil, cal, nil)
+}
+
+func TestEstimateDelivery(t *testing.T) {
+	// 2026-03-02 is a Monday; the built-in calendar works Monday to
+	// Saturday with a 17:00 cutoff.
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST)
+	inFlight := []inFlightFixture{
+		{id: "queued", status: "placed", verified: true, qty: 1200},
+		{id: "printing", status: "printing", verified: true, qty: 600, sheets: 50},
+		{id: "at-plant", status: "ready_for_plant", verified: true, qty: 2000, sheets: 170},
+		{id: "unpaid", status: "placed", qty: 5000, sheets: 400},
+	}
+
+	tests := []struct {
+		name       string
+		capacities map[string]int
+		qty        int
+		sheets     int
+		printing   StageEstimate
+		plant      StageEstimate
+		days       int
+	}{
+		{
+			name: "no capacity falls back to the fixed promise",
+			qty:  600, sheets: 50,
+			printing: StageEstimate{Quantity: 50, Backlog: 150, ReadyAfterDays: 2},
+			plant:    StageEstimate{Quantity: 600, Backlog: 3800, ReadyAfterDays: 5},
+			days:     expectedDeliveryDays,
+		},
+		{
+			name:       "printing capacity only",
+			capacities: map[string]int{"printer-a": 60, "printer-b": 40},
+			qty:        600, sheets: 50,
+			printing: StageEstimate{Quantity: 50, Backlog: 150, DailyCapacity: 100, ReadyAfterDays: 2},
+			plant:    StageEstimate{Quantity: 600, Backlog: 3800, ReadyAfterDays: 5},
+			days:     7,
+		},
+		{
+			name:       "plant backlog is the bottleneck",
+			capacities: map[string]int{"printer-a": 100, "plant-a": 1000},
+			qty:        600, sheets: 50,
+			printing: StageEstimate{Quantity: 50, Backlog: 150, DailyCapacity: 100, ReadyAfterDays: 2},
+			plant:    StageEstimate{Quantity: 600, Backlog: 3800, DailyCapacity: 1000, ReadyAfterDays: 5},
+			days:     7,
+		},
+		{
+			name:       "plant waits for printing",
+			capacities: map[string]int{"printer-a": 10, "plant-a": 10000},
+			qty:        600, sheets: 50,
+			printing: StageEstimate{Quantity: 50, Backlog: 150, DailyCapacity: 10, ReadyAfterDays: 20},
+			plant:    StageEstimate{Quantity: 600, Backlog: 3800, DailyCapacity: 10000, ReadyAfterDays: 21},
+			days:     23,
+		},
+		{
+			name:       "plant capacity only",
+			capacities: map[string]int{"plant-a": 2000},
+			qty:        600, sheets: 50,
+			printing: StageEstimate{Quantity: 50, Backlog: 150, ReadyAfterDays: 2},
+			plant:    StageEstimate{Quantity: 600, Backlog: 3800, DailyCapacity: 2000, ReadyAfterDays: 3},
+			days:     5,
+		},
+	}
+	sched := testSchedule(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newEstimateService(t, inFlight, tt.capacities)
+			quote, err := s.estimateDelivery(tt.qty, tt.sheets, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.printing.Role, tt.printing.Unit = "printing", "sheets"
+			tt.plant.Role, tt.plant.Unit = "plant", "bottles"
+			if len(quote.Stages) != 2 || quote.Stages[0] != tt.printing || quote.Stages[1] != tt.plant {
+				t.Errorf("stages = %+v, want %+v and %+v", quote.Stages, tt.printing, tt.plant)
+			}
+			if quote.BusinessDays != tt.days {
+				t.Errorf("business days = %d, want %d", quote.BusinessDays, tt.days)
+			}
+			if want := sched.AddBusinessDays(now, tt.days); !quote.ExpectedDelivery.Equal(want) {
+				t.Errorf("expected delivery = %v, want %v", quote.ExpectedDelivery, want)
+			}
+		})
+	}
+
+	quote, err := newEstimateService(t, nil, nil).estimateDelivery(120, 10, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 13, 17, 0, 0, 0, utils.IST); !quote.ExpectedDelivery.Equal(want) {
+		t.Errorf("empty queue: expected delivery = %v, want %v", quote.ExpectedDelivery.In(utils.IST), want)
+	}
+}
+
+func TestSheetsFor(t *testing.T) {
+	for _, tt := range []struct{ qty, perSheet, want int }{
+		{120, 12, 10},
+		{121, 12, 11},
+		{121, 0, 11},
+		{5, 24, 1},
+	} {
+		if got := sheetsFor(tt.qty, tt.perSheet); got != tt.want {
+			t.Errorf("sheetsFor(%d, %d) = %d, want %d", tt.qty, tt.perSheet, got, tt.want)
+		}
+	}
+}
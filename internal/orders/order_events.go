@@ -0,0 +1,322 @@
+package orders
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"enerzyflow_backend/internal/events"
+	"enerzyflow_backend/utils"
+)
+
+const (
+	EventOrderCreated    = "order.created"
+	EventStatusChanged   = "order.status_changed"
+	EventPaymentUpdated  = "order.payment_updated"
+	EventCommentAdded    = "order.comment_added"
+	EventInvoiceUploaded = "order.invoice_uploaded"
+	// EventAssignmentChanged is raised when an admin reassigns, unassigns
+	// or extends the deadline of a printing or plant assignment.
+	EventAssignmentChanged = "order.assignment_changed"
+	// EventSLAEscalated is raised by the SLA monitor when an assignment
+	// deadline or expected delivery date is at risk or missed.
+	EventSLAEscalated = "order.sla_escalated"
+	// EventDeliveryRescheduled is raised when the expected delivery date
+	// of a stalled order is moved.
+	EventDeliveryRescheduled = "order.delivery_rescheduled"
+)
+
+// OrderEvent describes one row written to order_status_history, a new
+// comment on an order, an invoice upload, an admin change to an
+// assignment, an SLA escalation or a rescheduled delivery.
+type OrderEvent struct {
+	Type           string    `json:"type"`
+	OrderID        string    `json:"order_id"`
+	OwnerID        string    `json:"owner_id"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previous_status,omitempty"`
+	ChangedBy      string    `json:"changed_by"`
+	Reason         string    `json:"reason,omitempty"`
+	ActorRole      string    `json:"actor_role,omitempty"`
+	Comment        string    `json:"comment,omitempty"`
+	InvoiceURL     string    `json:"invoice_url,omitempty"`
+	PiURL          string    `json:"pi_url,omitempty"`
+	OccurredAt     time.Time `json:"occurred_at"`
+
+	// Assignment changes: Action is an assignment history action and
+	// ActorRole the role of the assignment.
+	Action             string     `json:"action,omitempty"`
+	AssigneeID         string     `json:"assignee_id,omitempty"`
+	PreviousAssigneeID string     `json:"previous_assignee_id,omitempty"`
+	Deadline           *time.Time `json:"deadline,omitempty"`
+
+	// SLA escalations: Action is the level, ActorRole and AssigneeID the
+	// role and holder of a late assignment, and Deadline the due time.
+	SLAKind string `json:"sla_kind,omitempty"`
+
+	// Rescheduled deliveries.
+	ExpectedDelivery         *time.Time `json:"expected_delivery,omitempty"`
+	PreviousExpectedDelivery *time.Time `json:"previous_expected_delivery,omitempty"`
+}
+
+// EventHook runs inside the transaction that produced the event, so anything
+// it writes (emails, notifications) commits or rolls back with the change.
+type EventHook func(tx *sql.Tx, ev OrderEvent) error
+
+var eventHooks []EventHook
+
+// RegisterEventHook must be called during startup, before requests are served.
+func RegisterEventHook(h EventHook) {
+	eventHooks = append(eventHooks, h)
+}
+
+func runEventHooks(tx *sql.Tx, ev OrderEvent) error {
+	for _, h := range eventHooks {
+		if err := h(tx, ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CommitHook runs after a transaction that raised order events commits, for
+// work that must not start before the hooks' writes are visible.
+type CommitHook func()
+
+var commitHooks []CommitHook
+
+// RegisterCommitHook must be called during startup, before requests are served.
+func RegisterCommitHook(h CommitHook) {
+	commitHooks = append(commitHooks, h)
+}
+
+// orderTx is a transaction that collects the order events it raises and
+// publishes them on the event bus once it commits, unless the bus publishes
+// inside the transaction itself.
+type orderTx struct {
+	*sql.Tx
+	events []OrderEvent
+	raised bool
+}
+
+func (r *PostgresOrderRepository) beginOrderTx() (*orderTx, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &orderTx{Tx: tx}, nil
+}
+
+func (tx *orderTx) Commit() error {
+	if err := tx.Tx.Commit(); err != nil {
+		return err
+	}
+	for _, ev := range tx.events {
+		publishEvent(ev)
+	}
+	tx.events = nil
+	if tx.raised {
+		for _, h := range commitHooks {
+			h()
+		}
+		tx.raised = false
+	}
+	return nil
+}
+
+// raise runs the in-transaction hooks and publishes ev. Buses that support
+// it publish inside the transaction; otherwise ev is held until Commit.
+func (tx *orderTx) raise(ev OrderEvent) error {
+	if err := runEventHooks(tx.Tx, ev); err != nil {
+		return err
+	}
+	tx.raised = true
+	if p, ok := events.Default().(events.TxPublisher); ok {
+		payload, err := encodeEvent(ev)
+		if err != nil {
+			return err
+		}
+		return p.PublishTx(tx.Tx, events.Event{Topic: ev.Type, Payload: payload})
+	}
+	tx.events = append(tx.events, ev)
+	return nil
+}
+
+// encodeEvent drops the free text (comment, reason, document URLs) from
+// events too large to publish, and as a last resort keeps only the IDs;
+// subscribers can load the rest from /orders/:id. Publishing must never
+// fail the change that raised the event.
+func encodeEvent(ev OrderEvent) ([]byte, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil || len(payload) <= events.MaxPayload {
+		return payload, err
+	}
+	ev.Comment, ev.Reason, ev.InvoiceURL, ev.PiURL = "", "", "", ""
+	payload, err = json.Marshal(ev)
+	if err != nil || len(payload) <= events.MaxPayload {
+		return payload, err
+	}
+	return json.Marshal(OrderEvent{
+		Type:       ev.Type,
+		OrderID:    ev.OrderID,
+		OwnerID:    ev.OwnerID,
+		Status:     ev.Status,
+		OccurredAt: ev.OccurredAt,
+	})
+}
+
+func publishEvent(ev OrderEvent) {
+	payload, err := encodeEvent(ev)
+	if err != nil {
+		log.Printf("orders: failed to encode %s event: %v", ev.Type, err)
+		return
+	}
+	if err := events.Publish(events.Event{Topic: ev.Type, Payload: payload}); err != nil {
+		log.Printf("orders: failed to publish %s for order %s: %v", ev.Type, ev.OrderID, err)
+	}
+}
+
+// insertStatusHistoryTx is the single place order_status_history is written;
+// every insert is fanned out to the registered hooks.
+func insertStatusHistoryTx(tx *orderTx, eventType, orderID, status, previousStatus, changedBy, reason string) error {
+	now := utils.NowInIST()
+	if _, err := tx.Exec(`
+		INSERT INTO order_status_history (order_id, status, changed_at, changed_by, reason)
+		VALUES ($1, $2, $3, $4, $5)
+	`, orderID, status, now, changedBy, reason); err != nil {
+		return err
+	}
+
+	var ownerID string
+	if err := tx.QueryRow(`SELECT user_id FROM orders WHERE order_id = $1`, orderID).Scan(&ownerID); err != nil {
+		return err
+	}
+
+	return tx.raise(OrderEvent{
+		Type:           eventType,
+		OrderID:        orderID,
+		OwnerID:        ownerID,
+		Status:         status,
+		PreviousStatus: previousStatus,
+		ChangedBy:      changedBy,
+		Reason:         reason,
+		OccurredAt:     now,
+	})
+}
+
+func insertCommentTx(tx *orderTx, orderID, userID, role, comment string) error {
+	now := utils.NowInIST()
+	if _, err := tx.Exec(`
+        INSERT INTO order_comments (order_id, user_id, role, comment, created_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, orderID, userID, role, comment, now); err != nil {
+		return err
+	}
+
+	var ownerID string
+	if err := tx.QueryRow(`SELECT user_id FROM orders WHERE order_id = $1`, orderID).Scan(&ownerID); err != nil {
+		return err
+	}
+
+	return tx.raise(OrderEvent{
+		Type:       EventCommentAdded,
+		OrderID:    orderID,
+		OwnerID:    ownerID,
+		ChangedBy:  userID,
+		ActorRole:  role,
+		Comment:    comment,
+		OccurredAt: now,
+	})
+}
+
+func raiseInvoiceUploadedTx(tx *orderTx, orderID string, urls map[string]string) error {
+	var ownerID, status string
+	if err := tx.QueryRow(`SELECT user_id, status FROM orders WHERE order_id = $1`, orderID).Scan(&ownerID, &status); err != nil {
+		return err
+	}
+
+	return tx.raise(OrderEvent{
+		Type:       EventInvoiceUploaded,
+		OrderID:    orderID,
+		OwnerID:    ownerID,
+		Status:     status,
+		InvoiceURL: urls["invoice_url"],
+		PiURL:      urls["pi_url"],
+		OccurredAt: utils.NowInIST(),
+	})
+}
+
+// raiseAssignmentChangedTx writes e to the assignment history, bumps the
+// order's version and raises EventAssignmentChanged.
+func raiseAssignmentChangedTx(tx *orderTx, e AssignmentHistoryEntry) error {
+	if err := insertAssignmentHistory(tx, e); err != nil {
+		return err
+	}
+
+	var ownerID, status string
+	err := tx.QueryRow(`
+		UPDATE orders SET version = version + 1, updated_at = $1
+		WHERE order_id = $2
+		RETURNING user_id, status
+	`, e.ChangedAt, e.OrderID).Scan(&ownerID, &status)
+	if err != nil {
+		return err
+	}
+
+	return tx.raise(assignmentEvent(e, ownerID, status))
+}
+
+func assignmentEvent(e AssignmentHistoryEntry, ownerID, status string) OrderEvent {
+	return OrderEvent{
+		Type:               EventAssignmentChanged,
+		OrderID:            e.OrderID,
+		OwnerID:            ownerID,
+		Status:             status,
+		ChangedBy:          e.ChangedBy,
+		Reason:             e.Reason,
+		ActorRole:          e.Role,
+		Action:             e.Action,
+		AssigneeID:         e.ToUserID,
+		PreviousAssigneeID: e.FromUserID,
+		Deadline:           e.Deadline,
+		OccurredAt:         e.ChangedAt,
+	}
+}
+
+// raiseSLAEscalatedTx raises EventSLAEscalated for item.
+func raiseSLAEscalatedTx(tx *orderTx, item SLAItem, at time.Time) error {
+	var ownerID string
+	if err := tx.QueryRow(`SELECT user_id FROM orders WHERE order_id = $1`, item.OrderID).Scan(&ownerID); err != nil {
+		return err
+	}
+	return tx.raise(slaEvent(item, ownerID, at))
+}
+
+func slaEvent(item SLAItem, ownerID string, at time.Time) OrderEvent {
+	due := item.DueAt
+	return OrderEvent{
+		Type:       EventSLAEscalated,
+		OrderID:    item.OrderID,
+		OwnerID:    ownerID,
+		Status:     item.Status,
+		ActorRole:  item.Role,
+		Action:     item.Level,
+		AssigneeID: item.AssigneeID,
+		Deadline:   &due,
+		SLAKind:    item.Kind,
+		OccurredAt: at,
+	}
+}
+
+func deliveryRescheduledEvent(orderID, ownerID, status string, previous, expected, at time.Time) OrderEvent {
+	return OrderEvent{
+		Type:                     EventDeliveryRescheduled,
+		OrderID:                  orderID,
+		OwnerID:                  ownerID,
+		Status:                   status,
+		ExpectedDelivery:         &expected,
+		PreviousExpectedDelivery: &previous,
+		OccurredAt:               at,
+	}
+}
@@ -0,0 +1,58 @@
+package orders
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/events"
+)
+
+func TestEncodeEventFitsBus(t *testing.T) {
+	long := strings.Repeat("x", events.MaxPayload)
+	base := OrderEvent{
+		Type:       EventStatusChanged,
+		OrderID:    "3f0c1e9a-7d2b-4c55-9f1e-2a6b8c4d0e11",
+		OwnerID:    "8a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d",
+		Status:     "declined",
+		OccurredAt: time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name   string
+		ev     OrderEvent
+		reason string
+	}{
+		{"small event is kept whole", withReason(base, "wrong artwork"), "wrong artwork"},
+		{"long reason is dropped", withReason(base, long), ""},
+		{"long comment is dropped", OrderEvent{Type: EventCommentAdded, OrderID: base.OrderID, Comment: long}, ""},
+		{"long action falls back to ids", OrderEvent{Type: EventAssignmentChanged, OrderID: base.OrderID, Action: long}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := encodeEvent(tt.ev)
+			if err != nil {
+				t.Fatalf("encodeEvent: %v", err)
+			}
+			if len(payload) > events.MaxPayload {
+				t.Fatalf("payload is %d bytes, want at most %d", len(payload), events.MaxPayload)
+			}
+			var got OrderEvent
+			if err := json.Unmarshal(payload, &got); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got.Type != tt.ev.Type || got.OrderID != tt.ev.OrderID || got.Status != tt.ev.Status {
+				t.Errorf("got %s %s %q, want %s %s %q", got.Type, got.OrderID, got.Status, tt.ev.Type, tt.ev.OrderID, tt.ev.Status)
+			}
+			if got.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", got.Reason, tt.reason)
+			}
+		})
+	}
+}
+
+func withReason(ev OrderEvent, reason string) OrderEvent {
+	ev.Reason = reason
+	return ev
+}
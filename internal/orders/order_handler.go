@@ -1,16 +1,32 @@
 package orders
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
+	"enerzyflow_backend/internal/events"
+
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-func CreateOrderHandler(c *gin.Context) {
+type OrderHandler struct {
+	svc *OrderService
+}
+
+func NewOrderHandler(svc *OrderService) *OrderHandler {
+	return &OrderHandler{svc: svc}
+}
+
+func (h *OrderHandler) CreateOrderHandler(c *gin.Context) {
 	var req CreateOrderRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
@@ -28,7 +44,7 @@ func CreateOrderHandler(c *gin.Context) {
 		return
 	}
 
-	order, err := CreateOrderService(userID.String(), req)
+	order, err := h.svc.CreateOrderService(userID.String(), req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -40,7 +56,7 @@ func CreateOrderHandler(c *gin.Context) {
 	})
 }
 
-func GetOrderHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrderHandler(c *gin.Context) {
 	fmt.Println("order id")
 	orderID := c.Param("id")
 	if orderID == "" {
@@ -59,18 +75,19 @@ func GetOrderHandler(c *gin.Context) {
 		return
 	}
 
-	order, err := GetOrderService(userID.String(), orderID)
+	order, err := h.svc.GetOrderService(userID.String(), orderID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", orderETag(order.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"order": order,
 	})
 }
 
-func GetOrdersHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrdersHandler(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -95,7 +112,7 @@ func GetOrdersHandler(c *gin.Context) {
 		return
 	}
 
-	orders, err := GetOrdersService(userID.String(), limit, offset)
+	orders, err := h.svc.GetOrdersService(userID.String(), limit, offset)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -104,7 +121,7 @@ func GetOrdersHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, orders)
 }
 
-func GetAllOrdersHandler(c *gin.Context) {
+func (h *OrderHandler) GetAllOrdersHandler(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "10")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -131,7 +148,7 @@ func GetAllOrdersHandler(c *gin.Context) {
 		offset = 0
 	}
 
-	orders, total, err := GetAllOrdersService(role, limit, offset, userID.String())
+	orders, total, err := h.svc.GetAllOrdersService(role, limit, offset, userID.String())
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -143,7 +160,7 @@ func GetAllOrdersHandler(c *gin.Context) {
 	})
 }
 
-func UpdateOrderStatusHandler(c *gin.Context) {
+func (h *OrderHandler) UpdateOrderStatusHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(400, gin.H{"error": "order_id is required"})
@@ -155,6 +172,14 @@ func UpdateOrderStatusHandler(c *gin.Context) {
 		c.JSON(400, gin.H{"error": "invalid request body"})
 		return
 	}
+	if ifMatch := c.GetHeader("If-Match"); ifMatch != "" {
+		version, err := parseIfMatch(ifMatch)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Version = version
+	}
 
 	userIDVal, exists := c.Get("user_id")
 	if !exists {
@@ -169,17 +194,78 @@ func UpdateOrderStatusHandler(c *gin.Context) {
 		return
 	}
 
-	if err := UpdateOrderStatusService(userID.String(), role, orderID, req); err != nil {
+	version, err := h.svc.UpdateOrderStatusService(userID.String(), role, orderID, req)
+	if err != nil {
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			c.Header("ETag", orderETag(conflict.Current))
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "code": "version_conflict", "version": conflict.Current})
+			return
+		}
 		c.JSON(400, gin.H{"error": err.Error()})
 		return
 	}
 
+	c.Header("ETag", orderETag(version))
 	c.JSON(200, gin.H{
 		"message": "order status updated successfully",
+		"version": version,
 	})
 }
 
-func UpdatePaymentStatusHandler(c *gin.Context) {
+// orderETag formats an order version as a strong entity tag.
+func orderETag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch reads the order version from an If-Match header. "*" matches
+// any version and yields 0.
+func parseIfMatch(header string) (int, error) {
+	tag := strings.TrimSpace(header)
+	if tag == "*" {
+		return 0, nil
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	version, err := strconv.Atoi(strings.Trim(tag, `"`))
+	if err != nil || version <= 0 {
+		return 0, errors.New("If-Match must be a single order version ETag")
+	}
+	return version, nil
+}
+
+func (h *OrderHandler) GetAllowedTransitionsHandler(c *gin.Context) {
+	orderID := c.Param("id")
+	if orderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
+		return
+	}
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	role := c.GetString("role")
+
+	resp, err := h.svc.AllowedTransitionsService(orderID, userID.String(), role)
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("ETag", orderETag(resp.Version))
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *OrderHandler) UpdatePaymentStatusHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -212,7 +298,7 @@ func UpdatePaymentStatusHandler(c *gin.Context) {
 		return
 	}
 
-	if err := UpdatePaymentStatusService(orderID, req.Status, req.Reason, userID.String()); err != nil {
+	if err := h.svc.UpdatePaymentStatusService(orderID, req.Status, req.Reason, userID.String()); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -222,7 +308,7 @@ func UpdatePaymentStatusHandler(c *gin.Context) {
 	})
 }
 
-func UploadPaymentScreenshotHandler(c *gin.Context) {
+func (h *OrderHandler) UploadPaymentScreenshotHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -245,7 +331,7 @@ func UploadPaymentScreenshotHandler(c *gin.Context) {
 		return
 	}
 
-	url, err := UploadPaymentScreenshotService(orderID, fileHeader, userID.String())
+	url, err := h.svc.UploadPaymentScreenshotService(orderID, fileHeader, userID.String())
 	if err != nil {
 		switch err.Error() {
 		case "order not found":
@@ -266,7 +352,7 @@ func UploadPaymentScreenshotHandler(c *gin.Context) {
 	})
 }
 
-func GetOrderTrackingHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrderTrackingHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id required"})
@@ -284,7 +370,7 @@ func GetOrderTrackingHandler(c *gin.Context) {
 	}
 	role := c.GetString("role")
 
-	history, err := GetOrderTrackingService(orderID, userID.String(), role)
+	history, err := h.svc.GetOrderTrackingService(orderID, userID.String(), role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -293,7 +379,7 @@ func GetOrderTrackingHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"history": history})
 }
 
-func UploadInvoiceHandler(c *gin.Context) {
+func (h *OrderHandler) UploadInvoiceHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -308,7 +394,7 @@ func UploadInvoiceHandler(c *gin.Context) {
 		return
 	}
 
-	urls, err := UploadInvoiceService(orderID, invoiceFile, piFile)
+	urls, err := h.svc.UploadInvoiceService(orderID, invoiceFile, piFile)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -320,7 +406,7 @@ func UploadInvoiceHandler(c *gin.Context) {
 	})
 }
 
-func AddOrderCommentHandler(c *gin.Context) {
+func (h *OrderHandler) AddOrderCommentHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	var req struct {
 		Comment string `json:"comment"`
@@ -339,7 +425,7 @@ func AddOrderCommentHandler(c *gin.Context) {
 	userID := userIDVal.(uuid.UUID).String()
 
 	role := c.GetString("role")
-	if err := AddOrderCommentService(orderID, userID, role, req.Comment); err != nil {
+	if err := h.svc.AddOrderCommentService(orderID, userID, role, req.Comment); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -347,7 +433,7 @@ func AddOrderCommentHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "comment added successfully"})
 }
 
-func GetOrderCommentsHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrderCommentsHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	role := c.GetString("role")
 
@@ -358,7 +444,7 @@ func GetOrderCommentsHandler(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID).String()
 
-	comments, err := GetOrderCommentsService(orderID, role, userID)
+	comments, err := h.svc.GetOrderCommentsService(orderID, role, userID)
 	if err != nil {
 		switch {
 		case strings.Contains(err.Error(), "not assigned"):
@@ -374,7 +460,7 @@ func GetOrderCommentsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"comments": comments})
 }
 
-func SaveOrderLabelDetailsHandler(c *gin.Context) {
+func (h *OrderHandler) SaveOrderLabelDetailsHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -387,7 +473,7 @@ func SaveOrderLabelDetailsHandler(c *gin.Context) {
 		return
 	}
 
-	if err := SaveOrderLabelDetailsService(orderID, req.NoOfSheets, req.CuttingType, req.LabelsPerSheet, req.Description); err != nil {
+	if err := h.svc.SaveOrderLabelDetailsService(orderID, req.NoOfSheets, req.CuttingType, req.LabelsPerSheet, req.Description); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -395,7 +481,7 @@ func SaveOrderLabelDetailsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "label details saved successfully"})
 }
 
-func GetOrderLabelDetailsHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrderLabelDetailsHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -410,7 +496,7 @@ func GetOrderLabelDetailsHandler(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID).String()
 
-	details, err := GetOrderLabelDetailsService(orderID, userID, role)
+	details, err := h.svc.GetOrderLabelDetailsService(orderID, userID, role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -424,7 +510,7 @@ func GetOrderLabelDetailsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, details)
 }
 
-func GetOrderDetailHandler(c *gin.Context) {
+func (h *OrderHandler) GetOrderDetailHandler(c *gin.Context) {
 	orderID := c.Param("id")
 	if orderID == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "order_id is required"})
@@ -439,7 +525,7 @@ func GetOrderDetailHandler(c *gin.Context) {
 	}
 	userID := userIDVal.(uuid.UUID)
 
-	orderDetail, err := GetOrderDetailService(orderID, role, userID.String())
+	orderDetail, err := h.svc.GetOrderDetailService(orderID, role, userID.String())
 	if err != nil {
 		if err.Error() == "order not found" || strings.Contains(err.Error(), "unauthorized") {
 			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
@@ -449,7 +535,348 @@ func GetOrderDetailHandler(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", orderETag(orderDetail.Version))
 	c.JSON(http.StatusOK, gin.H{
 		"order": orderDetail,
 	})
 }
+
+const streamHeartbeat = 25 * time.Second
+
+// StreamOrdersHandler pushes order status, payment and comment events to the
+// client as Server-Sent Events, scoped to what the caller's role may see.
+func (h *OrderHandler) StreamOrdersHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	role := c.GetString("role")
+
+	scope := h.svc.NewEventScope(userID.String(), role)
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Render(http.StatusOK, sse.Event{Event: "ready", Data: gin.H{"role": role}})
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+
+		case msg, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			var ev OrderEvent
+			if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+				log.Printf("orders: skipping malformed %s event: %v", msg.Topic, err)
+				return true
+			}
+			ev, visible, err := scope.Filter(ev)
+			if err != nil {
+				log.Printf("orders: stream scope check failed for order %s: %v", ev.OrderID, err)
+				return true
+			}
+			if visible {
+				c.Render(-1, sse.Event{Event: ev.Type, Data: ev})
+			}
+			return true
+		}
+	})
+}
+
+func (h *OrderHandler) ClaimWorkHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	role := c.GetString("role")
+	if !isWorkRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only printing and plant users can claim work"})
+		return
+	}
+
+	claim, order, err := h.svc.ClaimWorkService(userID.String(), role)
+	if err != nil {
+		if errors.Is(err, ErrNoWork) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if order != nil {
+		c.Header("ETag", orderETag(order.Version))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"claim": claim,
+		"order": order,
+	})
+}
+
+func (h *OrderHandler) PinOrderHandler(c *gin.Context) {
+	orderID := c.Param("id")
+	var req PinOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	adminID := userIDVal.(uuid.UUID).String()
+
+	pin, err := h.svc.PinOrderService(orderID, req.UserID, adminID)
+	if err != nil {
+		switch err.Error() {
+		case "order not found", "user not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "order pinned successfully",
+		"pin":     pin,
+	})
+}
+
+func (h *OrderHandler) UnpinOrderHandler(c *gin.Context) {
+	if err := h.svc.UnpinOrderService(c.Param("id"), c.Param("role")); err != nil {
+		if strings.Contains(err.Error(), "not pinned") {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "order unpinned successfully"})
+}
+
+// assignmentErrorStatus maps assignment service errors to HTTP statuses.
+func assignmentErrorStatus(err error) int {
+	switch {
+	case err.Error() == "order not found", err.Error() == "user not found":
+		return http.StatusNotFound
+	case strings.HasPrefix(err.Error(), "order has no"), strings.Contains(err.Error(), "already"):
+		return http.StatusConflict
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func (h *OrderHandler) ReassignOrderHandler(c *gin.Context) {
+	var req ReassignOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	adminID := userIDVal.(uuid.UUID).String()
+
+	assignment, err := h.svc.ReassignOrderService(c.Param("id"), c.Param("role"), req, adminID)
+	if err != nil {
+		c.JSON(assignmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "order reassigned successfully",
+		"assignment": assignment,
+	})
+}
+
+func (h *OrderHandler) UnassignOrderHandler(c *gin.Context) {
+	var req UnassignOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	adminID := userIDVal.(uuid.UUID).String()
+
+	if err := h.svc.UnassignOrderService(c.Param("id"), c.Param("role"), req.Reason, adminID); err != nil {
+		c.JSON(assignmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "order returned to the " + c.Param("role") + " pool"})
+}
+
+func (h *OrderHandler) ExtendDeadlineHandler(c *gin.Context) {
+	var req ExtendDeadlineRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	adminID := userIDVal.(uuid.UUID).String()
+
+	assignment, err := h.svc.ExtendDeadlineService(c.Param("id"), c.Param("role"), req, adminID)
+	if err != nil {
+		c.JSON(assignmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "deadline extended successfully",
+		"assignment": assignment,
+	})
+}
+
+func (h *OrderHandler) GetAssignmentHistoryHandler(c *gin.Context) {
+	history, err := h.svc.GetAssignmentHistoryService(c.Param("id"))
+	if err != nil {
+		if err.Error() == "order not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"history": history})
+}
+
+func (h *OrderHandler) GetSLABreachesHandler(c *gin.Context) {
+	report, err := h.svc.SLABreachesService(c.Query("level"), c.Query("kind"))
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "unknown") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *OrderHandler) QuoteDeliveryHandler(c *gin.Context) {
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	quote, err := h.svc.QuoteDeliveryService(req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+func (h *OrderHandler) GetWorkCapacityHandler(c *gin.Context) {
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	role := c.GetString("role")
+	if !isWorkRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only printing and plant users have a capacity"})
+		return
+	}
+
+	capacity, err := h.svc.GetWorkCapacityService(userID.String(), role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"capacity": capacity})
+}
+
+func (h *OrderHandler) SaveWorkCapacityHandler(c *gin.Context) {
+	var req SaveCapacityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	userIDVal, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
+		return
+	}
+	userID, ok := userIDVal.(uuid.UUID)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user context"})
+		return
+	}
+	role := c.GetString("role")
+	if !isWorkRole(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only printing and plant users can declare capacity"})
+		return
+	}
+
+	capacity, err := h.svc.SaveWorkCapacityService(userID.String(), role, *req.DailyCapacity)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"capacity": capacity})
+}
+
+func (h *OrderHandler) ListWorkCapacitiesHandler(c *gin.Context) {
+	capacities, err := h.svc.ListWorkCapacitiesService()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"capacities": capacities})
+}
@@ -14,6 +14,7 @@ type Order struct {
 	Qty              int       `json:"qty"`
 	CapColor         string    `json:"cap_color"`
 	Volume           int       `json:"volume"`
+	SKUID            string    `json:"sku_id,omitempty"`
 	Status           string    `json:"status"`
 	PaymentStatus    string    `json:"payment_status"`
 	PaymentUrl       string    `json:"payment_url"`
@@ -24,12 +25,15 @@ type Order struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// CreateOrderRequest names the product by sku_id, or by the variant,
+// volume and cap color of an active SKU.
 type CreateOrderRequest struct {
 	LabelID  string `json:"label_id" binding:"required"`
-	Variant  string `json:"variant" binding:"required"`
+	SKUID    string `json:"sku_id"`
+	Variant  string `json:"variant"`
 	Qty      int    `json:"qty" binding:"required,min=1"`
-	CapColor string `json:"cap_color" binding:"required"`
-	Volume   int    `json:"volume" binding:"required,min=1"`
+	CapColor string `json:"cap_color"`
+	Volume   int    `json:"volume" binding:"omitempty,min=1"`
 }
 
 type OrderResponse struct {
@@ -40,6 +44,7 @@ type OrderResponse struct {
 	Qty              int       `json:"qty"`
 	CapColor         string    `json:"cap_color"`
 	Volume           int       `json:"volume"`
+	SKUID            string    `json:"sku_id,omitempty"`
 	Status           string    `json:"status"`
 	PaymentStatus    string    `json:"payment_status"`
 	DeclineReason    string    `json:"decline_reason"`
@@ -49,6 +54,7 @@ type OrderResponse struct {
 	ExpectedDelivery time.Time `json:"expected_delivery"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+	Version          int       `json:"version"`
 }
 
 type OrderListResponse struct {
@@ -59,6 +65,22 @@ type OrderListResponse struct {
 type UpdateOrderStatusRequest struct {
 	Status string `json:"status"`
 	Reason string `json:"reason,omitempty"`
+	// Version, when set, must match the order's current version. The
+	// If-Match header takes precedence.
+	Version int `json:"version,omitempty"`
+}
+
+type AllowedTransition struct {
+	Action         string `json:"action"`
+	To             string `json:"to"`
+	ReasonRequired bool   `json:"reason_required"`
+}
+
+type AllowedTransitionsResponse struct {
+	OrderID     string              `json:"order_id"`
+	Status      string              `json:"status"`
+	Version     int                 `json:"version"`
+	Transitions []AllowedTransition `json:"transitions"`
 }
 
 type AllOrderModel struct {
@@ -71,7 +93,8 @@ type AllOrderModel struct {
 	Variant          string    `json:"variant" db:"variant"`
 	Qty              int       `json:"qty" db:"qty"`
 	CapColor         string    `json:"cap_color" db:"cap_color"`
-	Volume           string    `json:"volume" db:"volume"`
+	Volume           int       `json:"volume" db:"volume"`
+	SKUID            string    `json:"sku_id,omitempty" db:"sku_id"`
 	Status           string    `json:"status,omitempty" db:"status"`
 	PaymentStatus    string    `json:"payment_status,omitempty"`
 	DeclineReason    string    `json:"decline_reason"`
@@ -123,6 +146,7 @@ type OrderAssignment struct {
     AssignedAt  time.Time `json:"assigned_at"`
     Deadline    time.Time `json:"deadline"`
     CompletedAt sql.NullTime `json:"completed_at"`
+    ReleasedAt  sql.NullTime `json:"-"`
 }
 
 type OrderDetailResponse struct {
@@ -133,6 +157,7 @@ type OrderDetailResponse struct {
 	Qty               int                    `json:"qty"`
 	CapColor          string                 `json:"cap_color"`
 	Volume            int                    `json:"volume"`
+	SKUID             string                 `json:"sku_id,omitempty"`
 	Status            string                 `json:"status"`
 	PaymentStatus     string                 `json:"payment_status,omitempty"`
 	PaymentUrl        string                 `json:"payment_url,omitempty"`
@@ -146,4 +171,158 @@ type OrderDetailResponse struct {
 	LabelDetails      *OrderLabelDetails     `json:"label_details,omitempty"`
 	Assignments       []OrderAssignment      `json:"assignments,omitempty"`
 	Comments          []OrderComment        `json:"comments,omitempty"`
-}
\ No newline at end of file
+	AssignmentHistory []AssignmentHistoryEntry `json:"assignment_history,omitempty"`
+	Version           int                    `json:"version"`
+}
+// WorkClaim is a lease on an order from the printing or plant work pool. It
+// lapses at LeaseExpiresAt unless the holder accepts the order first.
+type WorkClaim struct {
+	OrderID        string    `json:"order_id"`
+	Role           string    `json:"role"`
+	UserID         string    `json:"user_id"`
+	ClaimedAt      time.Time `json:"claimed_at"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	Pinned         bool      `json:"pinned"`
+}
+
+// OrderPin reserves an order in Role's work pool for UserID.
+type OrderPin struct {
+	OrderID  string    `json:"order_id"`
+	Role     string    `json:"role"`
+	UserID   string    `json:"user_id"`
+	PinnedBy string    `json:"pinned_by"`
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+type PinOrderRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+}
+
+// Assignment history actions.
+const (
+	AssignmentAssigned         = "assigned"
+	AssignmentReassigned       = "reassigned"
+	AssignmentUnassigned       = "unassigned"
+	AssignmentDeadlineExtended = "deadline_extended"
+	AssignmentCompleted        = "completed"
+)
+
+// AssignmentHistoryEntry is one change to who holds an order for a role.
+// FromUserID is the assignee before the change and ToUserID the one after.
+type AssignmentHistoryEntry struct {
+	ID         int64      `json:"id"`
+	OrderID    string     `json:"order_id"`
+	Role       string     `json:"role"`
+	Action     string     `json:"action"`
+	FromUserID string     `json:"from_user_id,omitempty"`
+	ToUserID   string     `json:"to_user_id,omitempty"`
+	Deadline   *time.Time `json:"deadline,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	ChangedBy  string     `json:"changed_by"`
+	ChangedAt  time.Time  `json:"changed_at"`
+}
+
+type ReassignOrderRequest struct {
+	UserID string `json:"user_id" binding:"required"`
+	Reason string `json:"reason"`
+}
+
+type UnassignOrderRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ExtendDeadlineRequest struct {
+	Days   int    `json:"days" binding:"required,min=1"`
+	Reason string `json:"reason"`
+}
+
+// SLA kinds and levels.
+const (
+	SLAKindAssignment = "assignment_deadline"
+	SLAKindDelivery   = "expected_delivery"
+
+	SLALevelAtRisk   = "at_risk"
+	SLALevelBreached = "breached"
+)
+
+// inFlightStatuses are the statuses of orders that are still being worked on.
+var inFlightStatuses = map[string]bool{"placed": true, "printing": true, "ready_for_plant": true, "plant_processing": true}
+
+// InFlightOrder is an order that is still being worked on, with its open
+// printing or plant assignment if it has one.
+type InFlightOrder struct {
+	OrderID          string
+	Status           string
+	PaymentStatus    string
+	ExpectedDelivery time.Time
+	Qty              int
+	// Sheets is zero until the order's label details are saved.
+	Sheets     int
+	Assignment *OrderAssignment
+}
+
+// SLAItem is an assignment deadline or expected delivery date that is at
+// risk or already missed. EstimatedCompletion is only set for deliveries.
+type SLAItem struct {
+	OrderID             string     `json:"order_id"`
+	Kind                string     `json:"kind"`
+	Level               string     `json:"level"`
+	Role                string     `json:"role,omitempty"`
+	AssigneeID          string     `json:"assignee_id,omitempty"`
+	Status              string     `json:"status"`
+	DueAt               time.Time  `json:"due_at"`
+	EstimatedCompletion *time.Time `json:"estimated_completion,omitempty"`
+	OverdueHours        int        `json:"overdue_hours"`
+	Bucket              string     `json:"bucket"`
+}
+
+type SLABucket struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type SLABreachesResponse struct {
+	GeneratedAt time.Time   `json:"generated_at"`
+	Buckets     []SLABucket `json:"buckets"`
+	Breaches    []SLAItem   `json:"breaches"`
+}
+
+// WorkCapacity is how much one printing user (sheets) or plant user
+// (bottles) gets through in a business day.
+type WorkCapacity struct {
+	UserID        string    `json:"user_id"`
+	Role          string    `json:"role"`
+	DailyCapacity int       `json:"daily_capacity"`
+	Unit          string    `json:"unit"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type SaveCapacityRequest struct {
+	DailyCapacity *int `json:"daily_capacity" binding:"required,min=0"`
+}
+
+// QuoteRequest describes an order that is not placed yet. Without
+// no_of_sheets the sheets are worked out from labels_per_sheet.
+type QuoteRequest struct {
+	Qty            int `json:"qty" binding:"required,min=1"`
+	NoOfSheets     int `json:"no_of_sheets" binding:"omitempty,min=1"`
+	LabelsPerSheet int `json:"labels_per_sheet" binding:"omitempty,min=1"`
+}
+
+// StageEstimate is one stage of a delivery quote. DailyCapacity is the sum
+// declared by the role's users; when it is zero the stage is counted at its
+// fixed deadline. ReadyAfterDays is when the stage finishes the order.
+type StageEstimate struct {
+	Role           string `json:"role"`
+	Unit           string `json:"unit"`
+	Quantity       int    `json:"quantity"`
+	Backlog        int    `json:"backlog"`
+	DailyCapacity  int    `json:"daily_capacity"`
+	ReadyAfterDays int    `json:"ready_after_days"`
+}
+
+type DeliveryQuote struct {
+	ExpectedDelivery time.Time       `json:"expected_delivery"`
+	BusinessDays     int             `json:"business_days"`
+	Stages           []StageEstimate `json:"stages"`
+}
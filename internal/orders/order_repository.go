@@ -2,7 +2,6 @@ package orders
 
 import (
 	"database/sql"
-	"enerzyflow_backend/internal/db"
 	"enerzyflow_backend/utils"
 	"errors"
 	"fmt"
@@ -11,12 +10,194 @@ import (
 	"time"
 )
 
-func CreateOrder(order *Order, userID string) error {
+// OrderTxRepository is what a status transition may do while it holds the
+// order row lock.
+type OrderTxRepository interface {
+	UpdateOrderStatus(orderID, status, changedBy, reason string) error
+	AssignOrder(orderID, userID, role string, deadline time.Time) error
+	CompleteOrderAssignment(orderID, userID string) error
+	IsOrderAssignedToUser(orderID, userID, role string) (bool, error)
+	// ReservedFor returns the user the order is pinned to or leased to in
+	// role's work pool, or "" when it is free.
+	ReservedFor(orderID, role string) (string, error)
+
+	// CurrentAssignment returns the order's assignment for role, or nil.
+	CurrentAssignment(orderID, role string) (*OrderAssignment, error)
+	// ReassignOrder hands the current assignment for role to userID, keeping
+	// its deadline.
+	ReassignOrder(orderID, role, userID, changedBy, reason string) error
+	// UnassignOrder releases the current assignment for role.
+	UnassignOrder(orderID, role, changedBy, reason string) error
+	ExtendAssignmentDeadline(orderID, role string, deadline time.Time, changedBy, reason string) error
+	// ReleaseWorkPool drops the lease and pin on the order in role's work
+	// pool.
+	ReleaseWorkPool(orderID, role string) error
+}
+
+// OrderRepository is the storage behind orders. Writes that change status,
+// payment, invoices or comments raise order events in the same transaction
+// and bump the order's version.
+type OrderRepository interface {
+	OrderTxRepository
+	// WithLockedOrder loads the order with SELECT ... FOR UPDATE and runs fn
+	// in the same transaction; it commits when fn returns nil. order is nil
+	// when the order does not exist.
+	WithLockedOrder(orderID string, fn func(tx OrderTxRepository, order *OrderResponse) error) error
+
+	CreateOrder(order *Order, userID string) error
+	GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int, error)
+	GetOrdersCountByCompanyID(userID string) (int, error)
+	GetOrderByID(orderID string) (*OrderResponse, error)
+	UpdatePaymentStatus(orderID, paymentStatus, changedBy, reason string) error
+	GetOrderStatusHistory(orderID string) ([]OrderStatusHistory, error)
+	GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int, error)
+	UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error
+	UpdateOrderInvoice(orderID string, urls map[string]string) error
+	AddOrderComment(orderID, userID, role, comment string) error
+	GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error)
+	// GetOrderAssignments returns the current assignments of an order, one
+	// per role at most; GetAssignmentHistory has every change.
+	GetOrderAssignments(orderID string) ([]OrderAssignment, error)
+	GetAssignmentHistory(orderID string) ([]AssignmentHistoryEntry, error)
+	IsOrderInQueue(orderID, userID, role string) (bool, error)
+	SaveOrderLabelDetails(details OrderLabelDetails) error
+	GetOrderLabelDetails(orderID string) (*OrderLabelDetails, error)
+
+	// ClaimNextOrder leases the highest priority unassigned order in role's
+	// work pool to userID, or returns the lease userID already holds. It
+	// returns nil when there is nothing to claim.
+	ClaimNextOrder(userID, role string, lease time.Duration) (*WorkClaim, error)
+	PinOrder(pin OrderPin) error
+	UnpinOrder(orderID, role string) (bool, error)
+
+	// ListInFlightOrders returns the orders between placed and
+	// plant_processing with their open assignment, if any.
+	ListInFlightOrders() ([]InFlightOrder, error)
+	// RecordSLAEscalation stores item and raises EventSLAEscalated unless
+	// the same escalation was recorded before; it reports whether it did.
+	RecordSLAEscalation(item SLAItem, at time.Time) (bool, error)
+	// RescheduleDelivery moves the expected delivery of an unfinished order
+	// to expected and raises EventDeliveryRescheduled, unless the order is
+	// already due that late; it reports whether it moved the date.
+	RescheduleDelivery(orderID string, expected, at time.Time) (bool, error)
+
+	// ListWorkCapacities returns the declared capacity of every printing
+	// and plant user.
+	ListWorkCapacities() ([]WorkCapacity, error)
+	SaveWorkCapacity(c WorkCapacity) error
+}
+
+type PostgresOrderRepository struct {
+	db *sql.DB
+}
+
+func NewPostgresOrderRepository(conn *sql.DB) *PostgresOrderRepository {
+	return &PostgresOrderRepository{db: conn}
+}
+
+// queryer is satisfied by *sql.DB and *orderTx, so helpers can run inside
+// or outside a transaction.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+const orderByIDQuery = `
+        SELECT o.order_id, o.user_id, l.label_url AS label_url, 
+               o.variant, o.qty, o.cap_color, o.volume, COALESCE(o.sku_id, '') AS sku_id,
+               o.status,o.payment_status,o.decline_reason,o.payment_screenshot_url,o.invoice_url,o.pi_url,o.created_at, o.updated_at, o.expected_delivery_date, o.version
+        FROM orders o
+        LEFT JOIN labels l ON o.label_id = l.label_id
+        WHERE o.order_id = $1 `
+
+func scanOrder(row *sql.Row) (*OrderResponse, error) {
+	order := &OrderResponse{}
+	err := row.Scan(&order.OrderID, &order.UserID, &order.LabelURL, &order.Variant,
+		&order.Qty, &order.CapColor, &order.Volume, &order.SKUID, &order.Status, &order.PaymentStatus, &order.DeclineReason, &order.PaymentUrl, &order.InvoiceUrl, &order.PiUrl, &order.CreatedAt, &order.UpdatedAt, &order.ExpectedDelivery, &order.Version)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return order, nil
+}
+
+// pgOrderTx runs transition writes on the transaction opened by
+// WithLockedOrder.
+type pgOrderTx struct {
+	tx *orderTx
+}
+
+func (t *pgOrderTx) UpdateOrderStatus(orderID, status, changedBy, reason string) error {
+	return updateOrderStatusTx(t.tx, orderID, status, changedBy, reason)
+}
+
+func (t *pgOrderTx) AssignOrder(orderID, userID, role string, deadline time.Time) error {
+	return assignOrder(t.tx, orderID, userID, role, deadline)
+}
+
+func (t *pgOrderTx) CompleteOrderAssignment(orderID, userID string) error {
+	return completeOrderAssignment(t.tx, orderID, userID)
+}
+
+func (t *pgOrderTx) IsOrderAssignedToUser(orderID, userID, role string) (bool, error) {
+	return isOrderAssignedToUser(t.tx, orderID, userID, role)
+}
+
+func (t *pgOrderTx) ReservedFor(orderID, role string) (string, error) {
+	return reservedFor(t.tx, orderID, role)
+}
+
+func (t *pgOrderTx) CurrentAssignment(orderID, role string) (*OrderAssignment, error) {
+	return currentAssignment(t.tx, orderID, role)
+}
+
+func (t *pgOrderTx) ReassignOrder(orderID, role, userID, changedBy, reason string) error {
+	return reassignOrderTx(t.tx, orderID, role, userID, changedBy, reason)
+}
+
+func (t *pgOrderTx) UnassignOrder(orderID, role, changedBy, reason string) error {
+	return unassignOrderTx(t.tx, orderID, role, changedBy, reason)
+}
+
+func (t *pgOrderTx) ExtendAssignmentDeadline(orderID, role string, deadline time.Time, changedBy, reason string) error {
+	return extendAssignmentDeadlineTx(t.tx, orderID, role, deadline, changedBy, reason)
+}
+
+func (t *pgOrderTx) ReleaseWorkPool(orderID, role string) error {
+	return releaseWorkPool(t.tx, orderID, role)
+}
+
+func (r *PostgresOrderRepository) WithLockedOrder(orderID string, fn func(tx OrderTxRepository, order *OrderResponse) error) error {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	order, err := scanOrder(tx.QueryRow(orderByIDQuery+` FOR UPDATE OF o`, orderID))
+	if err != nil {
+		return err
+	}
+	if err = fn(&pgOrderTx{tx: tx}, order); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresOrderRepository) CreateOrder(order *Order, userID string) error {
 	if order.OrderID == "" {
 		return errors.New("order_id is required")
 	}
 
-	tx, err := db.DB.Begin()
+	tx, err := r.beginOrderTx()
 	if err != nil {
 		return err
 	}
@@ -27,8 +208,8 @@ func CreateOrder(order *Order, userID string) error {
 	}()
 
 	_, err = tx.Exec(`
-        INSERT INTO orders (order_id, user_id, label_id, variant, qty, cap_color, volume, created_at, updated_at, expected_delivery_date) 
-        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,$10)`,
+        INSERT INTO orders (order_id, user_id, label_id, variant, qty, cap_color, volume, created_at, updated_at, expected_delivery_date, sku_id) 
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,$10, NULLIF($11, ''))`,
 		order.OrderID,
 		userID,
 		order.LabelID,
@@ -39,15 +220,13 @@ func CreateOrder(order *Order, userID string) error {
 		order.CreatedAt,
 		order.UpdatedAt,
 		order.ExpectedDelivery,
+		order.SKUID,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to insert order: %w", err)
 	}
 
-	_, err = tx.Exec(`
-        INSERT INTO order_status_history (order_id, status, changed_at, changed_by)
-        VALUES ($1, $2, NOW(), $3)
-    `, order.OrderID, order.Status, userID)
+	err = insertStatusHistoryTx(tx, EventOrderCreated, order.OrderID, order.Status, "", userID, "")
 	if err != nil {
 		return fmt.Errorf("failed to insert initial status history: %w", err)
 	}
@@ -59,11 +238,11 @@ func CreateOrder(order *Order, userID string) error {
 	return nil
 }
 
-func GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int, error) {
-	rows, err := db.DB.Query(`
+func (r *PostgresOrderRepository) GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int, error) {
+	rows, err := r.db.Query(`
         SELECT o.order_id, o.user_id, l.label_url AS label_url, 
-            o.variant, o.qty, o.cap_color, o.volume, 
-            o.status,o.payment_status,o.decline_reason,o.payment_screenshot_url,o.invoice_url,o.pi_url,o.created_at, o.updated_at, o.expected_delivery_date,COUNT(*) OVER() AS total_count
+            o.variant, o.qty, o.cap_color, o.volume, COALESCE(o.sku_id, '') AS sku_id,
+            o.status,o.payment_status,o.decline_reason,o.payment_screenshot_url,o.invoice_url,o.pi_url,o.created_at, o.updated_at, o.expected_delivery_date, o.version, COUNT(*) OVER() AS total_count
         FROM orders o
         LEFT JOIN labels l ON o.label_id = l.label_id
         WHERE o.user_id = $1 
@@ -82,7 +261,7 @@ func GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int,
 	for rows.Next() {
 		var order OrderResponse
 		err := rows.Scan(&order.OrderID, &order.UserID, &order.LabelURL, &order.Variant,
-			&order.Qty, &order.CapColor, &order.Volume, &order.Status, &order.PaymentStatus, &order.DeclineReason, &order.PaymentUrl, &order.InvoiceUrl, &order.PiUrl, &order.CreatedAt, &order.UpdatedAt, &order.ExpectedDelivery, &total)
+			&order.Qty, &order.CapColor, &order.Volume, &order.SKUID, &order.Status, &order.PaymentStatus, &order.DeclineReason, &order.PaymentUrl, &order.InvoiceUrl, &order.PiUrl, &order.CreatedAt, &order.UpdatedAt, &order.ExpectedDelivery, &order.Version, &total)
 		if err != nil {
 			return nil, 0, err
 		}
@@ -96,36 +275,18 @@ func GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int,
 	return orders, total, nil
 }
 
-func GetOrdersCountByCompanyID(userID string) (int, error) {
+func (r *PostgresOrderRepository) GetOrdersCountByCompanyID(userID string) (int, error) {
 	var count int
-	err := db.DB.QueryRow(`SELECT COUNT(*) FROM orders WHERE user_id = $1`, userID).Scan(&count)
+	err := r.db.QueryRow(`SELECT COUNT(*) FROM orders WHERE user_id = $1`, userID).Scan(&count)
 	return count, err
 }
 
-func GetOrderByID(orderID string) (*OrderResponse, error) {
-	row := db.DB.QueryRow(`
-        SELECT o.order_id, o.user_id, l.label_url AS label_url, 
-               o.variant, o.qty, o.cap_color, o.volume, 
-               o.status,o.payment_status,o.decline_reason,o.payment_screenshot_url,o.invoice_url,o.pi_url,o.created_at, o.updated_at, o.expected_delivery_date
-        FROM orders o
-        LEFT JOIN labels l ON o.label_id = l.label_id
-        WHERE o.order_id = $1 `, orderID)
-
-	order := &OrderResponse{}
-	err := row.Scan(&order.OrderID, &order.UserID, &order.LabelURL, &order.Variant,
-		&order.Qty, &order.CapColor, &order.Volume, &order.Status, &order.PaymentStatus, &order.DeclineReason, &order.PaymentUrl, &order.InvoiceUrl, &order.PiUrl, &order.CreatedAt, &order.UpdatedAt, &order.ExpectedDelivery)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return order, nil
+func (r *PostgresOrderRepository) GetOrderByID(orderID string) (*OrderResponse, error) {
+	return scanOrder(r.db.QueryRow(orderByIDQuery, orderID))
 }
 
-func UpdateOrderStatus(orderID, status, changedBy, reason string) error {
-	tx, err := db.DB.Begin()
+func (r *PostgresOrderRepository) UpdateOrderStatus(orderID, status, changedBy, reason string) error {
+	tx, err := r.beginOrderTx()
 	if err != nil {
 		return err
 	}
@@ -135,36 +296,41 @@ func UpdateOrderStatus(orderID, status, changedBy, reason string) error {
 		}
 	}()
 
+	if err = updateOrderStatusTx(tx, orderID, status, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func updateOrderStatusTx(tx *orderTx, orderID, status, changedBy, reason string) error {
+	var previous string
+	if err := tx.QueryRow(`SELECT status FROM orders WHERE order_id = $1 FOR UPDATE`, orderID).Scan(&previous); err != nil {
+		return err
+	}
+
+	var err error
 	if status == "declined" {
 		_, err = tx.Exec(`
 		UPDATE orders 
-		SET status = $1, decline_reason = $2, updated_at = $3
+		SET status = $1, decline_reason = $2, updated_at = $3, version = version + 1
 		WHERE order_id = $4
 	`, status, reason, utils.NowInIST(), orderID)
 	} else {
 		_, err = tx.Exec(`
 		UPDATE orders 
-		SET status = $1, updated_at = $2
+		SET status = $1, updated_at = $2, version = version + 1
 		WHERE order_id = $3
 	`, status, utils.NowInIST(), orderID)
-		if err != nil {
-			return err
-		}
 	}
-
-	_, err = tx.Exec(`
-		INSERT INTO order_status_history (order_id, status, changed_at, changed_by, reason)
-		VALUES ($1, $2, $3, $4, $5)
-	`, orderID, status, utils.NowInIST(), changedBy, reason)
 	if err != nil {
 		return err
 	}
 
-	return tx.Commit()
+	return insertStatusHistoryTx(tx, EventStatusChanged, orderID, status, previous, changedBy, reason)
 }
 
-func UpdatePaymentStatus(orderID, paymentStatus, changedBy, reason string) error {
-	tx, err := db.DB.Begin()
+func (r *PostgresOrderRepository) UpdatePaymentStatus(orderID, paymentStatus, changedBy, reason string) error {
+	tx, err := r.beginOrderTx()
 	if err != nil {
 		return err
 	}
@@ -174,28 +340,32 @@ func UpdatePaymentStatus(orderID, paymentStatus, changedBy, reason string) error
 		}
 	}()
 
+	var previous string
+	if err = tx.QueryRow(`SELECT payment_status FROM orders WHERE order_id = $1 FOR UPDATE`, orderID).Scan(&previous); err != nil {
+		return err
+	}
+
 	_, err = tx.Exec(`
 		UPDATE orders
 		SET payment_status = $1,
-		    updated_at = $2
+		    payment_verified_at = CASE WHEN $1 = 'payment_verified' THEN $2 ELSE payment_verified_at END,
+		    updated_at = $2,
+		    version = version + 1
 		WHERE order_id = $3
 	`, paymentStatus, utils.NowInIST(), orderID)
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO order_status_history (order_id, status, changed_at, changed_by, reason)
-		VALUES ($1, $2, $3, $4, $5)
-	`, orderID, paymentStatus, utils.NowInIST(), changedBy, reason)
+	err = insertStatusHistoryTx(tx, EventPaymentUpdated, orderID, paymentStatus, previous, changedBy, reason)
 	if err != nil {
 		return err
 	}
 	return tx.Commit()
 }
 
-func GetOrderStatusHistory(orderID string) ([]OrderStatusHistory, error) {
-	rows, err := db.DB.Query(`
+func (r *PostgresOrderRepository) GetOrderStatusHistory(orderID string) ([]OrderStatusHistory, error) {
+	rows, err := r.db.Query(`
 		SELECT status, changed_at, changed_by, reason
 		FROM order_status_history
 		WHERE order_id = $1
@@ -217,7 +387,7 @@ func GetOrderStatusHistory(orderID string) ([]OrderStatusHistory, error) {
 	return history, nil
 }
 
-func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int, error) {
+func (r *PostgresOrderRepository) GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int, error) {
 	baseQuery := `
 	SELECT 
 		o.order_id,
@@ -229,6 +399,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		o.qty,
 		o.cap_color,
 		o.volume,
+		COALESCE(o.sku_id, '') AS sku_id,
 		o.status,
 		o.payment_status,
 		o.payment_screenshot_url,
@@ -252,7 +423,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 	switch role {
 	case "admin":
 		query := baseQuery + ` ORDER BY o.created_at DESC LIMIT $1 OFFSET $2`
-		rows, err = db.DB.Query(query, limit, offset)
+		rows, err = r.db.Query(query, limit, offset)
 
 	case "printing":
 		query := `
@@ -266,6 +437,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		o.qty,
 		o.cap_color,
 		o.volume,
+		COALESCE(o.sku_id, '') AS sku_id,
 		o.status,
 		COALESCE(o.decline_reason, '') AS decline_reason,
 		o.created_at,
@@ -277,7 +449,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 	LEFT JOIN labels l ON o.label_id = l.label_id
 	INNER JOIN users u ON o.user_id = u.user_id
 	INNER JOIN companies c ON u.user_id = c.user_id
-	LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'printing'
+	LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'printing' AND oa.released_at IS NULL
 	INNER JOIN order_label_details ld ON o.order_id = ld.order_id
 	WHERE 
 		o.payment_status = 'payment_verified' AND
@@ -287,7 +459,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		AND NOT (o.status = 'declined' AND oa.user_id IS DISTINCT FROM $3)
 	ORDER BY o.created_at DESC LIMIT $1 OFFSET $2
 	`
-		rows, err = db.DB.Query(query, limit, offset, userID)
+		rows, err = r.db.Query(query, limit, offset, userID)
 
 	case "plant":
 		query := `
@@ -301,6 +473,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		o.qty,
 		o.cap_color,
 		o.volume,
+		COALESCE(o.sku_id, '') AS sku_id,
 		o.status,
 		COALESCE(o.decline_reason, '') AS decline_reason,
 		o.created_at,
@@ -312,13 +485,13 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 	LEFT JOIN labels l ON o.label_id = l.label_id
 	INNER JOIN users u ON o.user_id = u.user_id
 	INNER JOIN companies c ON u.user_id = c.user_id
-	LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'plant'
+	LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'plant' AND oa.released_at IS NULL
 	WHERE 
 		o.status IN ('ready_for_plant', 'plant_processing', 'dispatched', 'completed')
 		AND (oa.user_id IS NULL OR oa.user_id = $3)
 	ORDER BY o.created_at DESC LIMIT $1 OFFSET $2
 	`
-		rows, err = db.DB.Query(query, limit, offset, userID)
+		rows, err = r.db.Query(query, limit, offset, userID)
 
 	default:
 		return nil, 0, fmt.Errorf("unauthorized role: %s", role)
@@ -338,7 +511,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		if role == "admin" {
 			if err := rows.Scan(
 				&o.OrderID, &o.UserID, &o.CompanyName, &o.LabelID, &o.LabelURL,
-				&o.Variant, &o.Qty, &o.CapColor, &o.Volume, &o.Status,
+				&o.Variant, &o.Qty, &o.CapColor, &o.Volume, &o.SKUID, &o.Status,
 				&o.PaymentStatus, &o.PaymentUrl, &o.InvoiceUrl, &o.PiUrl, &o.DeclineReason,
 				&o.CreatedAt, &o.UpdatedAt, &o.UserName, &o.Deadline, &total,
 			); err != nil {
@@ -347,7 +520,7 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 		} else {
 			if err := rows.Scan(
 				&o.OrderID, &o.UserID, &o.CompanyName, &o.LabelID, &o.LabelURL,
-				&o.Variant, &o.Qty, &o.CapColor, &o.Volume, &o.Status,
+				&o.Variant, &o.Qty, &o.CapColor, &o.Volume, &o.SKUID, &o.Status,
 				&o.DeclineReason, &o.CreatedAt, &o.UpdatedAt, &o.UserName,
 				&o.Deadline, &total,
 			); err != nil {
@@ -361,12 +534,12 @@ func GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int,
 	return orders, total, nil
 }
 
-func UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error {
+func (r *PostgresOrderRepository) UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error {
 	if orderID == "" || screenshotURL == "" {
 		return errors.New("orderID and screenshotURL cannot be empty")
 	}
 
-	tx, err := db.DB.Begin()
+	tx, err := r.beginOrderTx()
 	if err != nil {
 		return err
 	}
@@ -376,21 +549,24 @@ func UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error {
 		}
 	}()
 
+	var previous string
+	if err = tx.QueryRow(`SELECT payment_status FROM orders WHERE order_id = $1 FOR UPDATE`, orderID).Scan(&previous); err != nil {
+		return err
+	}
+
 	_, err = tx.Exec(`
 		UPDATE orders
 		SET payment_screenshot_url = $1,
 		    payment_status = 'payment_uploaded',
-		    updated_at = $2
+		    updated_at = $2,
+		    version = version + 1
 		WHERE order_id = $3
 	`, screenshotURL, utils.NowInIST(), orderID)
 	if err != nil {
 		return fmt.Errorf("failed to update order payment screenshot: %w", err)
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO order_status_history (order_id, status, changed_at, changed_by)
-		VALUES ($1, $2,$3, $4)
-	`, orderID, "payment_uploaded", utils.NowInIST(), userID)
+	err = insertStatusHistoryTx(tx, EventPaymentUpdated, orderID, "payment_uploaded", previous, userID, "")
 	if err != nil {
 		return fmt.Errorf("failed to insert into order_status_history: %w", err)
 	}
@@ -402,8 +578,8 @@ func UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error {
 	return nil
 }
 
-func UpdateOrderInvoice(orderID string, urls map[string]string) error {
-	tx, err := db.DB.Begin()
+func (r *PostgresOrderRepository) UpdateOrderInvoice(orderID string, urls map[string]string) error {
+	tx, err := r.beginOrderTx()
 	if err != nil {
 		return err
 	}
@@ -430,31 +606,43 @@ func UpdateOrderInvoice(orderID string, urls map[string]string) error {
 	}
 
 	if len(setParts) == 0 {
-		return errors.New("no URLs to update")
+		err = errors.New("no URLs to update")
+		return err
 	}
 
-	setParts = append(setParts, "updated_at = NOW()")
+	setParts = append(setParts, "updated_at = NOW()", "version = version + 1")
 
 	query := "UPDATE orders SET " + strings.Join(setParts, ", ") + " WHERE order_id = $" + strconv.Itoa(argIdx)
 	args = append(args, orderID)
 
-	if _, err := tx.Exec(query, args...); err != nil {
+	if _, err = tx.Exec(query, args...); err != nil {
 		return fmt.Errorf("failed to update order: %w", err)
 	}
 
+	if err = raiseInvoiceUploadedTx(tx, orderID, urls); err != nil {
+		return err
+	}
 	return tx.Commit()
 }
 
-func AddOrderComment(orderID, userID, role, comment string) error {
-	_, err := db.DB.Exec(`
-        INSERT INTO order_comments (order_id, user_id, role, comment, created_at)
-        VALUES ($1, $2, $3, $4, $5)
-    `, orderID, userID, role, comment, utils.NowInIST())
+func (r *PostgresOrderRepository) AddOrderComment(orderID, userID, role, comment string) error {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
 
-	return err
+	if err = insertCommentTx(tx, orderID, userID, role, comment); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error) {
+func (r *PostgresOrderRepository) GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error) {
 	query := `SELECT id, order_id, user_id, role, comment, created_at
         FROM order_comments `
 
@@ -464,14 +652,14 @@ func GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error) {
 	switch role {
 	case "admin":
 		query += `Where order_id = $1`
-		rows, err = db.DB.Query(query, orderID)
+		rows, err = r.db.Query(query, orderID)
 		if err != nil {
 			return nil, err
 		}
 	
 	case "printing", "plant":
 		query+= `Where order_id = $1 and user_id = $2`
-		rows, err = db.DB.Query(query,orderID,userID)
+		rows, err = r.db.Query(query,orderID,userID)
 		if err != nil {
 			return nil, err
 		}
@@ -489,29 +677,94 @@ func GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error) {
 	return comments, nil
 }
 
-func AssignOrder(orderID, userID, role string, deadlineDays int) error {
-	deadline := utils.NowInIST().Add(time.Duration(deadlineDays*24) * time.Hour)
-	_, err := db.DB.Exec(`
+func (r *PostgresOrderRepository) AssignOrder(orderID, userID, role string, deadline time.Time) error {
+	return assignOrder(r.db, orderID, userID, role, deadline)
+}
+
+func assignOrder(q queryer, orderID, userID, role string, deadline time.Time) error {
+	now := utils.NowInIST()
+	_, err := q.Exec(`
         INSERT INTO order_assignments (order_id, user_id, role, assigned_at, deadline)
         VALUES ($1, $2, $3,$4, $5)
-    `, orderID, userID, role, utils.NowInIST(), deadline)
+    `, orderID, userID, role, now, deadline)
+	if err != nil {
+		return err
+	}
+	err = insertAssignmentHistory(q, AssignmentHistoryEntry{
+		OrderID:   orderID,
+		Role:      role,
+		Action:    AssignmentAssigned,
+		ToUserID:  userID,
+		Deadline:  &deadline,
+		ChangedBy: userID,
+		ChangedAt: now,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The order has left the work pool, so its lease and pin are done.
+	return releaseWorkPool(q, orderID, role)
+}
+
+func (r *PostgresOrderRepository) ReleaseWorkPool(orderID, role string) error {
+	return releaseWorkPool(r.db, orderID, role)
+}
+
+func releaseWorkPool(q queryer, orderID, role string) error {
+	if _, err := q.Exec(`DELETE FROM order_claims WHERE order_id = $1 AND role = $2`, orderID, role); err != nil {
+		return err
+	}
+	_, err := q.Exec(`DELETE FROM order_pins WHERE order_id = $1 AND role = $2`, orderID, role)
 	return err
 }
 
-func CompleteOrderAssignment(orderID, userID string) error {
-	_, err := db.DB.Exec(`
+func (r *PostgresOrderRepository) CompleteOrderAssignment(orderID, userID string) error {
+	return completeOrderAssignment(r.db, orderID, userID)
+}
+
+func completeOrderAssignment(q queryer, orderID, userID string) error {
+	now := utils.NowInIST()
+	rows, err := q.Query(`
         UPDATE order_assignments
         SET completed_at = $1
-        WHERE order_id = $2
-    `, utils.NowInIST(), orderID)
-	return err
+        WHERE order_id = $2 AND released_at IS NULL AND completed_at IS NULL
+        RETURNING role, user_id::text, deadline
+    `, now, orderID)
+	if err != nil {
+		return err
+	}
+
+	var completed []AssignmentHistoryEntry
+	for rows.Next() {
+		e := AssignmentHistoryEntry{OrderID: orderID, Action: AssignmentCompleted, ChangedBy: userID, ChangedAt: now}
+		var deadline time.Time
+		if err := rows.Scan(&e.Role, &e.FromUserID, &deadline); err != nil {
+			rows.Close()
+			return err
+		}
+		e.Deadline = &deadline
+		completed = append(completed, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, e := range completed {
+		if err := insertAssignmentHistory(q, e); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func GetOrderAssignments(orderID string) ([]OrderAssignment, error) {
-	rows, err := db.DB.Query(`
+func (r *PostgresOrderRepository) GetOrderAssignments(orderID string) ([]OrderAssignment, error) {
+	rows, err := r.db.Query(`
 		SELECT order_id, user_id, role, assigned_at, deadline, completed_at
 		FROM order_assignments
-		WHERE order_id = $1
+		WHERE order_id = $1 AND released_at IS NULL
+		ORDER BY assigned_at
 	`, orderID)
 	if err != nil {
 		return nil, err
@@ -529,23 +782,567 @@ func GetOrderAssignments(orderID string) ([]OrderAssignment, error) {
 	return assignments, nil
 }
 
-func IsOrderAssignedToUser(orderID, userID, role string) (bool, error) {
+func (r *PostgresOrderRepository) GetAssignmentHistory(orderID string) ([]AssignmentHistoryEntry, error) {
+	rows, err := r.db.Query(`
+		SELECT id, order_id, role, action, COALESCE(from_user_id::text, ''), COALESCE(to_user_id::text, ''),
+		       deadline, reason, changed_by, changed_at
+		FROM order_assignment_history
+		WHERE order_id = $1
+		ORDER BY changed_at, id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []AssignmentHistoryEntry
+	for rows.Next() {
+		var (
+			e        AssignmentHistoryEntry
+			deadline sql.NullTime
+		)
+		if err := rows.Scan(&e.ID, &e.OrderID, &e.Role, &e.Action, &e.FromUserID, &e.ToUserID,
+			&deadline, &e.Reason, &e.ChangedBy, &e.ChangedAt); err != nil {
+			return nil, err
+		}
+		if deadline.Valid {
+			e.Deadline = &deadline.Time
+		}
+		history = append(history, e)
+	}
+	return history, rows.Err()
+}
+
+// insertAssignmentHistory is the single place order_assignment_history is
+// written.
+func insertAssignmentHistory(q queryer, e AssignmentHistoryEntry) error {
+	_, err := q.Exec(`
+		INSERT INTO order_assignment_history (order_id, role, action, from_user_id, to_user_id, deadline, reason, changed_by, changed_at)
+		VALUES ($1, $2, $3, NULLIF($4, '')::uuid, NULLIF($5, '')::uuid, $6, $7, $8, $9)
+	`, e.OrderID, e.Role, e.Action, e.FromUserID, e.ToUserID, e.Deadline, e.Reason, e.ChangedBy, e.ChangedAt)
+	return err
+}
+
+func (r *PostgresOrderRepository) CurrentAssignment(orderID, role string) (*OrderAssignment, error) {
+	return currentAssignment(r.db, orderID, role)
+}
+
+func currentAssignment(q queryer, orderID, role string) (*OrderAssignment, error) {
+	var a OrderAssignment
+	err := q.QueryRow(`
+		SELECT order_id, user_id, role, assigned_at, deadline, completed_at
+		FROM order_assignments
+		WHERE order_id = $1 AND role = $2 AND released_at IS NULL
+	`, orderID, role).Scan(&a.OrderID, &a.UserID, &a.Role, &a.AssignedAt, &a.Deadline, &a.CompletedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// releaseAssignment marks the current assignment for role as replaced and
+// returns it.
+func releaseAssignment(tx *orderTx, orderID, role string, now time.Time) (*OrderAssignment, error) {
+	var a OrderAssignment
+	err := tx.QueryRow(`
+		UPDATE order_assignments
+		SET released_at = $1
+		WHERE order_id = $2 AND role = $3 AND released_at IS NULL
+		RETURNING order_id, user_id, role, assigned_at, deadline, completed_at
+	`, now, orderID, role).Scan(&a.OrderID, &a.UserID, &a.Role, &a.AssignedAt, &a.Deadline, &a.CompletedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("order has no %s assignment", role)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *PostgresOrderRepository) ReassignOrder(orderID, role, userID, changedBy, reason string) error {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = reassignOrderTx(tx, orderID, role, userID, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func reassignOrderTx(tx *orderTx, orderID, role, userID, changedBy, reason string) error {
+	now := utils.NowInIST()
+	previous, err := releaseAssignment(tx, orderID, role, now)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO order_assignments (order_id, user_id, role, assigned_at, deadline)
+		VALUES ($1, $2, $3, $4, $5)
+	`, orderID, userID, role, now, previous.Deadline)
+	if err != nil {
+		return err
+	}
+
+	return raiseAssignmentChangedTx(tx, AssignmentHistoryEntry{
+		OrderID:    orderID,
+		Role:       role,
+		Action:     AssignmentReassigned,
+		FromUserID: previous.UserID,
+		ToUserID:   userID,
+		Deadline:   &previous.Deadline,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+		ChangedAt:  now,
+	})
+}
+
+func (r *PostgresOrderRepository) UnassignOrder(orderID, role, changedBy, reason string) error {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = unassignOrderTx(tx, orderID, role, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func unassignOrderTx(tx *orderTx, orderID, role, changedBy, reason string) error {
+	now := utils.NowInIST()
+	previous, err := releaseAssignment(tx, orderID, role, now)
+	if err != nil {
+		return err
+	}
+
+	return raiseAssignmentChangedTx(tx, AssignmentHistoryEntry{
+		OrderID:    orderID,
+		Role:       role,
+		Action:     AssignmentUnassigned,
+		FromUserID: previous.UserID,
+		Deadline:   &previous.Deadline,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+		ChangedAt:  now,
+	})
+}
+
+func (r *PostgresOrderRepository) ExtendAssignmentDeadline(orderID, role string, deadline time.Time, changedBy, reason string) error {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err = extendAssignmentDeadlineTx(tx, orderID, role, deadline, changedBy, reason); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func extendAssignmentDeadlineTx(tx *orderTx, orderID, role string, deadline time.Time, changedBy, reason string) error {
+	var assignee string
+	err := tx.QueryRow(`
+		UPDATE order_assignments
+		SET deadline = $1
+		WHERE order_id = $2 AND role = $3 AND released_at IS NULL
+		RETURNING user_id::text
+	`, deadline, orderID, role).Scan(&assignee)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("order has no %s assignment", role)
+	}
+	if err != nil {
+		return err
+	}
+
+	return raiseAssignmentChangedTx(tx, AssignmentHistoryEntry{
+		OrderID:   orderID,
+		Role:      role,
+		Action:    AssignmentDeadlineExtended,
+		ToUserID:  assignee,
+		Deadline:  &deadline,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: utils.NowInIST(),
+	})
+}
+
+func (r *PostgresOrderRepository) IsOrderAssignedToUser(orderID, userID, role string) (bool, error) {
+	return isOrderAssignedToUser(r.db, orderID, userID, role)
+}
+
+func isOrderAssignedToUser(q queryer, orderID, userID, role string) (bool, error) {
 	var exists bool
-	err := db.DB.QueryRow(`
+	err := q.QueryRow(`
 		SELECT EXISTS(
 			SELECT 1 FROM order_assignments
-			WHERE order_id = $1 AND user_id = $2 AND role = $3
+			WHERE order_id = $1 AND user_id = $2 AND role = $3 AND released_at IS NULL
 		)
 	`, orderID, userID, role).Scan(&exists)
 	return exists, err
 }
 
-func SaveOrderLabelDetails(details OrderLabelDetails) error {
+// IsOrderInQueue reports whether the order currently shows up in the
+// printing or plant queue of userID, using the same rules as GetAllOrders.
+func (r *PostgresOrderRepository) IsOrderInQueue(orderID, userID, role string) (bool, error) {
+	var query string
+	switch role {
+	case "printing":
+		query = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM orders o
+			INNER JOIN order_label_details ld ON o.order_id = ld.order_id
+			LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'printing' AND oa.released_at IS NULL
+			WHERE o.order_id = $1
+				AND o.payment_status = 'payment_verified'
+				AND (oa.user_id IS NULL OR oa.user_id = $2)
+				AND NOT (o.status = 'declined' AND oa.user_id IS DISTINCT FROM $2)
+		)`
+	case "plant":
+		query = `
+		SELECT EXISTS(
+			SELECT 1
+			FROM orders o
+			LEFT JOIN order_assignments oa ON o.order_id = oa.order_id AND oa.role = 'plant' AND oa.released_at IS NULL
+			WHERE o.order_id = $1
+				AND o.status IN ('ready_for_plant', 'plant_processing', 'dispatched', 'completed')
+				AND (oa.user_id IS NULL OR oa.user_id = $2)
+		)`
+	default:
+		return false, fmt.Errorf("unsupported queue role: %s", role)
+	}
+
+	var exists bool
+	err := r.db.QueryRow(query, orderID, userID).Scan(&exists)
+	return exists, err
+}
+
+func (r *PostgresOrderRepository) ReservedFor(orderID, role string) (string, error) {
+	return reservedFor(r.db, orderID, role)
+}
+
+func reservedFor(q queryer, orderID, role string) (string, error) {
+	var userID string
+	err := q.QueryRow(`
+		SELECT COALESCE(
+			(SELECT user_id::text FROM order_pins WHERE order_id = $1 AND role = $2),
+			(SELECT user_id::text FROM order_claims WHERE order_id = $1 AND role = $2 AND lease_expires_at > $3),
+			''
+		)
+	`, orderID, role, utils.NowInIST()).Scan(&userID)
+	return userID, err
+}
+
+// workPoolFilters select the orders each role can accept next, matching the
+// "accepted" transitions.
+var workPoolFilters = map[string]string{
+	"printing": `o.status = 'placed' AND o.payment_status = 'payment_verified'
+			AND EXISTS (SELECT 1 FROM order_label_details ld WHERE ld.order_id = o.order_id)`,
+	"plant": `o.status = 'ready_for_plant'`,
+}
+
+var errClaimLost = errors.New("claim lost to a concurrent claimer")
+
+func (r *PostgresOrderRepository) ClaimNextOrder(userID, role string, lease time.Duration) (*WorkClaim, error) {
+	filter, ok := workPoolFilters[role]
+	if !ok {
+		return nil, fmt.Errorf("unsupported queue role: %s", role)
+	}
+
+	// A claim committed after the SELECT took its snapshot but before it
+	// locked the row is invisible to it. The upsert does see that claim and
+	// leaves it alone, and the next attempt skips the order.
+	for attempt := 0; attempt < 3; attempt++ {
+		claim, err := r.tryClaimOrder(filter, userID, role, lease)
+		if err != errClaimLost {
+			return claim, err
+		}
+	}
+	return nil, nil
+}
+
+func (r *PostgresOrderRepository) tryClaimOrder(filter, userID, role string, lease time.Duration) (*WorkClaim, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	now := utils.NowInIST()
+	var (
+		orderID      string
+		pinned       bool
+		holder       sql.NullString
+		claimedAt    sql.NullTime
+		leaseExpires sql.NullTime
+	)
+	err = tx.QueryRow(`
+		SELECT o.order_id, p.user_id IS NOT NULL, c.user_id::text, c.claimed_at, c.lease_expires_at
+		FROM orders o
+		LEFT JOIN order_claims c ON c.order_id = o.order_id AND c.role = $1
+		LEFT JOIN order_pins p ON p.order_id = o.order_id AND p.role = $1
+		WHERE `+filter+`
+			AND NOT EXISTS (SELECT 1 FROM order_assignments oa WHERE oa.order_id = o.order_id AND oa.role = $1 AND oa.released_at IS NULL)
+			AND (p.user_id IS NULL OR p.user_id = $2)
+			AND (c.user_id IS NULL OR c.user_id = $2 OR c.lease_expires_at <= $3)
+		ORDER BY
+			(c.user_id = $2 AND c.lease_expires_at > $3) IS TRUE DESC,
+			p.user_id IS NOT NULL DESC,
+			o.expected_delivery_date ASC,
+			o.payment_verified_at ASC NULLS LAST,
+			o.created_at ASC
+		LIMIT 1
+		FOR UPDATE OF o SKIP LOCKED
+	`, role, userID, now).Scan(&orderID, &pinned, &holder, &claimedAt, &leaseExpires)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	claim := &WorkClaim{OrderID: orderID, Role: role, UserID: userID, Pinned: pinned}
+	if holder.Valid && holder.String == userID && leaseExpires.Time.After(now) {
+		claim.ClaimedAt = claimedAt.Time
+		claim.LeaseExpiresAt = leaseExpires.Time
+		return claim, tx.Commit()
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO order_claims (order_id, role, user_id, claimed_at, lease_expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id, role) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    claimed_at = EXCLUDED.claimed_at,
+		    lease_expires_at = EXCLUDED.lease_expires_at
+		WHERE order_claims.lease_expires_at <= EXCLUDED.claimed_at
+		RETURNING claimed_at, lease_expires_at
+	`, orderID, role, userID, now, now.Add(lease)).Scan(&claim.ClaimedAt, &claim.LeaseExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, errClaimLost
+	}
+	if err != nil {
+		return nil, err
+	}
+	return claim, tx.Commit()
+}
+
+// PinOrder reserves the order for pin.UserID and drops any lease another
+// user holds on it.
+func (r *PostgresOrderRepository) PinOrder(pin OrderPin) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT 1 FROM orders WHERE order_id = $1 FOR UPDATE`, pin.OrderID); err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO order_pins (order_id, role, user_id, pinned_by, pinned_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (order_id, role) DO UPDATE
+		SET user_id = EXCLUDED.user_id,
+		    pinned_by = EXCLUDED.pinned_by,
+		    pinned_at = EXCLUDED.pinned_at
+	`, pin.OrderID, pin.Role, pin.UserID, pin.PinnedBy, pin.PinnedAt)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`DELETE FROM order_claims WHERE order_id = $1 AND role = $2 AND user_id <> $3`, pin.OrderID, pin.Role, pin.UserID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *PostgresOrderRepository) UnpinOrder(orderID, role string) (bool, error) {
+	res, err := r.db.Exec(`DELETE FROM order_pins WHERE order_id = $1 AND role = $2`, orderID, role)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *PostgresOrderRepository) ListInFlightOrders() ([]InFlightOrder, error) {
+	rows, err := r.db.Query(`
+		SELECT o.order_id, o.status, o.payment_status, o.expected_delivery_date,
+		       o.qty, COALESCE(ld.no_of_sheets, 0),
+		       oa.user_id::text, oa.role, oa.assigned_at, oa.deadline
+		FROM orders o
+		LEFT JOIN order_label_details ld ON ld.order_id = o.order_id
+		LEFT JOIN order_assignments oa ON oa.order_id = o.order_id
+			AND oa.released_at IS NULL AND oa.completed_at IS NULL
+		WHERE o.status IN ('placed', 'printing', 'ready_for_plant', 'plant_processing')
+		ORDER BY o.expected_delivery_date ASC, o.order_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []InFlightOrder
+	for rows.Next() {
+		var (
+			o          InFlightOrder
+			userID     sql.NullString
+			role       sql.NullString
+			assignedAt sql.NullTime
+			deadline   sql.NullTime
+		)
+		if err := rows.Scan(&o.OrderID, &o.Status, &o.PaymentStatus, &o.ExpectedDelivery,
+			&o.Qty, &o.Sheets, &userID, &role, &assignedAt, &deadline); err != nil {
+			return nil, err
+		}
+		if userID.Valid {
+			o.Assignment = &OrderAssignment{
+				OrderID:    o.OrderID,
+				UserID:     userID.String,
+				Role:       role.String,
+				AssignedAt: assignedAt.Time,
+				Deadline:   deadline.Time,
+			}
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+func (r *PostgresOrderRepository) RecordSLAEscalation(item SLAItem, at time.Time) (bool, error) {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var id int64
+	err = tx.QueryRow(`
+		INSERT INTO sla_escalations (order_id, kind, role, level, assignee_id, due_at, escalated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (order_id, kind, role, level, due_at) DO NOTHING
+		RETURNING id
+	`, item.OrderID, item.Kind, item.Role, item.Level, item.AssigneeID, item.DueAt, at).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if err = raiseSLAEscalatedTx(tx, item, at); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *PostgresOrderRepository) RescheduleDelivery(orderID string, expected, at time.Time) (bool, error) {
+	tx, err := r.beginOrderTx()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	var (
+		ownerID, status string
+		previous        time.Time
+	)
+	err = tx.QueryRow(`
+		SELECT user_id, status, expected_delivery_date
+		FROM orders
+		WHERE order_id = $1
+		FOR UPDATE
+	`, orderID).Scan(&ownerID, &status, &previous)
+	if err != nil {
+		return false, err
+	}
+	if !expected.After(previous) || !inFlightStatuses[status] {
+		return false, tx.Rollback()
+	}
+
+	_, err = tx.Exec(`
+		UPDATE orders SET expected_delivery_date = $1, version = version + 1, updated_at = $2
+		WHERE order_id = $3
+	`, expected, at, orderID)
+	if err != nil {
+		return false, err
+	}
+	if err = tx.raise(deliveryRescheduledEvent(orderID, ownerID, status, previous, expected, at)); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (r *PostgresOrderRepository) ListWorkCapacities() ([]WorkCapacity, error) {
+	rows, err := r.db.Query(`
+		SELECT wc.user_id::text, wc.role, wc.daily_capacity, wc.updated_at
+		FROM work_capacities wc
+		INNER JOIN users u ON u.user_id = wc.user_id AND u.role = wc.role
+		ORDER BY wc.role, wc.user_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []WorkCapacity
+	for rows.Next() {
+		var c WorkCapacity
+		if err := rows.Scan(&c.UserID, &c.Role, &c.DailyCapacity, &c.UpdatedAt); err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+func (r *PostgresOrderRepository) SaveWorkCapacity(c WorkCapacity) error {
+	_, err := r.db.Exec(`
+		INSERT INTO work_capacities (user_id, role, daily_capacity, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET role = EXCLUDED.role,
+		    daily_capacity = EXCLUDED.daily_capacity,
+		    updated_at = EXCLUDED.updated_at
+	`, c.UserID, c.Role, c.DailyCapacity, c.UpdatedAt)
+	return err
+}
+
+func (r *PostgresOrderRepository) SaveOrderLabelDetails(details OrderLabelDetails) error {
 	if details.OrderID == "" {
 		return errors.New("order_id is required")
 	}
 
-	_, err := db.DB.Exec(`
+	_, err := r.db.Exec(`
         INSERT INTO order_label_details 
             (order_id, no_of_sheets, cutting_type, labels_per_sheet, description)
         VALUES ($1, $2, $3, $4, $5)
@@ -560,8 +1357,8 @@ func SaveOrderLabelDetails(details OrderLabelDetails) error {
 	return err
 }
 
-func GetOrderLabelDetails(orderID string) (*OrderLabelDetails, error) {
-	row := db.DB.QueryRow(`
+func (r *PostgresOrderRepository) GetOrderLabelDetails(orderID string) (*OrderLabelDetails, error) {
+	row := r.db.QueryRow(`
         SELECT id, order_id, no_of_sheets, cutting_type, labels_per_sheet, description FROM order_label_details
         WHERE order_id = $1
     `, orderID)
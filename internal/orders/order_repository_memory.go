@@ -0,0 +1,861 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"enerzyflow_backend/utils"
+)
+
+// MemoryOrderRepository is an in-memory OrderRepository for tests. It keeps
+// the role scoping of the Postgres queries and publishes order events on the
+// bus, but event hooks are not run since there is no transaction to join.
+// Label URLs, user names and company names are not resolved, and
+// WithLockedOrder serializes callers but does not roll back on error.
+type MemoryOrderRepository struct {
+	lockMu      sync.Mutex
+	mu          sync.Mutex
+	orders      map[string]*OrderResponse
+	labelIDs    map[string]string
+	history     map[string][]OrderStatusHistory
+	comments    []OrderComment
+	assignments []OrderAssignment
+	assignLog   []AssignmentHistoryEntry
+	labels      map[string]OrderLabelDetails
+	verifiedAt  map[string]time.Time
+	claims      map[string]WorkClaim
+	pins        map[string]OrderPin
+	escalations map[string]bool
+	capacities  map[string]WorkCapacity
+}
+
+func NewMemoryOrderRepository() *MemoryOrderRepository {
+	return &MemoryOrderRepository{
+		orders:      map[string]*OrderResponse{},
+		labelIDs:    map[string]string{},
+		history:     map[string][]OrderStatusHistory{},
+		labels:      map[string]OrderLabelDetails{},
+		verifiedAt:  map[string]time.Time{},
+		claims:      map[string]WorkClaim{},
+		pins:        map[string]OrderPin{},
+		escalations: map[string]bool{},
+		capacities:  map[string]WorkCapacity{},
+	}
+}
+
+// recordLocked appends a history row and returns the event to publish once
+// the lock is released.
+func (r *MemoryOrderRepository) recordLocked(eventType string, o *OrderResponse, status, previous, changedBy, reason string) OrderEvent {
+	now := utils.NowInIST()
+	r.history[o.OrderID] = append(r.history[o.OrderID], OrderStatusHistory{
+		Status:    status,
+		ChangedAt: now,
+		ChangedBy: changedBy,
+		Reason:    reason,
+	})
+	return OrderEvent{
+		Type:           eventType,
+		OrderID:        o.OrderID,
+		OwnerID:        o.UserID,
+		Status:         status,
+		PreviousStatus: previous,
+		ChangedBy:      changedBy,
+		Reason:         reason,
+		OccurredAt:     now,
+	}
+}
+
+func (r *MemoryOrderRepository) CreateOrder(order *Order, userID string) error {
+	if order.OrderID == "" {
+		return errors.New("order_id is required")
+	}
+	r.mu.Lock()
+	if _, ok := r.orders[order.OrderID]; ok {
+		r.mu.Unlock()
+		return fmt.Errorf("failed to insert order: duplicate order_id %s", order.OrderID)
+	}
+	o := &OrderResponse{
+		OrderID:          order.OrderID,
+		UserID:           userID,
+		LabelURL:         order.LabelURL,
+		Variant:          order.Variant,
+		Qty:              order.Qty,
+		CapColor:         order.CapColor,
+		Volume:           order.Volume,
+		SKUID:            order.SKUID,
+		Status:           "placed",
+		PaymentStatus:    "payment_pending",
+		ExpectedDelivery: order.ExpectedDelivery,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+		Version:          1,
+	}
+	r.orders[o.OrderID] = o
+	r.labelIDs[o.OrderID] = order.LabelID
+	ev := r.recordLocked(EventOrderCreated, o, order.Status, "", userID, "")
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) sortedLocked(match func(o *OrderResponse) bool) []*OrderResponse {
+	var list []*OrderResponse
+	for _, o := range r.orders {
+		if match(o) {
+			list = append(list, o)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+func page[T any](list []T, limit, offset int) []T {
+	if offset >= len(list) {
+		return nil
+	}
+	list = list[offset:]
+	if limit >= 0 && limit < len(list) {
+		list = list[:limit]
+	}
+	return list
+}
+
+func (r *MemoryOrderRepository) GetOrdersByUserID(userID string, limit, offset int) ([]OrderResponse, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := r.sortedLocked(func(o *OrderResponse) bool { return o.UserID == userID })
+	var orders []OrderResponse
+	for _, o := range page(matched, limit, offset) {
+		orders = append(orders, *o)
+	}
+	return orders, len(matched), nil
+}
+
+func (r *MemoryOrderRepository) GetOrdersCountByCompanyID(userID string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return len(r.sortedLocked(func(o *OrderResponse) bool { return o.UserID == userID })), nil
+}
+
+func (r *MemoryOrderRepository) GetOrderByID(orderID string) (*OrderResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[orderID]
+	if !ok {
+		return nil, nil
+	}
+	c := *o
+	return &c, nil
+}
+
+func (r *MemoryOrderRepository) WithLockedOrder(orderID string, fn func(tx OrderTxRepository, order *OrderResponse) error) error {
+	r.lockMu.Lock()
+	defer r.lockMu.Unlock()
+
+	order, err := r.GetOrderByID(orderID)
+	if err != nil {
+		return err
+	}
+	return fn(r, order)
+}
+
+func (r *MemoryOrderRepository) UpdateOrderStatus(orderID, status, changedBy, reason string) error {
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	previous := o.Status
+	o.Status = status
+	if status == "declined" {
+		o.DeclineReason = reason
+	}
+	o.UpdatedAt = utils.NowInIST()
+	o.Version++
+	ev := r.recordLocked(EventStatusChanged, o, status, previous, changedBy, reason)
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) UpdatePaymentStatus(orderID, paymentStatus, changedBy, reason string) error {
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	previous := o.PaymentStatus
+	o.PaymentStatus = paymentStatus
+	o.UpdatedAt = utils.NowInIST()
+	if paymentStatus == "payment_verified" {
+		r.verifiedAt[orderID] = o.UpdatedAt
+	}
+	o.Version++
+	ev := r.recordLocked(EventPaymentUpdated, o, paymentStatus, previous, changedBy, reason)
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetOrderStatusHistory(orderID string) ([]OrderStatusHistory, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]OrderStatusHistory(nil), r.history[orderID]...), nil
+}
+
+// assignmentLocked returns the assignment for orderID in role, if any.
+func (r *MemoryOrderRepository) assignmentLocked(orderID, role string) *OrderAssignment {
+	for i := range r.assignments {
+		a := &r.assignments[i]
+		if a.OrderID == orderID && a.Role == role && !a.ReleasedAt.Valid {
+			return a
+		}
+	}
+	return nil
+}
+
+// logAssignmentLocked appends e to the assignment history.
+func (r *MemoryOrderRepository) logAssignmentLocked(e AssignmentHistoryEntry) {
+	e.ID = int64(len(r.assignLog) + 1)
+	r.assignLog = append(r.assignLog, e)
+}
+
+// assignmentChangedLocked logs an admin change to an assignment, bumps the
+// order's version and returns the event to publish.
+func (r *MemoryOrderRepository) assignmentChangedLocked(e AssignmentHistoryEntry) OrderEvent {
+	r.logAssignmentLocked(e)
+	o := r.orders[e.OrderID]
+	o.Version++
+	o.UpdatedAt = e.ChangedAt
+	return assignmentEvent(e, o.UserID, o.Status)
+}
+
+func (r *MemoryOrderRepository) inQueueLocked(o *OrderResponse, userID, role string) bool {
+	a := r.assignmentLocked(o.OrderID, role)
+	switch role {
+	case "printing":
+		if _, ok := r.labels[o.OrderID]; !ok || o.PaymentStatus != "payment_verified" {
+			return false
+		}
+		if a != nil && a.UserID != userID {
+			return false
+		}
+		return !(o.Status == "declined" && a == nil)
+	case "plant":
+		switch o.Status {
+		case "ready_for_plant", "plant_processing", "dispatched", "completed":
+			return a == nil || a.UserID == userID
+		}
+	}
+	return false
+}
+
+func (r *MemoryOrderRepository) GetAllOrders(limit, offset int, role, userID string) ([]AllOrderModel, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var matched []*OrderResponse
+	switch role {
+	case "admin":
+		matched = r.sortedLocked(func(*OrderResponse) bool { return true })
+	case "printing", "plant":
+		matched = r.sortedLocked(func(o *OrderResponse) bool { return r.inQueueLocked(o, userID, role) })
+	default:
+		return nil, 0, fmt.Errorf("unauthorized role: %s", role)
+	}
+
+	var orders []AllOrderModel
+	for _, o := range page(matched, limit, offset) {
+		m := AllOrderModel{
+			OrderID:          o.OrderID,
+			UserID:           o.UserID,
+			LabelID:          r.labelIDs[o.OrderID],
+			LabelURL:         o.LabelURL,
+			Variant:          o.Variant,
+			Qty:              o.Qty,
+			CapColor:         o.CapColor,
+			Volume:           o.Volume,
+			SKUID:            o.SKUID,
+			Status:           o.Status,
+			DeclineReason:    o.DeclineReason,
+			ExpectedDelivery: o.ExpectedDelivery,
+			CreatedAt:        o.CreatedAt,
+			UpdatedAt:        o.UpdatedAt,
+		}
+		if role == "admin" {
+			m.PaymentStatus = o.PaymentStatus
+			m.PaymentUrl = o.PaymentUrl
+			m.InvoiceUrl = o.InvoiceUrl
+			m.PiUrl = o.PiUrl
+		} else if a := r.assignmentLocked(o.OrderID, role); a != nil {
+			deadline := a.Deadline
+			m.Deadline = &deadline
+		}
+		orders = append(orders, m)
+	}
+	return orders, len(matched), nil
+}
+
+func (r *MemoryOrderRepository) UpdateOrderPaymentScreenshot(orderID, screenshotURL, userID string) error {
+	if orderID == "" || screenshotURL == "" {
+		return errors.New("orderID and screenshotURL cannot be empty")
+	}
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	previous := o.PaymentStatus
+	o.PaymentUrl = screenshotURL
+	o.PaymentStatus = "payment_uploaded"
+	o.UpdatedAt = utils.NowInIST()
+	o.Version++
+	ev := r.recordLocked(EventPaymentUpdated, o, "payment_uploaded", previous, userID, "")
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) UpdateOrderInvoice(orderID string, urls map[string]string) error {
+	if urls["invoice_url"] == "" && urls["pi_url"] == "" {
+		return errors.New("no URLs to update")
+	}
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	if u, ok := urls["invoice_url"]; ok {
+		o.InvoiceUrl = u
+	}
+	if u, ok := urls["pi_url"]; ok {
+		o.PiUrl = u
+	}
+	o.UpdatedAt = utils.NowInIST()
+	o.Version++
+	ev := OrderEvent{
+		Type:       EventInvoiceUploaded,
+		OrderID:    orderID,
+		OwnerID:    o.UserID,
+		Status:     o.Status,
+		InvoiceURL: urls["invoice_url"],
+		PiURL:      urls["pi_url"],
+		OccurredAt: o.UpdatedAt,
+	}
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) AddOrderComment(orderID, userID, role, comment string) error {
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return fmt.Errorf("order %s not found", orderID)
+	}
+	now := utils.NowInIST()
+	r.comments = append(r.comments, OrderComment{
+		ID:        len(r.comments) + 1,
+		OrderID:   orderID,
+		UserID:    userID,
+		Role:      role,
+		Comment:   comment,
+		CreatedAt: now,
+	})
+	ev := OrderEvent{
+		Type:       EventCommentAdded,
+		OrderID:    orderID,
+		OwnerID:    o.UserID,
+		ChangedBy:  userID,
+		ActorRole:  role,
+		Comment:    comment,
+		OccurredAt: now,
+	}
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetCommentsByOrder(orderID, userID, role string) ([]OrderComment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var comments []OrderComment
+	for _, c := range r.comments {
+		if c.OrderID != orderID {
+			continue
+		}
+		if role != "admin" && c.UserID != userID {
+			continue
+		}
+		comments = append(comments, c)
+	}
+	return comments, nil
+}
+
+func (r *MemoryOrderRepository) AssignOrder(orderID, userID, role string, deadline time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := utils.NowInIST()
+	r.assignments = append(r.assignments, OrderAssignment{
+		OrderID:    orderID,
+		UserID:     userID,
+		Role:       role,
+		AssignedAt: now,
+		Deadline:   deadline,
+	})
+	r.logAssignmentLocked(AssignmentHistoryEntry{
+		OrderID:   orderID,
+		Role:      role,
+		Action:    AssignmentAssigned,
+		ToUserID:  userID,
+		Deadline:  &deadline,
+		ChangedBy: userID,
+		ChangedAt: now,
+	})
+	delete(r.claims, workKey(orderID, role))
+	delete(r.pins, workKey(orderID, role))
+	return nil
+}
+
+func (r *MemoryOrderRepository) ReleaseWorkPool(orderID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.claims, workKey(orderID, role))
+	delete(r.pins, workKey(orderID, role))
+	return nil
+}
+
+func (r *MemoryOrderRepository) CompleteOrderAssignment(orderID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := utils.NowInIST()
+	for i := range r.assignments {
+		a := &r.assignments[i]
+		if a.OrderID != orderID || a.ReleasedAt.Valid || a.CompletedAt.Valid {
+			continue
+		}
+		a.CompletedAt.Time = now
+		a.CompletedAt.Valid = true
+		deadline := a.Deadline
+		r.logAssignmentLocked(AssignmentHistoryEntry{
+			OrderID:    orderID,
+			Role:       a.Role,
+			Action:     AssignmentCompleted,
+			FromUserID: a.UserID,
+			Deadline:   &deadline,
+			ChangedBy:  userID,
+			ChangedAt:  now,
+		})
+	}
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetOrderAssignments(orderID string) ([]OrderAssignment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var assignments []OrderAssignment
+	for _, a := range r.assignments {
+		if a.OrderID == orderID && !a.ReleasedAt.Valid {
+			assignments = append(assignments, a)
+		}
+	}
+	return assignments, nil
+}
+
+func (r *MemoryOrderRepository) GetAssignmentHistory(orderID string) ([]AssignmentHistoryEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var history []AssignmentHistoryEntry
+	for _, e := range r.assignLog {
+		if e.OrderID == orderID {
+			history = append(history, e)
+		}
+	}
+	return history, nil
+}
+
+func (r *MemoryOrderRepository) CurrentAssignment(orderID, role string) (*OrderAssignment, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := r.assignmentLocked(orderID, role)
+	if a == nil {
+		return nil, nil
+	}
+	c := *a
+	return &c, nil
+}
+
+func (r *MemoryOrderRepository) ReassignOrder(orderID, role, userID, changedBy, reason string) error {
+	r.mu.Lock()
+	a := r.assignmentLocked(orderID, role)
+	if a == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("order has no %s assignment", role)
+	}
+	now := utils.NowInIST()
+	a.ReleasedAt.Time, a.ReleasedAt.Valid = now, true
+	previous, deadline := a.UserID, a.Deadline
+	r.assignments = append(r.assignments, OrderAssignment{
+		OrderID:    orderID,
+		UserID:     userID,
+		Role:       role,
+		AssignedAt: now,
+		Deadline:   deadline,
+	})
+	ev := r.assignmentChangedLocked(AssignmentHistoryEntry{
+		OrderID:    orderID,
+		Role:       role,
+		Action:     AssignmentReassigned,
+		FromUserID: previous,
+		ToUserID:   userID,
+		Deadline:   &deadline,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+		ChangedAt:  now,
+	})
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) UnassignOrder(orderID, role, changedBy, reason string) error {
+	r.mu.Lock()
+	a := r.assignmentLocked(orderID, role)
+	if a == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("order has no %s assignment", role)
+	}
+	now := utils.NowInIST()
+	a.ReleasedAt.Time, a.ReleasedAt.Valid = now, true
+	deadline := a.Deadline
+	ev := r.assignmentChangedLocked(AssignmentHistoryEntry{
+		OrderID:    orderID,
+		Role:       role,
+		Action:     AssignmentUnassigned,
+		FromUserID: a.UserID,
+		Deadline:   &deadline,
+		Reason:     reason,
+		ChangedBy:  changedBy,
+		ChangedAt:  now,
+	})
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) ExtendAssignmentDeadline(orderID, role string, deadline time.Time, changedBy, reason string) error {
+	r.mu.Lock()
+	a := r.assignmentLocked(orderID, role)
+	if a == nil {
+		r.mu.Unlock()
+		return fmt.Errorf("order has no %s assignment", role)
+	}
+	a.Deadline = deadline
+	ev := r.assignmentChangedLocked(AssignmentHistoryEntry{
+		OrderID:   orderID,
+		Role:      role,
+		Action:    AssignmentDeadlineExtended,
+		ToUserID:  a.UserID,
+		Deadline:  &deadline,
+		Reason:    reason,
+		ChangedBy: changedBy,
+		ChangedAt: utils.NowInIST(),
+	})
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return nil
+}
+
+func (r *MemoryOrderRepository) IsOrderAssignedToUser(orderID, userID, role string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a := r.assignmentLocked(orderID, role)
+	return a != nil && a.UserID == userID, nil
+}
+
+func (r *MemoryOrderRepository) IsOrderInQueue(orderID, userID, role string) (bool, error) {
+	if role != "printing" && role != "plant" {
+		return false, fmt.Errorf("unsupported queue role: %s", role)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	o, ok := r.orders[orderID]
+	if !ok {
+		return false, nil
+	}
+	return r.inQueueLocked(o, userID, role), nil
+}
+
+func workKey(orderID, role string) string {
+	return orderID + "/" + role
+}
+
+func (r *MemoryOrderRepository) ReservedFor(orderID, role string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if pin, ok := r.pins[workKey(orderID, role)]; ok {
+		return pin.UserID, nil
+	}
+	if claim, ok := r.claims[workKey(orderID, role)]; ok && claim.LeaseExpiresAt.After(utils.NowInIST()) {
+		return claim.UserID, nil
+	}
+	return "", nil
+}
+
+// inWorkPoolLocked mirrors workPoolFilters.
+func (r *MemoryOrderRepository) inWorkPoolLocked(o *OrderResponse, role string) bool {
+	if r.assignmentLocked(o.OrderID, role) != nil {
+		return false
+	}
+	switch role {
+	case "printing":
+		_, ok := r.labels[o.OrderID]
+		return ok && o.Status == "placed" && o.PaymentStatus == "payment_verified"
+	case "plant":
+		return o.Status == "ready_for_plant"
+	}
+	return false
+}
+
+func (r *MemoryOrderRepository) ClaimNextOrder(userID, role string, lease time.Duration) (*WorkClaim, error) {
+	if _, ok := workPoolFilters[role]; !ok {
+		return nil, fmt.Errorf("unsupported queue role: %s", role)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := utils.NowInIST()
+	ownsLease := func(o *OrderResponse) bool {
+		c, ok := r.claims[workKey(o.OrderID, role)]
+		return ok && c.UserID == userID && c.LeaseExpiresAt.After(now)
+	}
+	isPinned := func(o *OrderResponse) bool {
+		_, ok := r.pins[workKey(o.OrderID, role)]
+		return ok
+	}
+
+	var candidates []*OrderResponse
+	for _, o := range r.orders {
+		if !r.inWorkPoolLocked(o, role) {
+			continue
+		}
+		if pin, ok := r.pins[workKey(o.OrderID, role)]; ok && pin.UserID != userID {
+			continue
+		}
+		if c, ok := r.claims[workKey(o.OrderID, role)]; ok && c.UserID != userID && c.LeaseExpiresAt.After(now) {
+			continue
+		}
+		candidates = append(candidates, o)
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if ownsLease(a) != ownsLease(b) {
+			return ownsLease(a)
+		}
+		if isPinned(a) != isPinned(b) {
+			return isPinned(a)
+		}
+		if !a.ExpectedDelivery.Equal(b.ExpectedDelivery) {
+			return a.ExpectedDelivery.Before(b.ExpectedDelivery)
+		}
+		va, vb := r.verifiedAt[a.OrderID], r.verifiedAt[b.OrderID]
+		if !va.Equal(vb) {
+			return !va.IsZero() && (vb.IsZero() || va.Before(vb))
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+
+	o := candidates[0]
+	if ownsLease(o) {
+		c := r.claims[workKey(o.OrderID, role)]
+		return &c, nil
+	}
+	c := WorkClaim{
+		OrderID:        o.OrderID,
+		Role:           role,
+		UserID:         userID,
+		ClaimedAt:      now,
+		LeaseExpiresAt: now.Add(lease),
+		Pinned:         isPinned(o),
+	}
+	r.claims[workKey(o.OrderID, role)] = c
+	return &c, nil
+}
+
+func (r *MemoryOrderRepository) PinOrder(pin OrderPin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.orders[pin.OrderID]; !ok {
+		return fmt.Errorf("order %s not found", pin.OrderID)
+	}
+	key := workKey(pin.OrderID, pin.Role)
+	r.pins[key] = pin
+	if c, ok := r.claims[key]; ok && c.UserID != pin.UserID {
+		delete(r.claims, key)
+	}
+	return nil
+}
+
+func (r *MemoryOrderRepository) UnpinOrder(orderID, role string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := workKey(orderID, role)
+	_, ok := r.pins[key]
+	delete(r.pins, key)
+	return ok, nil
+}
+
+func (r *MemoryOrderRepository) ListInFlightOrders() ([]InFlightOrder, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []InFlightOrder
+	for _, o := range r.sortedLocked(func(o *OrderResponse) bool { return inFlightStatuses[o.Status] }) {
+		f := InFlightOrder{
+			OrderID:          o.OrderID,
+			Status:           o.Status,
+			PaymentStatus:    o.PaymentStatus,
+			ExpectedDelivery: o.ExpectedDelivery,
+			Qty:              o.Qty,
+			Sheets:           r.labels[o.OrderID].NoOfSheets,
+		}
+		for _, a := range r.assignments {
+			if a.OrderID == o.OrderID && !a.ReleasedAt.Valid && !a.CompletedAt.Valid {
+				a := a
+				f.Assignment = &a
+			}
+		}
+		list = append(list, f)
+	}
+	return list, nil
+}
+
+func (r *MemoryOrderRepository) RecordSLAEscalation(item SLAItem, at time.Time) (bool, error) {
+	r.mu.Lock()
+	o, ok := r.orders[item.OrderID]
+	if !ok {
+		r.mu.Unlock()
+		return false, fmt.Errorf("order %s not found", item.OrderID)
+	}
+	key := fmt.Sprintf("%s/%s/%s/%s/%d", item.OrderID, item.Kind, item.Role, item.Level, item.DueAt.UnixNano())
+	if r.escalations[key] {
+		r.mu.Unlock()
+		return false, nil
+	}
+	r.escalations[key] = true
+	ev := slaEvent(item, o.UserID, at)
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return true, nil
+}
+
+func (r *MemoryOrderRepository) RescheduleDelivery(orderID string, expected, at time.Time) (bool, error) {
+	r.mu.Lock()
+	o, ok := r.orders[orderID]
+	if !ok {
+		r.mu.Unlock()
+		return false, fmt.Errorf("order %s not found", orderID)
+	}
+	previous := o.ExpectedDelivery
+	if !expected.After(previous) || !inFlightStatuses[o.Status] {
+		r.mu.Unlock()
+		return false, nil
+	}
+	o.ExpectedDelivery = expected
+	o.Version++
+	o.UpdatedAt = at
+	ev := deliveryRescheduledEvent(orderID, o.UserID, o.Status, previous, expected, at)
+	r.mu.Unlock()
+
+	publishEvent(ev)
+	return true, nil
+}
+
+func (r *MemoryOrderRepository) ListWorkCapacities() ([]WorkCapacity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]WorkCapacity, 0, len(r.capacities))
+	for _, c := range r.capacities {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Role != list[j].Role {
+			return list[i].Role < list[j].Role
+		}
+		return list[i].UserID < list[j].UserID
+	})
+	return list, nil
+}
+
+func (r *MemoryOrderRepository) SaveWorkCapacity(c WorkCapacity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.capacities[c.UserID] = c
+	return nil
+}
+
+func (r *MemoryOrderRepository) SaveOrderLabelDetails(details OrderLabelDetails) error {
+	if details.OrderID == "" {
+		return errors.New("order_id is required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.labels[details.OrderID]; ok {
+		details.ID = existing.ID
+	} else {
+		details.ID = len(r.labels) + 1
+	}
+	r.labels[details.OrderID] = details
+	return nil
+}
+
+func (r *MemoryOrderRepository) GetOrderLabelDetails(orderID string) (*OrderLabelDetails, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	details, ok := r.labels[orderID]
+	if !ok {
+		return nil, nil
+	}
+	return &details, nil
+}
@@ -2,7 +2,10 @@ package orders
 
 import (
 	"context"
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/internal/catalog"
 	"enerzyflow_backend/internal/companies"
+	"enerzyflow_backend/internal/users"
 	"enerzyflow_backend/utils"
 	"errors"
 	"fmt"
@@ -16,12 +19,24 @@ import (
 	"github.com/google/uuid"
 )
 
-func CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse, error) {
+type OrderService struct {
+	repo      OrderRepository
+	companies companies.CompanyRepository
+	users     users.UserRepository
+	calendar  *calendar.CalendarService
+	catalog   *catalog.CatalogService
+}
+
+func NewOrderService(repo OrderRepository, companyRepo companies.CompanyRepository, userRepo users.UserRepository, cal *calendar.CalendarService, skus *catalog.CatalogService) *OrderService {
+	return &OrderService{repo: repo, companies: companyRepo, users: userRepo, calendar: cal, catalog: skus}
+}
+
+func (s *OrderService) CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse, error) {
 	if userID == "" {
 		return nil, errors.New("missing authenticated user id")
 	}
 
-	company, err := companies.GetCompanyByUserID(userID)
+	company, err := s.companies.GetCompanyByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +44,7 @@ func CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse,
 		return nil, errors.New("company not found for user")
 	}
 
-	label, err := companies.GetLabelByIDAndCompanyID(req.LabelID, company.CompanyID)
+	label, err := s.companies.GetLabelByIDAndCompanyID(req.LabelID, company.CompanyID)
 	if err != nil {
 		return nil, errors.New("failed to validate label: " + err.Error())
 	}
@@ -37,21 +52,33 @@ func CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse,
 		return nil, errors.New("label does not belong to your company")
 	}
 
+	sku, err := s.catalog.OrderableSKU(req.SKUID, req.Variant, req.Volume, req.CapColor, req.Qty)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.NowInIST()
+	quote, err := s.estimateDelivery(req.Qty, sheetsFor(req.Qty, 0), now)
+	if err != nil {
+		return nil, err
+	}
+
 	order := &Order{
 		OrderID:          uuid.New().String(),
 		UserID:           userID,
 		LabelID:          req.LabelID,
-		Variant:          req.Variant,
+		Variant:          sku.Variant,
 		Qty:              req.Qty,
-		CapColor:         req.CapColor,
-		Volume:           req.Volume,
+		CapColor:         sku.CapColor,
+		Volume:           sku.VolumeML,
+		SKUID:            sku.SKUID,
 		Status:           "placed",
-		ExpectedDelivery: utils.NowInIST().Add(10 * 24 * time.Hour),
-		CreatedAt:        utils.NowInIST(),
-		UpdatedAt:        utils.NowInIST(),
+		ExpectedDelivery: quote.ExpectedDelivery,
+		CreatedAt:        now,
+		UpdatedAt:        now,
 	}
 
-	if err := CreateOrder(order, userID); err != nil {
+	if err := s.repo.CreateOrder(order, userID); err != nil {
 		return nil, fmt.Errorf("failed to insert order: %w", err)
 	}
 
@@ -59,10 +86,11 @@ func CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse,
 		OrderID:          order.OrderID,
 		UserID:           userID,
 		LabelURL:         label.URL,
-		Variant:          req.Variant,
+		Variant:          order.Variant,
 		Qty:              req.Qty,
-		CapColor:         req.CapColor,
-		Volume:           req.Volume,
+		CapColor:         order.CapColor,
+		Volume:           order.Volume,
+		SKUID:            order.SKUID,
 		Status:           "placed",
 		PaymentStatus:    "payment_pending",
 		ExpectedDelivery: order.ExpectedDelivery,
@@ -71,12 +99,12 @@ func CreateOrderService(userID string, req CreateOrderRequest) (*OrderResponse,
 	}, nil
 }
 
-func GetOrderService(userID, orderID string) (*OrderResponse, error) {
+func (s *OrderService) GetOrderService(userID, orderID string) (*OrderResponse, error) {
 	if userID == "" {
 		return nil, errors.New("missing authenticated user id")
 	}
 
-	order, err := GetOrderByID(orderID)
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -95,6 +123,7 @@ func GetOrderService(userID, orderID string) (*OrderResponse, error) {
 		Qty:              order.Qty,
 		CapColor:         order.CapColor,
 		Volume:           order.Volume,
+		SKUID:            order.SKUID,
 		Status:           order.Status,
 		PaymentStatus:    order.PaymentStatus,
 		DeclineReason:    order.DeclineReason,
@@ -104,15 +133,16 @@ func GetOrderService(userID, orderID string) (*OrderResponse, error) {
 		ExpectedDelivery: order.ExpectedDelivery,
 		CreatedAt:        order.CreatedAt,
 		UpdatedAt:        order.UpdatedAt,
+		Version:          order.Version,
 	}, nil
 }
 
-func GetOrdersService(userID string, limit, offset int) (*OrderListResponse, error) {
+func (s *OrderService) GetOrdersService(userID string, limit, offset int) (*OrderListResponse, error) {
 	if userID == "" {
 		return nil, errors.New("missing authenticated user id")
 	}
 
-	company, err := companies.GetCompanyByUserID(userID)
+	company, err := s.companies.GetCompanyByUserID(userID)
 	if err != nil {
 		return nil, err
 	}
@@ -120,7 +150,7 @@ func GetOrdersService(userID string, limit, offset int) (*OrderListResponse, err
 		return nil, errors.New("company not found for user")
 	}
 
-	orders, total, err := GetOrdersByUserID(userID, limit, offset)
+	orders, total, err := s.repo.GetOrdersByUserID(userID, limit, offset)
 	if err != nil {
 		return nil, err
 	}
@@ -135,6 +165,7 @@ func GetOrdersService(userID string, limit, offset int) (*OrderListResponse, err
 			Qty:              order.Qty,
 			CapColor:         order.CapColor,
 			Volume:           order.Volume,
+			SKUID:            order.SKUID,
 			Status:           order.Status,
 			PaymentStatus:    order.PaymentStatus,
 			DeclineReason:    order.DeclineReason,
@@ -144,6 +175,7 @@ func GetOrdersService(userID string, limit, offset int) (*OrderListResponse, err
 			ExpectedDelivery: order.ExpectedDelivery,
 			CreatedAt:        order.CreatedAt,
 			UpdatedAt:        order.UpdatedAt,
+			Version:          order.Version,
 		}
 	}
 
@@ -153,120 +185,83 @@ func GetOrdersService(userID string, limit, offset int) (*OrderListResponse, err
 	}, nil
 }
 
-func GetAllOrdersService(role string, limit, offset int, userID string) ([]AllOrderModel, int, error) {
-	return GetAllOrders(limit, offset, role, userID)
+func (s *OrderService) GetAllOrdersService(role string, limit, offset int, userID string) ([]AllOrderModel, int, error) {
+	return s.repo.GetAllOrders(limit, offset, role, userID)
 }
 
-func UpdateOrderStatusService(userID, role, orderID string, req UpdateOrderStatusRequest) error {
-	order, err := GetOrderByID(orderID)
-	if err != nil {
-		return err
-	}
-	if order == nil {
-		return errors.New("order not found")
-	}
-
-	switch role {
-	case "admin":
-		validStatuses := map[string]bool{
-			"dispatched": true,
-			"completed":  true,
-			"declined":   true,
+// UpdateOrderStatusService applies the requested transition while holding
+// the order row lock and returns the order's new version. When
+// expectedVersion is non-zero and the order has moved on, it returns
+// ErrOrderConflict.
+func (s *OrderService) UpdateOrderStatusService(userID, role, orderID string, req UpdateOrderStatusRequest) (int, error) {
+	var version int
+	err := s.repo.WithLockedOrder(orderID, func(tx OrderTxRepository, order *OrderResponse) error {
+		if order == nil {
+			return errors.New("order not found")
 		}
-
-		if !validStatuses[req.Status] {
-			return fmt.Errorf("invalid status '%s' for admin", req.Status)
+		if req.Version != 0 && req.Version != order.Version {
+			return &ConflictError{Current: order.Version}
 		}
 
-		switch order.Status {
-		case "declined":
-			return fmt.Errorf("cannot update a declined order")
-
-		case "dispatched":
-			if req.Status != "completed" {
-				return fmt.Errorf("dispatched orders can only be updated to 'completed'")
-			}
-		case "completed":
-			return fmt.Errorf("cannot update a completed order")
-		}
-		
-		if req.Status == "declined" {
-			reason := strings.TrimSpace(req.Reason)
-			reason = strings.Trim(reason, `"`)
-			if reason == "" {
-				return errors.New("reason required when declining an order")
-			}
-			req.Reason = reason
+		t, err := findTransition(role, order.Status, req.Status)
+		if err != nil {
+			return err
 		}
-
-		if req.Status != "declined" && order.PaymentStatus != "payment_verified" {
-			return fmt.Errorf("cannot update order status until payment is verified")
+		if err := s.checkGuards(tx, t, order, userID, role, req.Reason, false); err != nil {
+			return err
 		}
-
-		return UpdateOrderStatus(orderID, req.Status, userID, req.Reason)
-
-	case "printing":
-		if order.PaymentStatus != "payment_verified" {
-			return errors.New("printing can only handle payment-verified orders")
+		if err := s.applyTransition(tx, t, order, userID, req.Reason); err != nil {
+			return err
 		}
+		version = order.Version + 1
+		return nil
+	})
+	return version, err
+}
 
-		switch order.Status {
-		case "placed":
-			switch req.Status {
-			case "accepted":
-				if err := AssignOrder(orderID, userID, "printing", 2); err != nil {
-					return err
-				}
-
-				return UpdateOrderStatus(orderID, "printing", userID, "")
-			case "declined":
-				if req.Reason == "" {
-					return errors.New("reason required when declining order")
-				}
-				return UpdateOrderStatus(orderID, "declined", userID, req.Reason)
-			default:
-				return errors.New("printing can only accept or decline orders")
-			}
-		case "printing":
-			if req.Status == "ready_for_plant" {
-				if err := UpdateOrderStatus(orderID, "ready_for_plant", userID, ""); err != nil {
-					return err
-				}
-
-				if err := CompleteOrderAssignment(orderID, userID); err != nil {
-					return err
-				}
-				return nil
-			}
+// AllowedTransitionsService lists the status changes the caller can make on
+// the order right now, i.e. those whose guards pass apart from the reason.
+func (s *OrderService) AllowedTransitionsService(orderID, userID, role string) (*AllowedTransitionsResponse, error) {
+	order, err := s.repo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	if role == "business_owner" && order.UserID != userID {
+		return nil, errors.New("order not found")
+	}
 
-			return errors.New("invalid status update from printing")
-		default:
-			return errors.New("printing cannot handle this status")
+	resp := &AllowedTransitionsResponse{
+		OrderID:     order.OrderID,
+		Status:      order.Status,
+		Version:     order.Version,
+		Transitions: []AllowedTransition{},
+	}
+	for i := range Transitions {
+		t := &Transitions[i]
+		if t.From != order.Status || !t.allows(role) {
+			continue
 		}
-
-	case "plant":
-		switch order.Status {
-		case "ready_for_plant":
-			if err := AssignOrder(orderID, userID, "plant", 3); err != nil {
-				return err
-			}
-			return UpdateOrderStatus(orderID, "plant_processing", userID, "")
-		case "plant_processing":
-			if err := CompleteOrderAssignment(orderID, userID); err != nil {
-				return err
-			}
-			return UpdateOrderStatus(orderID, "dispatched", userID, "")
-		default:
-			return errors.New("plant can only handle 'ready_for_plant' or 'plant_processing' statuses")
+		if err := s.checkGuards(s.repo, t, order, userID, role, "", true); err != nil {
+			continue
 		}
-
-	default:
-		return errors.New("unauthorized role")
+		action := t.Action
+		if action == "" {
+			action = t.To
+		}
+		resp.Transitions = append(resp.Transitions, AllowedTransition{
+			Action:         action,
+			To:             t.To,
+			ReasonRequired: t.requires(GuardReasonRequired),
+		})
 	}
+	return resp, nil
 }
 
-func UpdatePaymentStatusService(orderID, paymentStatus, reason, adminID string) error {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) UpdatePaymentStatusService(orderID, paymentStatus, reason, adminID string) error {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
 	}
@@ -280,21 +275,21 @@ func UpdatePaymentStatusService(orderID, paymentStatus, reason, adminID string)
 
 	switch paymentStatus {
 	case "payment_verified":
-		return UpdatePaymentStatus(orderID, "payment_verified", adminID, "")
+		return s.repo.UpdatePaymentStatus(orderID, "payment_verified", adminID, "")
 
 	case "payment_rejected":
 		if reason == "" {
 			return errors.New("reason required when rejecting payment")
 		}
-		return UpdatePaymentStatus(orderID, "payment_rejected", adminID, reason)
+		return s.repo.UpdatePaymentStatus(orderID, "payment_rejected", adminID, reason)
 
 	default:
 		return errors.New("invalid payment status")
 	}
 }
 
-func GetOrderTrackingService(orderID, userID, role string) ([]OrderStatusHistory, error) {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) GetOrderTrackingService(orderID, userID, role string) ([]OrderStatusHistory, error) {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -307,11 +302,11 @@ func GetOrderTrackingService(orderID, userID, role string) ([]OrderStatusHistory
 		}
 	}
 
-	return GetOrderStatusHistory(orderID)
+	return s.repo.GetOrderStatusHistory(orderID)
 }
 
-func UploadPaymentScreenshotService(orderID string, fileHeader *multipart.FileHeader, userID string) (string, error) {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) UploadPaymentScreenshotService(orderID string, fileHeader *multipart.FileHeader, userID string) (string, error) {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return "", err
 	}
@@ -349,15 +344,15 @@ func UploadPaymentScreenshotService(orderID string, fileHeader *multipart.FileHe
 		return "", err
 	}
 
-	if err := UpdateOrderPaymentScreenshot(orderID, uploadResult.SecureURL, userID); err != nil {
+	if err := s.repo.UpdateOrderPaymentScreenshot(orderID, uploadResult.SecureURL, userID); err != nil {
 		return "", err
 	}
 
 	return uploadResult.SecureURL, nil
 }
 
-func UploadInvoiceService(orderID string, invoiceFile, piFile *multipart.FileHeader) (map[string]string, error) {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) UploadInvoiceService(orderID string, invoiceFile, piFile *multipart.FileHeader) (map[string]string, error) {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch order: %w", err)
 	}
@@ -382,15 +377,15 @@ func UploadInvoiceService(orderID string, invoiceFile, piFile *multipart.FileHea
 		resutl["pi_url"] = piURL
 	}
 
-	if err := UpdateOrderInvoice(orderID, resutl); err != nil {
+	if err := s.repo.UpdateOrderInvoice(orderID, resutl); err != nil {
 		return nil, fmt.Errorf("failed to update order: %w", err)
 	}
 
 	return resutl, nil
 }
 
-func AddOrderCommentService(orderID, userID, role, comment string) error {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) AddOrderCommentService(orderID, userID, role, comment string) error {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
 	}
@@ -401,7 +396,7 @@ func AddOrderCommentService(orderID, userID, role, comment string) error {
 		return errors.New("comment cannot be empty")
 	}
 
-	assigned, err := IsOrderAssignedToUser(orderID, userID, role)
+	assigned, err := s.repo.IsOrderAssignedToUser(orderID, userID, role)
 	if err != nil {
 		return fmt.Errorf("failed to verify assignment: %v", err)
 	}
@@ -422,23 +417,23 @@ func AddOrderCommentService(orderID, userID, role, comment string) error {
 		return errors.New("unauthorized role")
 	}
 
-	return AddOrderComment(orderID, userID, role, comment)
+	return s.repo.AddOrderComment(orderID, userID, role, comment)
 }
 
-func GetOrderCommentsService(orderID, role, userID string) ([]OrderComment, error) {
+func (s *OrderService) GetOrderCommentsService(orderID, role, userID string) ([]OrderComment, error) {
 	if role == "admin" {
-		return GetCommentsByOrder(orderID, userID, role)
+		return s.repo.GetCommentsByOrder(orderID, userID, role)
 	}
-	if isTrue, err := IsOrderAssignedToUser(orderID, userID, role); err != nil {
+	if isTrue, err := s.repo.IsOrderAssignedToUser(orderID, userID, role); err != nil {
 		return nil, fmt.Errorf("failed to verify assignment: %v", err)
 	} else if !isTrue {
 		return nil, errors.New("you are not assigned to this order")
 	}
-	return GetCommentsByOrder(orderID, userID, role)
+	return s.repo.GetCommentsByOrder(orderID, userID, role)
 }
 
-func SaveOrderLabelDetailsService(orderID string, noOfSheets int, cuttingType string, labelsPerSheet int, description string) error {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) SaveOrderLabelDetailsService(orderID string, noOfSheets int, cuttingType string, labelsPerSheet int, description string) error {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return err
 	}
@@ -454,23 +449,23 @@ func SaveOrderLabelDetailsService(orderID string, noOfSheets int, cuttingType st
 		Description:    description,
 	}
 
-	return SaveOrderLabelDetails(details)
+	return s.repo.SaveOrderLabelDetails(details)
 }
 
-func GetOrderLabelDetailsService(orderID, userID, role string) (*OrderLabelDetails, error) {
+func (s *OrderService) GetOrderLabelDetailsService(orderID, userID, role string) (*OrderLabelDetails, error) {
 	switch role {
 	case "admin":
-		return GetOrderLabelDetails(orderID)
+		return s.repo.GetOrderLabelDetails(orderID)
 
 	case "printing":
-		assigned, err := IsOrderAssignedToUser(orderID, userID, role)
+		assigned, err := s.repo.IsOrderAssignedToUser(orderID, userID, role)
 		if err != nil {
 			return nil, fmt.Errorf("failed to verify assignment: %v", err)
 		}
 		if !assigned {
 			return nil, errors.New("you are not assigned to this order")
 		}
-		return GetOrderLabelDetails(orderID)
+		return s.repo.GetOrderLabelDetails(orderID)
 
 	default:
 		return nil, errors.New("unauthorized role")
@@ -478,8 +473,8 @@ func GetOrderLabelDetailsService(orderID, userID, role string) (*OrderLabelDetai
 
 }
 
-func GetOrderDetailService(orderID, role, userID string) (*OrderDetailResponse, error) {
-	order, err := GetOrderByID(orderID)
+func (s *OrderService) GetOrderDetailService(orderID, role, userID string) (*OrderDetailResponse, error) {
+	order, err := s.repo.GetOrderByID(orderID)
 	if err != nil {
 		return nil, err
 	}
@@ -487,41 +482,496 @@ func GetOrderDetailService(orderID, role, userID string) (*OrderDetailResponse,
 		return nil, errors.New("order not found")
 	}
 
-	assignments, err := GetOrderAssignments(orderID)
+	assignments, err := s.repo.GetOrderAssignments(orderID)
+	if err != nil {
+		return nil, err
+	}
+
+	comments, err := s.repo.GetCommentsByOrder(orderID, userID, role)
 	if err != nil {
 		return nil, err
 	}
 
-	comments, err := GetCommentsByOrder(orderID, userID, role)
+	labelDetails, err := s.repo.GetOrderLabelDetails(orderID)
 	if err != nil {
 		return nil, err
 	}
 
-	labelDetails, err := GetOrderLabelDetails(orderID)
+	assignmentHistory, err := s.repo.GetAssignmentHistory(orderID)
 	if err != nil {
 		return nil, err
 	}
 
 	response := &OrderDetailResponse{
-		OrderID:          order.OrderID,
-		UserID:           order.UserID,
-		LabelURL:         order.LabelURL,
-		Variant:          order.Variant,
-		Qty:              order.Qty,
-		CapColor:         order.CapColor,
-		Volume:           order.Volume,
-		Status:           order.Status,
-		PaymentStatus:    order.PaymentStatus,
-		PaymentUrl:       order.PaymentUrl,
-		InvoiceUrl:       order.InvoiceUrl,
-		PiUrl:            order.PiUrl,
-		DeclineReason:    order.DeclineReason,
-		CreatedAt:        order.CreatedAt,
-		UpdatedAt:        order.UpdatedAt,
-		ExpectedDelivery: order.ExpectedDelivery,
-		LabelDetails:     labelDetails,
-		Assignments:      assignments,
-		Comments:         comments,
+		OrderID:           order.OrderID,
+		UserID:            order.UserID,
+		LabelURL:          order.LabelURL,
+		Variant:           order.Variant,
+		Qty:               order.Qty,
+		CapColor:          order.CapColor,
+		Volume:            order.Volume,
+		SKUID:             order.SKUID,
+		Status:            order.Status,
+		PaymentStatus:     order.PaymentStatus,
+		PaymentUrl:        order.PaymentUrl,
+		InvoiceUrl:        order.InvoiceUrl,
+		PiUrl:             order.PiUrl,
+		DeclineReason:     order.DeclineReason,
+		CreatedAt:         order.CreatedAt,
+		UpdatedAt:         order.UpdatedAt,
+		ExpectedDelivery:  order.ExpectedDelivery,
+		LabelDetails:      labelDetails,
+		Assignments:       assignments,
+		Comments:          comments,
+		AssignmentHistory: assignmentHistory,
+		Version:           order.Version,
 	}
 	return response, nil
 }
+
+// eventScopeTTL bounds how long a stream trusts a cached scope check.
+// Claims and lease expiry move orders between queues without an event.
+const eventScopeTTL = 30 * time.Second
+
+// EventScope applies the same role scoping as the order list endpoints to
+// the event stream of one subscriber: owners see their own orders, printing
+// and plant see their queues (and comments on orders assigned to them),
+// admins see all. SLA escalations go to admins only.
+//
+// Scope checks are cached per order and redone after an event that can
+// change them, so a busy order costs one query per connection, not one
+// per event.
+type EventScope struct {
+	svc    *OrderService
+	userID string
+	role   string
+	cache  map[string]scopeCheck
+}
+
+type scopeCheck struct {
+	visible bool
+	at      time.Time
+}
+
+func (s *OrderService) NewEventScope(userID, role string) *EventScope {
+	return &EventScope{svc: s, userID: userID, role: role, cache: map[string]scopeCheck{}}
+}
+
+// Filter reports whether the subscriber may see ev and returns it as they
+// may see it: printing and plant users only get the text of their own
+// comments, as on GET /orders/:id.
+func (sc *EventScope) Filter(ev OrderEvent) (OrderEvent, bool, error) {
+	visible, err := sc.canSee(ev)
+	if err != nil || !visible {
+		return ev, false, err
+	}
+	if ev.Type == EventCommentAdded && isWorkRole(sc.role) && ev.ChangedBy != sc.userID {
+		ev.Comment = ""
+	}
+	return ev, true, nil
+}
+
+func (sc *EventScope) canSee(ev OrderEvent) (bool, error) {
+	if ev.Type == EventSLAEscalated {
+		return sc.role == "admin", nil
+	}
+	switch sc.role {
+	case "admin":
+		return true, nil
+	case "business_owner":
+		return ev.OwnerID == sc.userID && ev.Type != EventCommentAdded && ev.Type != EventAssignmentChanged, nil
+	case "printing", "plant":
+		switch ev.Type {
+		case EventOrderCreated, EventStatusChanged, EventPaymentUpdated, EventAssignmentChanged:
+			delete(sc.cache, "assigned:"+ev.OrderID)
+			delete(sc.cache, "queue:"+ev.OrderID)
+		}
+		if ev.Type == EventAssignmentChanged && (ev.AssigneeID == sc.userID || ev.PreviousAssigneeID == sc.userID) {
+			return true, nil
+		}
+		if ev.Type == EventCommentAdded {
+			return sc.cached("assigned:"+ev.OrderID, func() (bool, error) {
+				return sc.svc.repo.IsOrderAssignedToUser(ev.OrderID, sc.userID, sc.role)
+			})
+		}
+		return sc.cached("queue:"+ev.OrderID, func() (bool, error) {
+			return sc.svc.repo.IsOrderInQueue(ev.OrderID, sc.userID, sc.role)
+		})
+	default:
+		return false, nil
+	}
+}
+
+func (sc *EventScope) cached(key string, check func() (bool, error)) (bool, error) {
+	now := time.Now()
+	if c, ok := sc.cache[key]; ok && now.Sub(c.at) < eventScopeTTL {
+		return c.visible, nil
+	}
+	visible, err := check()
+	if err != nil {
+		return false, err
+	}
+	sc.cache[key] = scopeCheck{visible: visible, at: now}
+	return visible, nil
+}
+
+// OrderStatuses lists every status an order can be in.
+var OrderStatuses = []string{"placed", "printing", "ready_for_plant", "plant_processing", "dispatched", "completed", "declined"}
+
+// workStages maps the work roles to the status an order is in while one
+// of their users holds it.
+var workStages = map[string]string{"printing": "printing", "plant": "plant_processing"}
+
+// poolRoles maps the statuses an order waits in to the work pool that
+// claims from them.
+var poolRoles = map[string]string{"placed": "printing", "ready_for_plant": "plant"}
+
+func statusRank(status string) int {
+	for i, st := range OrderStatuses {
+		if st == status {
+			return i
+		}
+	}
+	return -1
+}
+
+// ForceOrderStatusService sets an order's status without the role checks of
+// UpdateOrderStatusService. It is meant for operators fixing stuck orders;
+// the reason is recorded in the status history as an audit trail.
+//
+// The change runs under the order row lock and keeps assignments
+// consistent with the new status: unfinished assignments for stages the
+// order has moved past are completed, those for stages it was pulled back
+// from or declined out of are released, and leases and pins in the work
+// pool it leaves are dropped. Forcing an order into printing or
+// plant_processing assigns it to assigneeID (a user of that role) unless
+// the stage already has an unfinished assignment, and reassigns that one
+// when assigneeID names someone else.
+func (s *OrderService) ForceOrderStatusService(orderID, status, changedBy, reason, assigneeID string) error {
+	if statusRank(status) < 0 {
+		return fmt.Errorf("unknown status '%s'", status)
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return errors.New("reason is required when forcing a status")
+	}
+	reason = "[forced] " + reason
+
+	stageRole := ""
+	for role, stage := range workStages {
+		if stage == status {
+			stageRole = role
+		}
+	}
+	if assigneeID != "" {
+		if stageRole == "" {
+			return fmt.Errorf("an assignee only applies when forcing an order into %s or %s", workStages["printing"], workStages["plant"])
+		}
+		user, err := s.users.GetUserByID(assigneeID)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return errors.New("user not found")
+		}
+		if user.Role != stageRole {
+			return fmt.Errorf("user is not a %s user", stageRole)
+		}
+	}
+
+	return s.repo.WithLockedOrder(orderID, func(tx OrderTxRepository, order *OrderResponse) error {
+		if order == nil {
+			return errors.New("order not found")
+		}
+		if order.Status == status {
+			return fmt.Errorf("order is already '%s'", status)
+		}
+
+		complete, staffed := false, false
+		for _, role := range []string{"printing", "plant"} {
+			a, err := tx.CurrentAssignment(orderID, role)
+			if err != nil {
+				return err
+			}
+			if a == nil || a.CompletedAt.Valid {
+				continue
+			}
+			switch {
+			case role == stageRole:
+				if assigneeID != "" && assigneeID != a.UserID {
+					if err := tx.ReassignOrder(orderID, role, assigneeID, changedBy, reason); err != nil {
+						return err
+					}
+				}
+				staffed = true
+			case status != "declined" && statusRank(status) > statusRank(workStages[role]):
+				complete = true
+			default:
+				if err := tx.UnassignOrder(orderID, role, changedBy, reason); err != nil {
+					return err
+				}
+			}
+		}
+		if complete {
+			if err := tx.CompleteOrderAssignment(orderID, changedBy); err != nil {
+				return err
+			}
+		}
+
+		if role, ok := poolRoles[order.Status]; ok {
+			if err := tx.ReleaseWorkPool(orderID, role); err != nil {
+				return err
+			}
+		}
+		if stageRole != "" && !staffed {
+			if assigneeID == "" {
+				return fmt.Errorf("forcing an order into '%s' needs a %s user to assign it to", status, stageRole)
+			}
+			if err := s.assign(tx, orderID, assigneeID, assignEffect(status)); err != nil {
+				return err
+			}
+		}
+
+		return tx.UpdateOrderStatus(orderID, status, changedBy, reason)
+	})
+}
+
+const defaultWorkLeaseTTL = 30 * time.Minute
+
+// ErrNoWork is returned by ClaimWorkService when the caller's work pool is
+// empty.
+var ErrNoWork = errors.New("no work available")
+
+func workLeaseTTL() time.Duration {
+	if v := os.Getenv("WORK_LEASE_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultWorkLeaseTTL
+}
+
+func isWorkRole(role string) bool {
+	return role == "printing" || role == "plant"
+}
+
+// ClaimWorkService leases the next order in the caller's work pool to them.
+// Orders pinned to the caller come first, then those with the earliest
+// expected delivery and, among equals, the longest-verified payment. A
+// caller who already holds an unexpired lease gets that order back.
+func (s *OrderService) ClaimWorkService(userID, role string) (*WorkClaim, *OrderResponse, error) {
+	if !isWorkRole(role) {
+		return nil, nil, errors.New("only printing and plant users can claim work")
+	}
+
+	claim, err := s.repo.ClaimNextOrder(userID, role, workLeaseTTL())
+	if err != nil {
+		return nil, nil, err
+	}
+	if claim == nil {
+		return nil, nil, ErrNoWork
+	}
+
+	order, err := s.repo.GetOrderByID(claim.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return claim, order, nil
+}
+
+// PinOrderService reserves an order for one printing or plant user, in the
+// work pool of that user's role. Nobody else can claim or accept it until
+// it is unpinned.
+func (s *OrderService) PinOrderService(orderID, userID, adminID string) (*OrderPin, error) {
+	order, err := s.repo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	switch order.Status {
+	case "dispatched", "completed", "declined":
+		return nil, fmt.Errorf("cannot pin an order that is already '%s'", order.Status)
+	}
+
+	user, err := s.users.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if !isWorkRole(user.Role) {
+		return nil, errors.New("orders can only be pinned to printing or plant users")
+	}
+
+	assignments, err := s.repo.GetOrderAssignments(orderID)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assignments {
+		if a.Role == user.Role {
+			return nil, fmt.Errorf("order is already assigned for %s", a.Role)
+		}
+	}
+
+	pin := OrderPin{
+		OrderID:  orderID,
+		Role:     user.Role,
+		UserID:   userID,
+		PinnedBy: adminID,
+		PinnedAt: utils.NowInIST(),
+	}
+	if err := s.repo.PinOrder(pin); err != nil {
+		return nil, err
+	}
+	return &pin, nil
+}
+
+func (s *OrderService) UnpinOrderService(orderID, role string) error {
+	if !isWorkRole(role) {
+		return errors.New("role must be printing or plant")
+	}
+	removed, err := s.repo.UnpinOrder(orderID, role)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("order is not pinned for %s", role)
+	}
+	return nil
+}
+
+// unassignStatuses is the status an order goes back to when its assignment
+// for a role is released, so that the role's work pool picks it up again.
+var unassignStatuses = map[string]struct{ from, to string }{
+	"printing": {from: "printing", to: "placed"},
+	"plant":    {from: "plant_processing", to: "ready_for_plant"},
+}
+
+// openAssignment returns the order's unfinished assignment for role.
+func openAssignment(tx OrderTxRepository, order *OrderResponse, role string) (*OrderAssignment, error) {
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	a, err := tx.CurrentAssignment(order.OrderID, role)
+	if err != nil {
+		return nil, err
+	}
+	if a == nil {
+		return nil, fmt.Errorf("order has no %s assignment", role)
+	}
+	if a.CompletedAt.Valid {
+		return nil, fmt.Errorf("the %s assignment is already completed", role)
+	}
+	return a, nil
+}
+
+// ReassignOrderService hands an unfinished printing or plant assignment to
+// another user of that role. The deadline stays the same.
+func (s *OrderService) ReassignOrderService(orderID, role string, req ReassignOrderRequest, adminID string) (*OrderAssignment, error) {
+	if !isWorkRole(role) {
+		return nil, errors.New("role must be printing or plant")
+	}
+	reason := normalizeReason(req.Reason)
+	if reason == "" {
+		return nil, errors.New("reason required when reassigning an order")
+	}
+
+	user, err := s.users.GetUserByID(req.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Role != role {
+		return nil, fmt.Errorf("user is not a %s user", role)
+	}
+
+	var assignment *OrderAssignment
+	err = s.repo.WithLockedOrder(orderID, func(tx OrderTxRepository, order *OrderResponse) error {
+		a, err := openAssignment(tx, order, role)
+		if err != nil {
+			return err
+		}
+		if a.UserID == req.UserID {
+			return errors.New("order is already assigned to this user")
+		}
+		if err := tx.ReassignOrder(orderID, role, req.UserID, adminID, reason); err != nil {
+			return err
+		}
+		assignment, err = tx.CurrentAssignment(orderID, role)
+		return err
+	})
+	return assignment, err
+}
+
+// UnassignOrderService releases an unfinished printing or plant assignment
+// and moves the order back to the status its work pool claims from.
+func (s *OrderService) UnassignOrderService(orderID, role, reason, adminID string) error {
+	if !isWorkRole(role) {
+		return errors.New("role must be printing or plant")
+	}
+	reason = normalizeReason(reason)
+	if reason == "" {
+		return errors.New("reason required when unassigning an order")
+	}
+
+	return s.repo.WithLockedOrder(orderID, func(tx OrderTxRepository, order *OrderResponse) error {
+		if _, err := openAssignment(tx, order, role); err != nil {
+			return err
+		}
+		back := unassignStatuses[role]
+		if order.Status != back.from {
+			return fmt.Errorf("cannot unassign %s from an order in '%s' status", role, order.Status)
+		}
+		if err := tx.UnassignOrder(orderID, role, adminID, reason); err != nil {
+			return err
+		}
+		return tx.UpdateOrderStatus(orderID, back.to, adminID, reason)
+	})
+}
+
+// ExtendDeadlineService pushes the deadline of an unfinished printing or
+// plant assignment back by req.Days.
+func (s *OrderService) ExtendDeadlineService(orderID, role string, req ExtendDeadlineRequest, adminID string) (*OrderAssignment, error) {
+	if !isWorkRole(role) {
+		return nil, errors.New("role must be printing or plant")
+	}
+	if req.Days <= 0 {
+		return nil, errors.New("days must be at least 1")
+	}
+
+	var assignment *OrderAssignment
+	err := s.repo.WithLockedOrder(orderID, func(tx OrderTxRepository, order *OrderResponse) error {
+		a, err := openAssignment(tx, order, role)
+		if err != nil {
+			return err
+		}
+		sched, err := s.calendar.Schedule(a.UserID)
+		if err != nil {
+			return err
+		}
+		deadline := sched.AddBusinessDays(a.Deadline, req.Days)
+		if err := tx.ExtendAssignmentDeadline(orderID, role, deadline, adminID, normalizeReason(req.Reason)); err != nil {
+			return err
+		}
+		a.Deadline = deadline
+		assignment = a
+		return nil
+	})
+	return assignment, err
+}
+
+func (s *OrderService) GetAssignmentHistoryService(orderID string) ([]AssignmentHistoryEntry, error) {
+	order, err := s.repo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, errors.New("order not found")
+	}
+	return s.repo.GetAssignmentHistory(orderID)
+}
@@ -0,0 +1,381 @@
+package orders_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/container"
+	"enerzyflow_backend/internal/orders"
+	"enerzyflow_backend/internal/users"
+)
+
+const (
+	adminID     = "admin"
+	ownerID     = "owner"
+	printerID   = "printer"
+	printer2ID  = "printer-2"
+	plantUserID = "plant"
+)
+
+func newTestApp(t *testing.T) *container.Container {
+	t.Helper()
+	app := container.NewMemory()
+	for id, role := range map[string]string{
+		adminID:     "admin",
+		ownerID:     "business_owner",
+		printerID:   "printing",
+		printer2ID:  "printing",
+		plantUserID: "plant",
+	} {
+		if err := app.UserRepo.InsertUser(&users.User{UserID: id, Email: id + "@example.com", Role: role}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return app
+}
+
+// placeOrder creates an order with label details, verifying its payment
+// when verified is set so that printing can pick it up. Orders due sooner
+// are claimed first.
+func placeOrder(t *testing.T, app *container.Container, orderID string, verified bool, dueIn time.Duration) {
+	t.Helper()
+	repo := app.OrderRepo
+	now := time.Now()
+	order := &orders.Order{
+		OrderID:          orderID,
+		LabelID:          "label",
+		Variant:          "classic",
+		Qty:              300,
+		CapColor:         "blue",
+		Volume:           500,
+		CreatedAt:        now,
+		ExpectedDelivery: now.Add(dueIn),
+	}
+	if err := repo.CreateOrder(order, ownerID); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveOrderLabelDetails(orders.OrderLabelDetails{OrderID: orderID, NoOfSheets: 30, CuttingType: "die", LabelsPerSheet: 12}); err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		if err := repo.UpdatePaymentStatus(orderID, "payment_verified", adminID, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func orderStatus(t *testing.T, app *container.Container, orderID string) string {
+	t.Helper()
+	o, err := app.OrderRepo.GetOrderByID(orderID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return o.Status
+}
+
+func assignee(t *testing.T, app *container.Container, orderID, role string) string {
+	t.Helper()
+	a, err := app.OrderRepo.CurrentAssignment(orderID, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == nil {
+		return ""
+	}
+	return a.UserID
+}
+
+func TestUpdateOrderStatusService(t *testing.T) {
+	tests := []struct {
+		name     string
+		verified bool
+		userID   string
+		role     string
+		req      orders.UpdateOrderStatusRequest
+		wantErr  string
+		want     string
+	}{
+		{"printing accepts a verified order", true, printerID, "printing", orders.UpdateOrderStatusRequest{Status: "accepted"}, "", "printing"},
+		{"printing cannot accept before payment", false, printerID, "printing", orders.UpdateOrderStatusRequest{Status: "accepted"}, "payment is verified", "placed"},
+		{"decline needs a reason", true, printerID, "printing", orders.UpdateOrderStatusRequest{Status: "declined"}, "reason required", "placed"},
+		{"decline with a reason", true, printerID, "printing", orders.UpdateOrderStatusRequest{Status: "declined", Reason: "artwork is blurry"}, "", "declined"},
+		{"plant cannot take a placed order", true, plantUserID, "plant", orders.UpdateOrderStatusRequest{Status: "accepted"}, "plant cannot move", "placed"},
+		{"owners cannot change status", true, ownerID, "business_owner", orders.UpdateOrderStatusRequest{Status: "dispatched"}, "unauthorized role", "placed"},
+		{"admin dispatches directly", true, adminID, "admin", orders.UpdateOrderStatusRequest{Status: "dispatched"}, "", "dispatched"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t)
+			placeOrder(t, app, "o1", tt.verified, 48*time.Hour)
+
+			_, err := app.Orders.UpdateOrderStatusService(tt.userID, tt.role, "o1", tt.req)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+			if got := orderStatus(t, app, "o1"); got != tt.want {
+				t.Errorf("status = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatusServiceWorkflow(t *testing.T) {
+	app := newTestApp(t)
+	placeOrder(t, app, "o1", true, 48*time.Hour)
+	svc := app.Orders
+
+	version, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "accepted", Version: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 3 {
+		t.Errorf("version = %d, want 3", version)
+	}
+	if got := assignee(t, app, "o1", "printing"); got != printerID {
+		t.Errorf("printing assignee = %q, want %q", got, printerID)
+	}
+
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "ready_for_plant", Version: 2}); !errors.Is(err, orders.ErrOrderConflict) {
+		t.Fatalf("stale version: error = %v, want ErrOrderConflict", err)
+	}
+	if _, err := svc.UpdateOrderStatusService(printer2ID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "ready_for_plant"}); err == nil {
+		t.Fatal("a printer who is not assigned finished the order")
+	}
+
+	steps := []struct {
+		userID, role, status, want string
+	}{
+		{printerID, "printing", "ready_for_plant", "ready_for_plant"},
+		{plantUserID, "plant", "accepted", "plant_processing"},
+		{plantUserID, "plant", "dispatched", "dispatched"},
+		{adminID, "admin", "completed", "completed"},
+	}
+	for _, st := range steps {
+		if _, err := svc.UpdateOrderStatusService(st.userID, st.role, "o1", orders.UpdateOrderStatusRequest{Status: st.status}); err != nil {
+			t.Fatalf("%s -> %s: %v", st.role, st.status, err)
+		}
+		if got := orderStatus(t, app, "o1"); got != st.want {
+			t.Fatalf("status = %s, want %s", got, st.want)
+		}
+	}
+	if got := assignee(t, app, "o1", "plant"); got != plantUserID {
+		t.Errorf("plant assignee = %q, want %q", got, plantUserID)
+	}
+}
+
+func TestUpdateOrderStatusServiceLegacyPlant(t *testing.T) {
+	app := newTestApp(t)
+	placeOrder(t, app, "o1", true, 48*time.Hour)
+	placeOrder(t, app, "unassigned", true, 48*time.Hour)
+	svc := app.Orders
+
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "ready_for_plant"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, st := range []struct{ sent, want string }{{"plant_processing", "plant_processing"}, {"done", "dispatched"}} {
+		if _, err := svc.UpdateOrderStatusService(plantUserID, "plant", "o1", orders.UpdateOrderStatusRequest{Status: st.sent}); err != nil {
+			t.Fatalf("plant sent %q: %v", st.sent, err)
+		}
+		if got := orderStatus(t, app, "o1"); got != st.want {
+			t.Fatalf("status = %s, want %s", got, st.want)
+		}
+	}
+
+	// An order that reached plant_processing without an assignment row.
+	if err := app.OrderRepo.UpdateOrderStatus("unassigned", "plant_processing", adminID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.UpdateOrderStatusService(plantUserID, "plant", "unassigned", orders.UpdateOrderStatusRequest{Status: "dispatched"}); err != nil {
+		t.Fatalf("unassigned order: %v", err)
+	}
+	if got := orderStatus(t, app, "unassigned"); got != "dispatched" {
+		t.Errorf("status = %s, want dispatched", got)
+	}
+}
+
+func TestClaimWorkService(t *testing.T) {
+	app := newTestApp(t)
+	placeOrder(t, app, "later", true, 72*time.Hour)
+	placeOrder(t, app, "sooner", true, 24*time.Hour)
+	placeOrder(t, app, "unpaid", false, time.Hour)
+	svc := app.Orders
+
+	if _, _, err := svc.ClaimWorkService(ownerID, "business_owner"); err == nil {
+		t.Fatal("an owner claimed work")
+	}
+
+	claim, order, err := svc.ClaimWorkService(printerID, "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.OrderID != "sooner" || order.OrderID != "sooner" {
+		t.Fatalf("first claim got %s, want the order due sooner", claim.OrderID)
+	}
+	if !claim.LeaseExpiresAt.After(claim.ClaimedAt) {
+		t.Errorf("lease expires at %v, before it was claimed at %v", claim.LeaseExpiresAt, claim.ClaimedAt)
+	}
+
+	again, _, err := svc.ClaimWorkService(printerID, "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.OrderID != "sooner" {
+		t.Errorf("reclaim got %s, want the lease already held", again.OrderID)
+	}
+
+	other, _, err := svc.ClaimWorkService(printer2ID, "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.OrderID != "later" {
+		t.Errorf("second printer got %s, want later", other.OrderID)
+	}
+	if _, err := svc.UpdateOrderStatusService(printer2ID, "printing", "sooner", orders.UpdateOrderStatusRequest{Status: "accepted"}); err == nil {
+		t.Error("a printer accepted an order claimed by someone else")
+	}
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "sooner", orders.UpdateOrderStatusRequest{Status: "accepted"}); err != nil {
+		t.Errorf("claim holder could not accept: %v", err)
+	}
+
+	if _, _, err := svc.ClaimWorkService(plantUserID, "plant"); !errors.Is(err, orders.ErrNoWork) {
+		t.Errorf("plant claim: error = %v, want ErrNoWork", err)
+	}
+}
+
+func TestReassignOrderService(t *testing.T) {
+	app := newTestApp(t)
+	placeOrder(t, app, "o1", true, 48*time.Hour)
+	placeOrder(t, app, "idle", true, 48*time.Hour)
+	svc := app.Orders
+
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+	before, err := app.OrderRepo.CurrentAssignment("o1", "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failures := []struct {
+		name    string
+		orderID string
+		role    string
+		req     orders.ReassignOrderRequest
+		wantErr string
+	}{
+		{"reason is required", "o1", "printing", orders.ReassignOrderRequest{UserID: printer2ID}, "reason required"},
+		{"user must hold the role", "o1", "printing", orders.ReassignOrderRequest{UserID: plantUserID, Reason: "cover"}, "not a printing user"},
+		{"unknown user", "o1", "printing", orders.ReassignOrderRequest{UserID: "nobody", Reason: "cover"}, "user not found"},
+		{"same user", "o1", "printing", orders.ReassignOrderRequest{UserID: printerID, Reason: "cover"}, "already assigned to this user"},
+		{"nothing assigned", "idle", "printing", orders.ReassignOrderRequest{UserID: printer2ID, Reason: "cover"}, "no printing assignment"},
+		{"role must be a work role", "o1", "admin", orders.ReassignOrderRequest{UserID: printer2ID, Reason: "cover"}, "printing or plant"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ReassignOrderService(tt.orderID, tt.role, tt.req, adminID)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	a, err := svc.ReassignOrderService("o1", "printing", orders.ReassignOrderRequest{UserID: printer2ID, Reason: "  \"on leave\" "}, adminID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.UserID != printer2ID {
+		t.Errorf("assignee = %q, want %q", a.UserID, printer2ID)
+	}
+	if !a.Deadline.Equal(before.Deadline) {
+		t.Errorf("deadline = %v, want it kept at %v", a.Deadline, before.Deadline)
+	}
+
+	history, err := svc.GetAssignmentHistoryService("o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := history[len(history)-1]
+	if last.Action != orders.AssignmentReassigned || last.FromUserID != printerID || last.ToUserID != printer2ID || last.Reason != "on leave" {
+		t.Errorf("last history entry = %+v", last)
+	}
+
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "ready_for_plant"}); err == nil {
+		t.Error("the previous assignee could still finish the order")
+	}
+	if _, err := svc.UpdateOrderStatusService(printer2ID, "printing", "o1", orders.UpdateOrderStatusRequest{Status: "ready_for_plant"}); err != nil {
+		t.Errorf("the new assignee could not finish the order: %v", err)
+	}
+}
+
+func TestForceOrderStatusService(t *testing.T) {
+	app := newTestApp(t)
+	placeOrder(t, app, "o1", true, 48*time.Hour)
+	svc := app.Orders
+
+	if _, _, err := svc.ClaimWorkService(printerID, "printing"); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ForceOrderStatusService("o1", "printing", adminID, "stuck", ""); err == nil || !strings.Contains(err.Error(), "needs a printing user") {
+		t.Fatalf("forcing into printing without an assignee: error = %v", err)
+	}
+	if err := svc.ForceOrderStatusService("o1", "printing", adminID, "stuck", plantUserID); err == nil || !strings.Contains(err.Error(), "not a printing user") {
+		t.Fatalf("forcing into printing with a plant user: error = %v", err)
+	}
+	if err := svc.ForceOrderStatusService("o1", "printing", adminID, "stuck", printer2ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := assignee(t, app, "o1", "printing"); got != printer2ID {
+		t.Errorf("printing assignee = %q, want %q", got, printer2ID)
+	}
+	if holder, err := app.OrderRepo.ReservedFor("o1", "printing"); err != nil || holder != "" {
+		t.Errorf("printing reservation = %q, %v; want the claim dropped", holder, err)
+	}
+
+	before, err := app.OrderRepo.GetOrderByID("o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ForceOrderStatusService("o1", "dispatched", adminID, "plant outage", ""); err != nil {
+		t.Fatal(err)
+	}
+	after, err := app.OrderRepo.GetOrderByID("o1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Status != "dispatched" || after.Version <= before.Version {
+		t.Errorf("status %s version %d, want dispatched with a version above %d", after.Status, after.Version, before.Version)
+	}
+	a, err := app.OrderRepo.CurrentAssignment("o1", "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == nil || !a.CompletedAt.Valid {
+		t.Errorf("printing assignment = %+v, want it completed", a)
+	}
+
+	placeOrder(t, app, "o2", true, 48*time.Hour)
+	if _, err := svc.UpdateOrderStatusService(printerID, "printing", "o2", orders.UpdateOrderStatusRequest{Status: "accepted"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.ForceOrderStatusService("o2", "placed", adminID, "wrong artwork", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := assignee(t, app, "o2", "printing"); got != "" {
+		t.Errorf("printing assignee = %q, want the assignment released", got)
+	}
+	claim, _, err := svc.ClaimWorkService(printer2ID, "printing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claim.OrderID != "o2" {
+		t.Errorf("claim got %s, want o2 back in the pool", claim.OrderID)
+	}
+}
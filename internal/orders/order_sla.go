@@ -0,0 +1,274 @@
+package orders
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/utils"
+)
+
+// defaultSLAWarningWindow is how long before its deadline an assignment is
+// reported as at risk. SLA_WARNING_WINDOW overrides it.
+const defaultSLAWarningWindow = 12 * time.Hour
+
+// Aging buckets of the SLA report. At-risk work is due soon; breaches are
+// bucketed by how long ago they were due.
+const (
+	SLABucketDueSoon = "due_soon"
+	SLABucketUnder1d = "overdue_0_1d"
+	SLABucket1To3d   = "overdue_1_3d"
+	SLABucket3To7d   = "overdue_3_7d"
+	SLABucketOver7d  = "overdue_7d_plus"
+)
+
+var slaBuckets = []string{SLABucketDueSoon, SLABucketUnder1d, SLABucket1To3d, SLABucket3To7d, SLABucketOver7d}
+
+func slaWarningWindow() time.Duration {
+	if v := os.Getenv("SLA_WARNING_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultSLAWarningWindow
+}
+
+func slaBucket(overdue time.Duration) string {
+	switch {
+	case overdue <= 0:
+		return SLABucketDueSoon
+	case overdue < 24*time.Hour:
+		return SLABucketUnder1d
+	case overdue < 3*24*time.Hour:
+		return SLABucket1To3d
+	case overdue < 7*24*time.Hour:
+		return SLABucket3To7d
+	default:
+		return SLABucketOver7d
+	}
+}
+
+// stageDays is the deadline, in business days, the workflow gives a
+// role's assignment.
+func stageDays(role string) int {
+	for _, t := range Transitions {
+		for _, e := range t.Effects {
+			if e.Kind == EffectAssign && e.Role == role {
+				return e.DeadlineDays
+			}
+		}
+	}
+	return 0
+}
+
+// daysAfter is the business days the stages after status still need.
+func daysAfter(status string) int {
+	switch status {
+	case "placed":
+		return stageDays("printing") + stageDays("plant")
+	case "printing", "ready_for_plant":
+		return stageDays("plant")
+	default:
+		return 0
+	}
+}
+
+// estimateCompletion is when o will be ready to dispatch if each remaining
+// stage takes its full deadline. The open assignment counts until its own
+// deadline, or until now once that has passed.
+func estimateCompletion(o InFlightOrder, now time.Time, sched *calendar.Schedule) time.Time {
+	start := now
+	if o.Assignment != nil && o.Assignment.Deadline.After(now) {
+		start = o.Assignment.Deadline
+	}
+	if days := daysAfter(o.Status); days > 0 {
+		return sched.AddBusinessDays(start, days)
+	}
+	return start
+}
+
+func newSLAItem(o InFlightOrder, kind string, due, now time.Time, atRisk bool) (SLAItem, bool) {
+	item := SLAItem{OrderID: o.OrderID, Kind: kind, Status: o.Status, DueAt: due}
+	switch {
+	case now.After(due):
+		item.Level = SLALevelBreached
+		item.OverdueHours = int(now.Sub(due).Hours())
+	case atRisk:
+		item.Level = SLALevelAtRisk
+	default:
+		return item, false
+	}
+	item.Bucket = slaBucket(now.Sub(due))
+	return item, true
+}
+
+// evaluateSLA lists the at-risk and breached work among orders, most
+// overdue first. An unverified placed order is waiting on its owner, so it
+// does not count against the expected delivery date.
+func evaluateSLA(orders []InFlightOrder, now time.Time, window time.Duration, sched *calendar.Schedule) []SLAItem {
+	var items []SLAItem
+	for _, o := range orders {
+		if a := o.Assignment; a != nil {
+			if item, ok := newSLAItem(o, SLAKindAssignment, a.Deadline, now, a.Deadline.Sub(now) <= window); ok {
+				item.Role = a.Role
+				item.AssigneeID = a.UserID
+				items = append(items, item)
+			}
+		}
+
+		if o.Status == "placed" && o.PaymentStatus != "payment_verified" {
+			continue
+		}
+		estimate := estimateCompletion(o, now, sched)
+		if item, ok := newSLAItem(o, SLAKindDelivery, o.ExpectedDelivery, now, estimate.After(o.ExpectedDelivery)); ok {
+			item.EstimatedCompletion = &estimate
+			items = append(items, item)
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DueAt.Equal(items[j].DueAt) {
+			return items[i].DueAt.Before(items[j].DueAt)
+		}
+		if items[i].OrderID != items[j].OrderID {
+			return items[i].OrderID < items[j].OrderID
+		}
+		return items[i].Kind < items[j].Kind
+	})
+	return items
+}
+
+func (s *OrderService) slaItems(now time.Time) ([]SLAItem, error) {
+	orders, err := s.repo.ListInFlightOrders()
+	if err != nil {
+		return nil, err
+	}
+	sched, err := s.calendar.Schedule(calendar.DefaultCalendarID)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateSLA(orders, now, slaWarningWindow(), sched), nil
+}
+
+// SLABreachesService reports the at-risk and breached work right now with
+// a count per aging bucket. level and kind narrow the report when set.
+func (s *OrderService) SLABreachesService(level, kind string) (*SLABreachesResponse, error) {
+	if level != "" && level != SLALevelAtRisk && level != SLALevelBreached {
+		return nil, fmt.Errorf("unknown level '%s'", level)
+	}
+	if kind != "" && kind != SLAKindAssignment && kind != SLAKindDelivery {
+		return nil, fmt.Errorf("unknown kind '%s'", kind)
+	}
+
+	now := utils.NowInIST()
+	items, err := s.slaItems(now)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	breaches := []SLAItem{}
+	for _, item := range items {
+		if (level != "" && item.Level != level) || (kind != "" && item.Kind != kind) {
+			continue
+		}
+		counts[item.Bucket]++
+		breaches = append(breaches, item)
+	}
+
+	buckets := make([]SLABucket, 0, len(slaBuckets))
+	for _, name := range slaBuckets {
+		buckets = append(buckets, SLABucket{Name: name, Count: counts[name]})
+	}
+	return &SLABreachesResponse{GeneratedAt: now, Buckets: buckets, Breaches: breaches}, nil
+}
+
+// EscalateSLAService raises EventSLAEscalated for work that became at risk
+// or breached since it was last escalated and returns how many it raised.
+func (s *OrderService) EscalateSLAService(now time.Time) (int, error) {
+	items, err := s.slaItems(now)
+	if err != nil {
+		return 0, err
+	}
+
+	raised := 0
+	for _, item := range items {
+		ok, err := s.repo.RecordSLAEscalation(item, now)
+		if err != nil {
+			return raised, fmt.Errorf("escalate %s for order %s: %w", item.Kind, item.OrderID, err)
+		}
+		if ok {
+			raised++
+		}
+	}
+	return raised, nil
+}
+
+// RescheduleStalledService moves the expected delivery of stalled orders,
+// whose printing or plant job is past its deadline, to when the remaining
+// work can be done: one more business day for the late job plus the full
+// time of the stages after it. Dates are only ever moved later. It returns
+// how many orders it moved.
+func (s *OrderService) RescheduleStalledService(now time.Time) (int, error) {
+	orders, err := s.repo.ListInFlightOrders()
+	if err != nil {
+		return 0, err
+	}
+	sched, err := s.calendar.Schedule(calendar.DefaultCalendarID)
+	if err != nil {
+		return 0, err
+	}
+
+	moved := 0
+	for _, o := range orders {
+		a := o.Assignment
+		if a == nil || !now.After(a.Deadline) {
+			continue
+		}
+		expected := sched.AddBusinessDays(now, 1+daysAfter(o.Status))
+		if !expected.After(o.ExpectedDelivery) {
+			continue
+		}
+		ok, err := s.repo.RescheduleDelivery(o.OrderID, expected, now)
+		if err != nil {
+			return moved, fmt.Errorf("reschedule order %s: %w", o.OrderID, err)
+		}
+		if ok {
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+// StartSLAMonitor reschedules stalled orders and escalates SLA breaches
+// every interval until ctx is done. Rescheduling runs first, so a stalled
+// order is escalated for its late job but not again for its delivery.
+func (s *OrderService) StartSLAMonitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			now := utils.NowInIST()
+			if n, err := s.RescheduleStalledService(now); err != nil {
+				log.Printf("orders: rescheduling stalled orders failed: %v", err)
+			} else if n > 0 {
+				log.Printf("orders: rescheduled delivery of %d stalled orders", n)
+			}
+			n, err := s.EscalateSLAService(now)
+			if err != nil {
+				log.Printf("orders: sla check failed: %v", err)
+			} else if n > 0 {
+				log.Printf("orders: sla check raised %d escalations", n)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
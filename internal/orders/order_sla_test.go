@@ -0,0 +1,101 @@
+package orders
+
+import (
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/calendar"
+	"enerzyflow_backend/utils"
+)
+
+// testSchedule works Monday to Saturday with a 17:00 cutoff.
+func testSchedule(t *testing.T) *calendar.Schedule {
+	t.Helper()
+	s, err := calendar.NewSchedule(calendar.WorkCalendar{
+		CalendarID:  calendar.DefaultCalendarID,
+		WorkingDays: []string{"mon", "tue", "wed", "thu", "fri", "sat"},
+		Cutoff:      "17:00",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func assignment(role, userID string, deadline time.Time) *OrderAssignment {
+	return &OrderAssignment{Role: role, UserID: userID, Deadline: deadline}
+}
+
+func TestEstimateCompletion(t *testing.T) {
+	sched := testSchedule(t)
+	// 2026-03-02 is a Monday.
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST)
+
+	tests := []struct {
+		name  string
+		order InFlightOrder
+		want  time.Time
+	}{
+		{"placed needs printing and plant", InFlightOrder{Status: "placed"}, time.Date(2026, 3, 7, 17, 0, 0, 0, utils.IST)},
+		{"printing waits for its deadline", InFlightOrder{Status: "printing", Assignment: assignment("printing", "p", time.Date(2026, 3, 4, 17, 0, 0, 0, utils.IST))}, time.Date(2026, 3, 7, 17, 0, 0, 0, utils.IST)},
+		{"overdue printing counts from now", InFlightOrder{Status: "printing", Assignment: assignment("printing", "p", time.Date(2026, 3, 1, 17, 0, 0, 0, utils.IST))}, time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST)},
+		{"ready for plant", InFlightOrder{Status: "ready_for_plant"}, time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST)},
+		{"plant ends at its deadline", InFlightOrder{Status: "plant_processing", Assignment: assignment("plant", "q", time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST))}, time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST)},
+		{"overdue plant is due now", InFlightOrder{Status: "plant_processing", Assignment: assignment("plant", "q", time.Date(2026, 2, 27, 17, 0, 0, 0, utils.IST))}, now},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := estimateCompletion(tt.order, now, sched); !got.Equal(tt.want) {
+				t.Errorf("estimateCompletion = %v, want %v", got.In(utils.IST), tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateSLA(t *testing.T) {
+	sched := testSchedule(t)
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, utils.IST)
+	farOff := time.Date(2026, 3, 20, 17, 0, 0, 0, utils.IST)
+
+	inFlight := []InFlightOrder{
+		{OrderID: "due-soon", Status: "printing", PaymentStatus: "payment_verified", ExpectedDelivery: farOff,
+			Assignment: assignment("printing", "p1", now.Add(6*time.Hour))},
+		{OrderID: "late-print", Status: "printing", PaymentStatus: "payment_verified", ExpectedDelivery: farOff,
+			Assignment: assignment("printing", "p2", now.Add(-30*time.Hour))},
+		{OrderID: "on-track", Status: "printing", PaymentStatus: "payment_verified", ExpectedDelivery: farOff,
+			Assignment: assignment("printing", "p3", now.Add(48*time.Hour))},
+		{OrderID: "unpaid", Status: "placed", PaymentStatus: "payment_pending", ExpectedDelivery: now.Add(-48 * time.Hour)},
+		{OrderID: "tight", Status: "placed", PaymentStatus: "payment_verified", ExpectedDelivery: time.Date(2026, 3, 5, 17, 0, 0, 0, utils.IST)},
+		{OrderID: "very-late", Status: "ready_for_plant", PaymentStatus: "payment_verified", ExpectedDelivery: now.Add(-8 * 24 * time.Hour)},
+	}
+
+	want := []struct {
+		orderID, kind, level, bucket string
+		overdueHours                 int
+	}{
+		{"very-late", SLAKindDelivery, SLALevelBreached, SLABucketOver7d, 192},
+		{"late-print", SLAKindAssignment, SLALevelBreached, SLABucket1To3d, 30},
+		{"due-soon", SLAKindAssignment, SLALevelAtRisk, SLABucketDueSoon, 0},
+		{"tight", SLAKindDelivery, SLALevelAtRisk, SLABucketDueSoon, 0},
+	}
+
+	got := evaluateSLA(inFlight, now, 12*time.Hour, sched)
+	if len(got) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		if g.OrderID != w.orderID || g.Kind != w.kind || g.Level != w.level || g.Bucket != w.bucket || g.OverdueHours != w.overdueHours {
+			t.Errorf("item %d = %s %s %s %s %dh, want %s %s %s %s %dh", i,
+				g.OrderID, g.Kind, g.Level, g.Bucket, g.OverdueHours,
+				w.orderID, w.kind, w.level, w.bucket, w.overdueHours)
+		}
+	}
+
+	if a := got[2]; a.Role != "printing" || a.AssigneeID != "p1" || a.EstimatedCompletion != nil {
+		t.Errorf("assignment item = %+v, want role and assignee but no estimate", a)
+	}
+	if d := got[3]; d.EstimatedCompletion == nil || !d.EstimatedCompletion.Equal(time.Date(2026, 3, 7, 17, 0, 0, 0, utils.IST)) {
+		t.Errorf("delivery estimate = %v, want Saturday 17:00", d.EstimatedCompletion)
+	}
+}
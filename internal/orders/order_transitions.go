@@ -0,0 +1,230 @@
+package orders
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"enerzyflow_backend/utils"
+)
+
+// Guards are preconditions a transition checks before it runs.
+const (
+	GuardPaymentVerified  = "payment_verified"
+	GuardReasonRequired   = "reason_required"
+	GuardAssignmentExists = "assignment_exists"
+	GuardNotReserved      = "not_reserved"
+)
+
+// Effect kinds are the work a transition does besides changing the status.
+const (
+	EffectAssign             = "assign"
+	EffectCompleteAssignment = "complete_assignment"
+)
+
+// Effect assigns the order to the acting user for Role with a deadline of
+// DeadlineDays business days on their calendar, or completes the order's
+// open assignments.
+type Effect struct {
+	Kind         string
+	Role         string
+	DeadlineDays int
+}
+
+// Transition moves an order from From to To when one of Roles requests
+// Action (To itself is accepted too). AnyAction accepts whatever status
+// is requested, for clients that only ever sent a placeholder. Guards are
+// checked in order and Effects run before the status is written.
+type Transition struct {
+	From      string
+	To        string
+	Action    string
+	AnyAction bool
+	Roles     []string
+	Guards    []string
+	Effects   []Effect
+}
+
+func (t Transition) matches(action string) bool {
+	return t.AnyAction || action == t.To || (t.Action != "" && action == t.Action)
+}
+
+func (t Transition) allows(role string) bool {
+	for _, r := range t.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (t Transition) requires(guard string) bool {
+	for _, g := range t.Guards {
+		if g == guard {
+			return true
+		}
+	}
+	return false
+}
+
+// Transitions is the order workflow. Printing accepts and prints verified
+// orders, plant fills and dispatches them, and admins can dispatch, complete
+// or decline anything that has not finished. Plant apps have always moved
+// an order one step on regardless of the status they send, so the plant
+// transitions take any action.
+var Transitions = []Transition{
+	{From: "placed", To: "printing", Action: "accepted", Roles: []string{"printing"},
+		Guards:  []string{GuardPaymentVerified, GuardNotReserved},
+		Effects: []Effect{{Kind: EffectAssign, Role: "printing", DeadlineDays: 2}}},
+	{From: "placed", To: "declined", Roles: []string{"printing"},
+		Guards: []string{GuardPaymentVerified, GuardReasonRequired}},
+	{From: "printing", To: "ready_for_plant", Roles: []string{"printing"},
+		Guards:  []string{GuardPaymentVerified, GuardAssignmentExists},
+		Effects: []Effect{{Kind: EffectCompleteAssignment}}},
+
+	{From: "ready_for_plant", To: "plant_processing", Action: "accepted", AnyAction: true, Roles: []string{"plant"},
+		Guards:  []string{GuardNotReserved},
+		Effects: []Effect{{Kind: EffectAssign, Role: "plant", DeadlineDays: 3}}},
+	{From: "plant_processing", To: "dispatched", AnyAction: true, Roles: []string{"plant"},
+		Guards:  []string{GuardAssignmentExists},
+		Effects: []Effect{{Kind: EffectCompleteAssignment}}},
+
+	{From: "placed", To: "dispatched", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "printing", To: "dispatched", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "ready_for_plant", To: "dispatched", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "plant_processing", To: "dispatched", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "placed", To: "completed", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "printing", To: "completed", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "ready_for_plant", To: "completed", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "plant_processing", To: "completed", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "dispatched", To: "completed", Roles: []string{"admin"}, Guards: []string{GuardPaymentVerified}},
+	{From: "placed", To: "declined", Roles: []string{"admin"}, Guards: []string{GuardReasonRequired}},
+	{From: "printing", To: "declined", Roles: []string{"admin"}, Guards: []string{GuardReasonRequired}},
+	{From: "ready_for_plant", To: "declined", Roles: []string{"admin"}, Guards: []string{GuardReasonRequired}},
+	{From: "plant_processing", To: "declined", Roles: []string{"admin"}, Guards: []string{GuardReasonRequired}},
+}
+
+// findTransition returns the transition role may take from status when it
+// requests action.
+func findTransition(role, status, action string) (*Transition, error) {
+	roleKnown := false
+	for i := range Transitions {
+		t := &Transitions[i]
+		if !t.allows(role) {
+			continue
+		}
+		roleKnown = true
+		if t.From == status && t.matches(action) {
+			return t, nil
+		}
+	}
+	if !roleKnown {
+		return nil, errors.New("unauthorized role")
+	}
+	return nil, fmt.Errorf("%s cannot move an order from '%s' to '%s'", role, status, action)
+}
+
+// normalizeReason trims whitespace and surrounding quotes from a reason.
+func normalizeReason(reason string) string {
+	return strings.Trim(strings.TrimSpace(reason), `"`)
+}
+
+// ErrOrderConflict is matched by ConflictError.
+var ErrOrderConflict = errors.New("order was changed by someone else")
+
+// ConflictError reports that the caller's version of an order is stale.
+type ConflictError struct {
+	Current int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s; current version is %d", ErrOrderConflict, e.Current)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrOrderConflict
+}
+
+// checkGuards verifies t's guards for userID acting on order, reading
+// through q so a transition sees its own locked transaction. The reason
+// guard is skipped when skipReason is set, which allowed-transitions uses
+// since the caller has not typed a reason yet.
+func (s *OrderService) checkGuards(q OrderTxRepository, t *Transition, order *OrderResponse, userID, role, reason string, skipReason bool) error {
+	for _, g := range t.Guards {
+		switch g {
+		case GuardPaymentVerified:
+			if order.PaymentStatus != "payment_verified" {
+				return errors.New("cannot update order status until payment is verified")
+			}
+		case GuardReasonRequired:
+			if !skipReason && normalizeReason(reason) == "" {
+				return fmt.Errorf("reason required when moving an order to '%s'", t.To)
+			}
+		case GuardAssignmentExists:
+			// Orders that reached this stage before assignments were
+			// recorded have none, and anyone in the role may finish them.
+			a, err := q.CurrentAssignment(order.OrderID, role)
+			if err != nil {
+				return fmt.Errorf("failed to verify assignment: %v", err)
+			}
+			if a != nil && a.UserID != userID {
+				return errors.New("you are not assigned to this order")
+			}
+		case GuardNotReserved:
+			holder, err := q.ReservedFor(order.OrderID, role)
+			if err != nil {
+				return fmt.Errorf("failed to check work claims: %v", err)
+			}
+			if holder != "" && holder != userID {
+				return errors.New("order is claimed by or pinned to another user")
+			}
+		default:
+			return fmt.Errorf("unknown guard '%s'", g)
+		}
+	}
+	return nil
+}
+
+// applyTransition runs t's effects and writes the new status.
+func (s *OrderService) applyTransition(q OrderTxRepository, t *Transition, order *OrderResponse, userID, reason string) error {
+	for _, e := range t.Effects {
+		var err error
+		switch e.Kind {
+		case EffectAssign:
+			err = s.assign(q, order.OrderID, userID, e)
+		case EffectCompleteAssignment:
+			err = q.CompleteOrderAssignment(order.OrderID, userID)
+		default:
+			err = fmt.Errorf("unknown effect '%s'", e.Kind)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return q.UpdateOrderStatus(order.OrderID, t.To, userID, normalizeReason(reason))
+}
+
+// assignEffect returns the assign effect of the workflow transition into
+// status.
+func assignEffect(status string) Effect {
+	for _, t := range Transitions {
+		if t.To != status {
+			continue
+		}
+		for _, e := range t.Effects {
+			if e.Kind == EffectAssign {
+				return e
+			}
+		}
+	}
+	return Effect{}
+}
+
+func (s *OrderService) assign(q OrderTxRepository, orderID, userID string, e Effect) error {
+	sched, err := s.calendar.Schedule(userID)
+	if err != nil {
+		return err
+	}
+	return q.AssignOrder(orderID, userID, e.Role, sched.AddBusinessDays(utils.NowInIST(), e.DeadlineDays))
+}
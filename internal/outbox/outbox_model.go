@@ -0,0 +1,25 @@
+package outbox
+
+import "time"
+
+const (
+	StatusPending = "pending"
+	StatusSending = "sending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+type OutboxMessage struct {
+	ID            int64      `json:"id"`
+	To            []string   `json:"to"`
+	Subject       string     `json:"subject"`
+	HTML          string     `json:"html,omitempty"`
+	Text          string     `json:"text,omitempty"`
+	Sensitive     bool       `json:"sensitive"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+}
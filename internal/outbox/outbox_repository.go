@@ -0,0 +1,166 @@
+package outbox
+
+import (
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+	"strings"
+	"time"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+func insertMessage(ex execer, to []string, subject, html, text string, sensitive bool) error {
+	_, err := ex.Exec(`
+		INSERT INTO email_outbox (to_address, subject, html_body, text_body, sensitive, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', 0, NOW(), NOW(), NOW())
+	`, strings.Join(to, ","), subject, html, text, sensitive)
+	return err
+}
+
+// claimDue marks up to limit due messages as sending and returns them.
+// SKIP LOCKED lets several instances run the dispatcher concurrently; a
+// message stuck in sending past its lease is picked up again and the lost
+// try counts as an attempt, so a message that keeps crashing the worker
+// still reaches the dead-letter limit.
+func claimDue(limit int, lease time.Duration) ([]OutboxMessage, error) {
+	rows, err := db.DB.Query(`
+		UPDATE email_outbox
+		SET status = 'sending', locked_until = $2, updated_at = NOW(),
+		    attempts = attempts + CASE WHEN status = 'sending' THEN 1 ELSE 0 END
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, to_address, subject, html_body, text_body, sensitive, attempts
+	`, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		var to string
+		if err := rows.Scan(&m.ID, &to, &m.Subject, &m.HTML, &m.Text, &m.Sensitive, &m.Attempts); err != nil {
+			return nil, err
+		}
+		m.To = strings.Split(to, ",")
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+// markSent records a delivery. Bodies of sensitive messages (OTP codes) are
+// dropped once they have left the building.
+func markSent(id int64) error {
+	_, err := db.DB.Exec(`
+		UPDATE email_outbox
+		SET status = 'sent', attempts = attempts + 1, sent_at = NOW(), last_error = NULL, locked_until = NULL, updated_at = NOW(),
+		    html_body = CASE WHEN sensitive THEN '' ELSE html_body END,
+		    text_body = CASE WHEN sensitive THEN '' ELSE text_body END
+		WHERE id = $1
+	`, id)
+	return err
+}
+
+// markFailed schedules another attempt, or dead-letters the message. A dead
+// sensitive message can never be resent, so its body is dropped as well.
+func markFailed(id int64, lastErr string, next time.Time, dead bool) error {
+	status := StatusPending
+	if dead {
+		status = StatusDead
+	}
+	_, err := db.DB.Exec(`
+		UPDATE email_outbox
+		SET status = $1, attempts = attempts + 1, last_error = $2, next_attempt_at = $3, locked_until = NULL, updated_at = NOW(),
+		    html_body = CASE WHEN sensitive AND $5 THEN '' ELSE html_body END,
+		    text_body = CASE WHEN sensitive AND $5 THEN '' ELSE text_body END
+		WHERE id = $4
+	`, status, lastErr, next, id, dead)
+	return err
+}
+
+// markDead dead-letters a message without counting another attempt.
+func markDead(id int64, lastErr string) error {
+	_, err := db.DB.Exec(`
+		UPDATE email_outbox
+		SET status = $1, last_error = $2, locked_until = NULL, updated_at = NOW(),
+		    html_body = CASE WHEN sensitive THEN '' ELSE html_body END,
+		    text_body = CASE WHEN sensitive THEN '' ELSE text_body END
+		WHERE id = $3
+	`, StatusDead, lastErr, id)
+	return err
+}
+
+func ListMessages(status string, limit, offset int) ([]OutboxMessage, int, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, to_address, subject, sensitive, status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at, sent_at,
+		       COUNT(*) OVER() AS total_count
+		FROM email_outbox
+		WHERE ($1 = '' OR status = $1)
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3
+	`, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		out   []OutboxMessage
+		total int
+	)
+	for rows.Next() {
+		var m OutboxMessage
+		var to string
+		if err := rows.Scan(&m.ID, &to, &m.Subject, &m.Sensitive, &m.Status, &m.Attempts, &m.NextAttemptAt,
+			&m.LastError, &m.CreatedAt, &m.SentAt, &total); err != nil {
+			return nil, 0, err
+		}
+		m.To = strings.Split(to, ",")
+		out = append(out, m)
+	}
+	return out, total, rows.Err()
+}
+
+func GetMessage(id int64) (*OutboxMessage, error) {
+	var m OutboxMessage
+	var to string
+	err := db.DB.QueryRow(`
+		SELECT id, to_address, subject, html_body, text_body, sensitive, status, attempts, next_attempt_at, COALESCE(last_error, ''), created_at, sent_at
+		FROM email_outbox
+		WHERE id = $1
+	`, id).Scan(&m.ID, &to, &m.Subject, &m.HTML, &m.Text, &m.Sensitive, &m.Status, &m.Attempts, &m.NextAttemptAt,
+		&m.LastError, &m.CreatedAt, &m.SentAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	m.To = strings.Split(to, ",")
+	return &m, nil
+}
+
+// requeueMessage puts a dead or sent message back into the queue with a
+// fresh attempt budget. Sensitive messages are never resent: their bodies
+// are gone once they are sent or dead.
+func requeueMessage(id int64) (bool, error) {
+	res, err := db.DB.Exec(`
+		UPDATE email_outbox
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('dead', 'sent') AND NOT sensitive
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,159 @@
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/mailer"
+	"errors"
+	"log"
+	"time"
+)
+
+const (
+	maxAttempts  = 8
+	backoffBase  = 30 * time.Second
+	backoffMax   = time.Hour
+	claimBatch   = 20
+	sendingLease = 2 * time.Minute
+)
+
+var (
+	ErrMessageNotFound = errors.New("message not found")
+	ErrNotResendable   = errors.New("only dead or sent messages that are not sensitive can be resent")
+)
+
+var wake = make(chan struct{}, 1)
+
+// Wake makes the dispatcher look for due messages now rather than at its
+// next poll. Callers of EnqueueTx call it once their transaction commits.
+func Wake() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue stores msg for background delivery.
+func Enqueue(msg mailer.Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("outbox: message has no recipients")
+	}
+	if err := insertMessage(db.DB, msg.To, msg.Subject, msg.HTML, msg.Text, msg.Sensitive); err != nil {
+		return err
+	}
+	Wake()
+	return nil
+}
+
+// EnqueueTx stores msg as part of tx, so the email goes out if and only if
+// the surrounding business change commits. The row is not visible to the
+// dispatcher before then; call Wake after the commit.
+func EnqueueTx(tx *sql.Tx, msg mailer.Message) error {
+	if len(msg.To) == 0 {
+		return errors.New("outbox: message has no recipients")
+	}
+	return insertMessage(tx, msg.To, msg.Subject, msg.HTML, msg.Text, msg.Sensitive)
+}
+
+func backoff(attempts int) time.Duration {
+	d := backoffBase
+	for i := 1; i < attempts && d < backoffMax; i++ {
+		d *= 2
+	}
+	if d > backoffMax {
+		d = backoffMax
+	}
+	return d
+}
+
+// StartDispatcher delivers queued messages through the default mailer every
+// interval (and immediately after an Enqueue) until ctx is cancelled.
+func StartDispatcher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			dispatchDue()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
+
+func dispatchDue() {
+	for {
+		batch, err := claimDue(claimBatch, sendingLease)
+		if err != nil {
+			log.Printf("outbox: claim failed: %v", err)
+			return
+		}
+		for _, m := range batch {
+			if m.Attempts >= maxAttempts {
+				// every try so far was cut short by a crash or a lost lease
+				log.Printf("outbox: message %d dead-lettered after %d interrupted attempts", m.ID, m.Attempts)
+				if err := markDead(m.ID, "delivery interrupted too many times"); err != nil {
+					log.Printf("outbox: mark dead %d: %v", m.ID, err)
+				}
+				continue
+			}
+			deliver(m)
+		}
+		if len(batch) < claimBatch {
+			return
+		}
+	}
+}
+
+func deliver(m OutboxMessage) {
+	err := mailer.Send(mailer.Message{To: m.To, Subject: m.Subject, HTML: m.HTML, Text: m.Text})
+	if err == nil {
+		if err := markSent(m.ID); err != nil {
+			log.Printf("outbox: mark sent %d: %v", m.ID, err)
+		}
+		return
+	}
+
+	attempts := m.Attempts + 1
+	dead := attempts >= maxAttempts
+	if dead {
+		log.Printf("outbox: message %d dead-lettered after %d attempts: %v", m.ID, attempts, err)
+	} else {
+		log.Printf("outbox: message %d attempt %d failed: %v", m.ID, attempts, err)
+	}
+	if err := markFailed(m.ID, err.Error(), time.Now().Add(backoff(attempts)), dead); err != nil {
+		log.Printf("outbox: mark failed %d: %v", m.ID, err)
+	}
+}
+
+func ListMessagesService(status string, limit, offset int) ([]OutboxMessage, int, error) {
+	switch status {
+	case "", StatusPending, StatusSending, StatusSent, StatusDead:
+	default:
+		return nil, 0, errors.New("invalid status filter")
+	}
+	return ListMessages(status, limit, offset)
+}
+
+func ResendMessageService(id int64) error {
+	m, err := GetMessage(id)
+	if err != nil {
+		return err
+	}
+	if m == nil {
+		return ErrMessageNotFound
+	}
+	ok, err := requeueMessage(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotResendable
+	}
+	Wake()
+	return nil
+}
@@ -0,0 +1,76 @@
+package testdb
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"enerzyflow_backend/internal/db"
+)
+
+// IDs of the rows in fixtures.sql.
+const (
+	AdminID    = "00000000-0000-0000-0000-000000000001"
+	PrintingID = "00000000-0000-0000-0000-000000000002"
+	PlantID    = "00000000-0000-0000-0000-000000000003"
+	OwnerID    = "00000000-0000-0000-0000-000000000004"
+	OtherID    = "00000000-0000-0000-0000-000000000005"
+	// SecondPrintingID is another printing user, for work pool contention.
+	SecondPrintingID = "00000000-0000-0000-0000-000000000006"
+
+	OwnerCompanyID = "00000000-0000-0000-0001-000000000001"
+	OtherCompanyID = "00000000-0000-0000-0001-000000000002"
+
+	OwnerLabelID = "label-acme-classic"
+	OtherLabelID = "label-blue-peak"
+
+	// OrderPaymentUploaded is placed with a payment screenshot awaiting review.
+	OrderPaymentUploaded = "00000000-0000-0000-0002-000000000001"
+	// OrderPaymentPending is placed without any payment.
+	OrderPaymentPending = "00000000-0000-0000-0002-000000000002"
+	// OrderReadyForPrinting belongs to OtherID, is payment verified and has
+	// label details, so it is in the printing queue.
+	OrderReadyForPrinting = "00000000-0000-0000-0002-000000000003"
+)
+
+//go:embed fixtures.sql
+var fixturesSQL string
+
+// Reset empties every table except schema_migrations and reloads the
+// fixtures, so each test starts from the same data.
+func Reset(ctx context.Context) error {
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT quote_ident(table_name)
+		FROM information_schema.tables
+		WHERE table_schema = current_schema()
+		  AND table_type = 'BASE TABLE'
+		  AND table_name <> 'schema_migrations'
+	`)
+	if err != nil {
+		return err
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(tables) > 0 {
+		if _, err := db.DB.ExecContext(ctx, `TRUNCATE `+strings.Join(tables, ", ")+` RESTART IDENTITY CASCADE`); err != nil {
+			return fmt.Errorf("truncate: %w", err)
+		}
+	}
+	if _, err := db.DB.ExecContext(ctx, fixturesSQL); err != nil {
+		return fmt.Errorf("load fixtures: %w", err)
+	}
+	return nil
+}
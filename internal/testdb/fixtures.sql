@@ -0,0 +1,40 @@
+-- Fixture data loaded by testdb.Reset. IDs are mirrored as constants in
+-- fixtures.go so tests can refer to them.
+
+INSERT INTO users (user_id, email, name, role) VALUES
+    ('00000000-0000-0000-0000-000000000001', 'admin@test.enerzyflow.com',    'Test Admin',    'admin'),
+    ('00000000-0000-0000-0000-000000000002', 'printing@test.enerzyflow.com', 'Test Printing', 'printing'),
+    ('00000000-0000-0000-0000-000000000003', 'plant@test.enerzyflow.com',    'Test Plant',    'plant'),
+    ('00000000-0000-0000-0000-000000000004', 'owner@test.enerzyflow.com',    'Test Owner',    'business_owner'),
+    ('00000000-0000-0000-0000-000000000005', 'other@test.enerzyflow.com',    'Other Owner',   'business_owner'),
+    ('00000000-0000-0000-0000-000000000006', 'printing2@test.enerzyflow.com', 'Second Printing', 'printing');
+
+INSERT INTO companies (company_id, user_id, name, address) VALUES
+    ('00000000-0000-0000-0001-000000000001', '00000000-0000-0000-0000-000000000004', 'Acme Springs', '12 Market Road, Pune'),
+    ('00000000-0000-0000-0001-000000000002', '00000000-0000-0000-0000-000000000005', 'Blue Peak',    '4 Lake View, Nashik');
+
+INSERT INTO labels (label_id, company_id, name, label_url) VALUES
+    ('label-acme-classic', '00000000-0000-0000-0001-000000000001', 'Acme Classic', 'https://example.com/labels/acme-classic.png'),
+    ('label-blue-peak',    '00000000-0000-0000-0001-000000000002', 'Blue Peak',    'https://example.com/labels/blue-peak.png');
+
+INSERT INTO skus (sku_id, variant, volume_ml, cap_color, moq, active) VALUES
+    ('classic-500ml-blue',   'classic', 500,  'blue',  100, TRUE),
+    ('classic-1000ml-white', 'classic', 1000, 'white', 100, TRUE),
+    ('sport-750ml-red',      'sport',   750,  'red',   100, TRUE);
+
+-- One order per interesting starting point of the lifecycle.
+INSERT INTO orders (order_id, user_id, label_id, variant, qty, cap_color, volume, sku_id, status, payment_status, payment_screenshot_url, expected_delivery_date, created_at, updated_at) VALUES
+    ('00000000-0000-0000-0002-000000000001', '00000000-0000-0000-0000-000000000004', 'label-acme-classic', 'classic', 500, 'blue',  500,  'classic-500ml-blue',   'placed', 'payment_uploaded', 'https://example.com/payments/1.png', NOW() + INTERVAL '10 days', NOW() - INTERVAL '3 hours', NOW() - INTERVAL '3 hours'),
+    ('00000000-0000-0000-0002-000000000002', '00000000-0000-0000-0000-000000000004', 'label-acme-classic', 'classic', 200, 'white', 1000, 'classic-1000ml-white', 'placed', 'payment_pending',  '',                                   NOW() + INTERVAL '10 days', NOW() - INTERVAL '2 hours', NOW() - INTERVAL '2 hours'),
+    ('00000000-0000-0000-0002-000000000003', '00000000-0000-0000-0000-000000000005', 'label-blue-peak',    'sport',   300, 'red',   750,  'sport-750ml-red',      'placed', 'payment_verified', 'https://example.com/payments/3.png', NOW() + INTERVAL '10 days', NOW() - INTERVAL '1 hour',  NOW() - INTERVAL '1 hour');
+
+UPDATE orders SET payment_verified_at = NOW() - INTERVAL '30 minutes'
+WHERE order_id = '00000000-0000-0000-0002-000000000003';
+
+INSERT INTO order_status_history (order_id, status, changed_at, changed_by) VALUES
+    ('00000000-0000-0000-0002-000000000001', 'placed', NOW() - INTERVAL '3 hours', '00000000-0000-0000-0000-000000000004'),
+    ('00000000-0000-0000-0002-000000000002', 'placed', NOW() - INTERVAL '2 hours', '00000000-0000-0000-0000-000000000004'),
+    ('00000000-0000-0000-0002-000000000003', 'placed', NOW() - INTERVAL '1 hour',  '00000000-0000-0000-0000-000000000005');
+
+INSERT INTO order_label_details (order_id, no_of_sheets, cutting_type, labels_per_sheet, description) VALUES
+    ('00000000-0000-0000-0002-000000000003', 30, 'die_cut', 12, 'Sport label, gloss finish');
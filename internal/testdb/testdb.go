@@ -0,0 +1,163 @@
+// Package testdb gives integration tests a migrated Postgres database with
+// fixture data.
+//
+// The database comes from PG_TEST_URL when it is set. Otherwise an embedded
+// Postgres binary is downloaded (once, into the module cache directory) and
+// started on a free port; set PG_TEST_EMBEDDED=off to skip that. Each run
+// works in its own schema, which is dropped again by the stop function, so
+// PG_TEST_URL may point at a shared development database.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/db"
+
+	embeddedpostgres "github.com/fergusstrange/embedded-postgres"
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+var unavailable string
+
+// Start points db.DB at a fresh schema and applies all migrations. When no
+// database can be reached it returns a nil error and a no-op stop function,
+// and Require skips the calling tests with the reason.
+func Start() (stop func(), err error) {
+	ctx := context.Background()
+	baseURL := os.Getenv("PG_TEST_URL")
+	stopServer := func() {}
+
+	if baseURL == "" {
+		if strings.EqualFold(os.Getenv("PG_TEST_EMBEDDED"), "off") {
+			unavailable = "PG_TEST_URL is not set and PG_TEST_EMBEDDED=off"
+			return func() {}, nil
+		}
+		baseURL, stopServer, err = startEmbedded()
+		if err != nil {
+			unavailable = "PG_TEST_URL is not set and embedded Postgres did not start: " + err.Error()
+			return func() {}, nil
+		}
+	}
+
+	admin, err := sql.Open("pgx", baseURL)
+	if err != nil {
+		stopServer()
+		return nil, err
+	}
+	if err := admin.PingContext(ctx); err != nil {
+		admin.Close()
+		stopServer()
+		unavailable = "cannot reach PG_TEST_URL: " + err.Error()
+		return func() {}, nil
+	}
+
+	schema := "enerzyflow_test_" + uuid.New().String()[:8]
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		admin.Close()
+		stopServer()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	stop = func() {
+		if db.DB != nil {
+			db.DB.Close()
+		}
+		_, _ = admin.ExecContext(context.Background(), `DROP SCHEMA `+schema+` CASCADE`)
+		admin.Close()
+		stopServer()
+	}
+
+	testURL, err := withSearchPath(baseURL, schema)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	db.DB, err = sql.Open("pgx", testURL)
+	if err != nil {
+		stop()
+		return nil, err
+	}
+	if _, err := db.MigrateUp(ctx); err != nil {
+		stop()
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+	if err := Reset(ctx); err != nil {
+		stop()
+		return nil, err
+	}
+	return stop, nil
+}
+
+// Require skips t when Start found no database.
+func Require(t testing.TB) {
+	t.Helper()
+	if unavailable != "" {
+		t.Skip("integration test skipped: " + unavailable)
+	}
+	if db.DB == nil {
+		t.Skip("integration test skipped: testdb.Start was not called")
+	}
+}
+
+func withSearchPath(rawURL, schema string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("parse PG_TEST_URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+func startEmbedded() (string, func(), error) {
+	port, err := freePort()
+	if err != nil {
+		return "", nil, err
+	}
+	runtime, err := os.MkdirTemp("", "enerzyflow-pg-")
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg := embeddedpostgres.DefaultConfig().
+		Port(uint32(port)).
+		Database("enerzyflow_test").
+		RuntimePath(runtime).
+		DataPath(filepath.Join(runtime, "data")).
+		StartTimeout(60 * time.Second).
+		Logger(io.Discard)
+	if cache, err := os.UserCacheDir(); err == nil {
+		cfg = cfg.CachePath(filepath.Join(cache, "enerzyflow", "embedded-postgres"))
+	}
+
+	pg := embeddedpostgres.NewDatabase(cfg)
+	if err := pg.Start(); err != nil {
+		os.RemoveAll(runtime)
+		return "", nil, err
+	}
+	stop := func() {
+		_ = pg.Stop()
+		os.RemoveAll(runtime)
+	}
+	return cfg.GetConnectionURL() + "?sslmode=disable", stop, nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
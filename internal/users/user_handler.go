@@ -7,7 +7,15 @@ import (
 	"github.com/google/uuid"
 )
 
-func SaveProfileHandler(c *gin.Context) {
+type UserHandler struct {
+	svc *UserService
+}
+
+func NewUserHandler(svc *UserService) *UserHandler {
+	return &UserHandler{svc: svc}
+}
+
+func (h *UserHandler) SaveProfileHandler(c *gin.Context) {
     var req SaveProfileRequest
 
     if err := c.ShouldBindJSON(&req); err != nil {
@@ -27,7 +35,7 @@ func SaveProfileHandler(c *gin.Context) {
     }
 
 
-    resp, err := SaveProfileService(userID.String(), req)
+    resp, err := h.svc.SaveProfileService(userID.String(), req)
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
@@ -42,7 +50,7 @@ func SaveProfileHandler(c *gin.Context) {
     })
 }
 
-func GetProfileHandler(c *gin.Context) {
+func (h *UserHandler) GetProfileHandler(c *gin.Context) {
     userIDVal, exists := c.Get("user_id")
     if !exists {
         c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthenticated"})
@@ -54,7 +62,7 @@ func GetProfileHandler(c *gin.Context) {
         return
     }
 
-    resp, err := GetProfileService(userID.String())
+    resp, err := h.svc.GetProfileService(userID.String())
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
@@ -63,8 +71,8 @@ func GetProfileHandler(c *gin.Context) {
     c.JSON(http.StatusOK, resp)
 }
 
-func GetAllUsersHandler(c *gin.Context) {
-	users, err := GetAllUserService()
+func (h *UserHandler) GetAllUsersHandler(c *gin.Context) {
+	users, err := h.svc.GetAllUserService()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -73,7 +81,7 @@ func GetAllUsersHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"users": users})
 }
 
-func CreateUserByAdminHandler(c *gin.Context){
+func (h *UserHandler) CreateUserByAdminHandler(c *gin.Context){
     role := c.GetString("role")
     if role != "admin" {
         c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
@@ -85,7 +93,7 @@ func CreateUserByAdminHandler(c *gin.Context){
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
     }
-    user, err := CreateUserByAdminService(req)
+    user, err := h.svc.CreateUserByAdminService(req)
     if err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
         return
@@ -98,14 +106,14 @@ func CreateUserByAdminHandler(c *gin.Context){
 }
 
 
-func SubmitEnquiryHandler(c *gin.Context){
+func (h *UserHandler) SubmitEnquiryHandler(c *gin.Context){
     var req SubmitEnquiryRequest
     if err := c.ShouldBindJSON(&req); err != nil {
         c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
         return
     }
 
-    err := SubmitEnquiryService(req)
+    err := h.svc.SubmitEnquiryService(req)
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
         return
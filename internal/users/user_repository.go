@@ -2,12 +2,45 @@ package users
 
 import (
     "database/sql"
-    "enerzyflow_backend/internal/db"
     "errors"
 )
 
-func GetUserByEmail(email string) (*User, error) {
-    row := db.DB.QueryRow("SELECT user_id, email, name, phone, designation, role, profile_url FROM users WHERE email = $1", email)
+// UserRepository is the storage behind users. WithTx runs fn in a
+// transaction that *Tx methods (here and in other repositories) join;
+// in-memory implementations pass a nil tx.
+type UserRepository interface {
+    GetUserByEmail(email string) (*User, error)
+    GetUserByPhone(phone string) (*User, error)
+    GetUserByID(userID string) (*User, error)
+    GetAllUsers() ([]User, error)
+    InsertUser(u *User) error
+    UpdateUserRole(userID, role string) error
+    UpdateUserProfileTx(tx *sql.Tx, u *User) error
+    WithTx(fn func(tx *sql.Tx) error) error
+}
+
+type PostgresUserRepository struct {
+    db *sql.DB
+}
+
+func NewPostgresUserRepository(conn *sql.DB) *PostgresUserRepository {
+    return &PostgresUserRepository{db: conn}
+}
+
+func (r *PostgresUserRepository) WithTx(fn func(tx *sql.Tx) error) error {
+    tx, err := r.db.Begin()
+    if err != nil {
+        return err
+    }
+    if err := fn(tx); err != nil {
+        _ = tx.Rollback()
+        return err
+    }
+    return tx.Commit()
+}
+
+func (r *PostgresUserRepository) GetUserByEmail(email string) (*User, error) {
+    row := r.db.QueryRow("SELECT user_id, email, name, phone, designation, role, profile_url FROM users WHERE email = $1", email)
     u := &User{}
     if err := row.Scan(&u.UserID, &u.Email, &u.Name, &u.Phone, &u.Designation, &u.Role, &u.ProfileURL); err != nil {
         if err == sql.ErrNoRows {
@@ -18,8 +51,8 @@ func GetUserByEmail(email string) (*User, error) {
     return u, nil
 }
 
-func GetUserByPhone(phone string) (*User, error) {
-    row:= db.DB.QueryRow("SELECT user_id, email, name, phone, designation, role, profile_url FROM users WHERE phone = $1", phone)
+func (r *PostgresUserRepository) GetUserByPhone(phone string) (*User, error) {
+    row:= r.db.QueryRow("SELECT user_id, email, name, phone, designation, role, profile_url FROM users WHERE phone = $1", phone)
     u := &User{}
     if err := row.Scan(&u.UserID, &u.Email, &u.Name, &u.Phone, &u.Designation, &u.Role, &u.ProfileURL); err != nil {
         if err == sql.ErrNoRows {
@@ -30,8 +63,8 @@ func GetUserByPhone(phone string) (*User, error) {
     return u, nil
 }
 
-func GetUserByID(userID string) (*User, error) {
-    row := db.DB.QueryRow("SELECT user_id, email, name, COALESCE(phone, ''), designation, role, profile_url FROM users WHERE user_id = $1", userID)
+func (r *PostgresUserRepository) GetUserByID(userID string) (*User, error) {
+    row := r.db.QueryRow("SELECT user_id, email, name, COALESCE(phone, ''), designation, role, profile_url FROM users WHERE user_id = $1", userID)
     u := &User{}
     if err := row.Scan(&u.UserID, &u.Email, &u.Name, &u.Phone, &u.Designation, &u.Role, &u.ProfileURL); err != nil {
         if err == sql.ErrNoRows {
@@ -42,20 +75,20 @@ func GetUserByID(userID string) (*User, error) {
     return u, nil
 }
 
-func UpdateUserProfile(id int, name string, phone string) error {
-    _, err := db.DB.Exec("UPDATE users SET name = $1, phone = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3", name, phone, id)
-    return err
-}
-
-func InsertUser(u *User) error {
+func (r *PostgresUserRepository) InsertUser(u *User) error {
     if u.Email == "" || u.Role == "" || u.UserID == "" {
         return errors.New("user_id, email and role are required")
     }
-    _, err := db.DB.Exec(`INSERT INTO users (user_id, email, role) VALUES ($1, $2, $3)`, u.UserID, u.Email, u.Role)
+    _, err := r.db.Exec(`INSERT INTO users (user_id, email, role) VALUES ($1, $2, $3)`, u.UserID, u.Email, u.Role)
+    return err
+}
+
+func (r *PostgresUserRepository) UpdateUserRole(userID, role string) error {
+    _, err := r.db.Exec(`UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2`, role, userID)
     return err
 }
 
-func UpdateUserProfileTx(tx *sql.Tx, u *User) error {
+func (r *PostgresUserRepository) UpdateUserProfileTx(tx *sql.Tx, u *User) error {
     if u == nil || u.UserID == "" {
         return errors.New("user is nil or user_id missing")
     }
@@ -63,8 +96,8 @@ func UpdateUserProfileTx(tx *sql.Tx, u *User) error {
     return err
 }
 
-func GetAllUsers() ([]User, error) {
-	rows, err := db.DB.Query(`SELECT user_id, email, name, phone, role, profile_url FROM users`)
+func (r *PostgresUserRepository) GetAllUsers() ([]User, error) {
+	rows, err := r.db.Query(`SELECT user_id, email, name, phone, role, profile_url FROM users`)
 	if err != nil {
 		return nil, err
 	}
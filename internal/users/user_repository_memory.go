@@ -0,0 +1,116 @@
+package users
+
+import (
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// MemoryUserRepository is an in-memory UserRepository for tests. WithTx
+// calls fn with a nil tx and does not roll back on error.
+type MemoryUserRepository struct {
+	mu    sync.Mutex
+	users map[string]User
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: map[string]User{}}
+}
+
+func (r *MemoryUserRepository) WithTx(fn func(tx *sql.Tx) error) error {
+	return fn(nil)
+}
+
+func (r *MemoryUserRepository) find(match func(User) bool) *User {
+	for _, u := range r.users {
+		if match(u) {
+			u := u
+			return &u
+		}
+	}
+	return nil
+}
+
+func (r *MemoryUserRepository) GetUserByEmail(email string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.find(func(u User) bool { return u.Email == email }), nil
+}
+
+func (r *MemoryUserRepository) GetUserByPhone(phone string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.find(func(u User) bool { return u.Phone != nil && *u.Phone == phone }), nil
+}
+
+func (r *MemoryUserRepository) GetUserByID(userID string) (*User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (r *MemoryUserRepository) GetAllUsers() ([]User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]User, 0, len(r.users))
+	for _, u := range r.users {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Email < list[j].Email })
+	return list, nil
+}
+
+func (r *MemoryUserRepository) InsertUser(u *User) error {
+	if u.Email == "" || u.Role == "" || u.UserID == "" {
+		return errors.New("user_id, email and role are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.find(func(existing User) bool { return existing.Email == u.Email }) != nil {
+		return errors.New("email already exists")
+	}
+	r.users[u.UserID] = *u
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateUserRole(userID, role string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[userID]
+	if !ok {
+		return nil
+	}
+	u.Role = role
+	r.users[userID] = u
+	return nil
+}
+
+func (r *MemoryUserRepository) UpdateUserProfileTx(_ *sql.Tx, u *User) error {
+	if u == nil || u.UserID == "" {
+		return errors.New("user is nil or user_id missing")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	existing, ok := r.users[u.UserID]
+	if !ok {
+		return nil
+	}
+	existing.Name = u.Name
+	existing.Phone = u.Phone
+	existing.Designation = u.Designation
+	existing.ProfileURL = u.ProfileURL
+	r.users[u.UserID] = existing
+	return nil
+}
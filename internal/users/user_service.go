@@ -3,18 +3,31 @@ package users
 import (
 	"database/sql"
 	"enerzyflow_backend/internal/companies"
-	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/outbox"
 	"errors"
 	"fmt"
-	"os"
 
 	"github.com/google/uuid"
-	"github.com/resendlabs/resend-go"
 )
 
-func GetUserByEmailService(email string) (*User, bool, error) {
+type UserService struct {
+	users       UserRepository
+	companyRepo companies.CompanyRepository
+	companies   *companies.CompanyService
+}
+
+func NewUserService(users UserRepository, companyRepo companies.CompanyRepository) *UserService {
+	return &UserService{
+		users:       users,
+		companyRepo: companyRepo,
+		companies:   companies.NewCompanyService(companyRepo),
+	}
+}
+
+func (s *UserService) GetUserByEmailService(email string) (*User, bool, error) {
 	var u *User
-	u, err := GetUserByEmail(email)
+	u, err := s.users.GetUserByEmail(email)
 
 	if err == sql.ErrNoRows {
 		return nil, false, nil
@@ -26,13 +39,13 @@ func GetUserByEmailService(email string) (*User, bool, error) {
 	return u, true, nil
 }
 
-func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*SaveProfileResponse, error) {
+func (s *UserService) SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*SaveProfileResponse, error) {
 	resp := &SaveProfileResponse{}
 	if authenticatedUserID == "" {
 		return nil, errors.New("missing authenticated user id")
 	}
 
-	u, err := GetUserByID(authenticatedUserID)
+	u, err := s.users.GetUserByID(authenticatedUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -40,18 +53,8 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 		return nil, errors.New("user not found")
 	}
 
-	tx, err := db.DB.Begin()
-	if err != nil {
-		return nil, err
-	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		}
-	}()
-
 	if req.Profile.Phone != nil && *req.Profile.Phone != "" {
-		existingUser, err := GetUserByPhone(*req.Profile.Phone)
+		existingUser, err := s.users.GetUserByPhone(*req.Profile.Phone)
 		if err != nil {
 			return nil, err
 		}
@@ -64,11 +67,8 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 	u.Phone = req.Profile.Phone
 	u.Designation = req.Profile.Designation
 	u.ProfileURL = req.Profile.ProfileURL
-	if err = UpdateUserProfileTx(tx, u); err != nil {
-		return nil, err
-	}
 
-	existingCompany, err := companies.GetCompanyByUserID(u.UserID)
+	existingCompany, err := s.companyRepo.GetCompanyByUserID(u.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -84,9 +84,6 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 		Address: req.Company.Address,
 		Logo:    req.Company.Logo,
 	}
-	if err = companies.UpsertCompanyTx(tx, company); err != nil {
-		return nil, err
-	}
 
 	outlets := make([]companies.CompanyOutlet, 0, len(req.Company.Outlets))
 	for _, o := range req.Company.Outlets {
@@ -98,9 +95,6 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 			Address:   o.Address,
 		})
 	}
-	if err = companies.SaveCompanyOutletsService(tx, company.CompanyID, outlets); err != nil {
-		return nil, err
-	}
 
 	labelsToSave := make([]companies.Label, 0, len(req.Labels))
 	for _, l := range req.Labels {
@@ -125,15 +119,25 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 		})
 	}
 
-	blocked, err := companies.SaveCompanyLabelsService(tx, company.CompanyID, labelsToSave)
+	var blocked []companies.BlockedLabel
+	err = s.users.WithTx(func(tx *sql.Tx) error {
+		if err := s.users.UpdateUserProfileTx(tx, u); err != nil {
+			return err
+		}
+		if err := s.companyRepo.UpsertCompanyTx(tx, company); err != nil {
+			return err
+		}
+		if err := s.companies.SaveCompanyOutletsService(tx, company.CompanyID, outlets); err != nil {
+			return err
+		}
+		var err error
+		blocked, err = s.companies.SaveCompanyLabelsService(tx, company.CompanyID, labelsToSave)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	if err = tx.Commit(); err != nil {
-		return nil, err
-	}
-
 	resp.User.UserID = u.UserID
 	resp.User.Email = u.Email
 	resp.User.Name = u.Name
@@ -157,8 +161,8 @@ func SaveProfileService(authenticatedUserID string, req SaveProfileRequest) (*Sa
 	return resp, nil
 }
 
-func GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error) {
-	u, err := GetUserByID(authenticatedUserID)
+func (s *UserService) GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error) {
+	u, err := s.users.GetUserByID(authenticatedUserID)
 	if err != nil {
 		return nil, err
 	}
@@ -175,7 +179,7 @@ func GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error)
 	resp.User.Role = u.Role
 	resp.User.ProfileURL = u.ProfileURL
 
-	company, err := companies.GetCompanyByUserID(u.UserID)
+	company, err := s.companyRepo.GetCompanyByUserID(u.UserID)
 	if err != nil {
 		return nil, err
 	}
@@ -184,7 +188,7 @@ func GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error)
 		resp.Company.Name = company.Name
 		resp.Company.Address = company.Address
 		resp.Company.Logo = company.Logo
-		outs, err := companies.ListCompanyOutlets(company.CompanyID)
+		outs, err := s.companyRepo.ListCompanyOutlets(company.CompanyID)
 		if err != nil {
 			return nil, err
 		}
@@ -196,7 +200,7 @@ func GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error)
 			}{ID: o.ID, Name: o.Name, Address: o.Address})
 		}
 
-		companyLabels, err := companies.GetLabelsByCompanyID(company.CompanyID)
+		companyLabels, err := s.companyRepo.GetLabelsByCompanyID(company.CompanyID)
 		if err != nil {
 			return nil, err
 		}
@@ -211,16 +215,16 @@ func GetProfileService(authenticatedUserID string) (*SaveProfileResponse, error)
 	return resp, nil
 }
 
-func GetAllUserService() ([]User, error) {
-	return GetAllUsers()
+func (s *UserService) GetAllUserService() ([]User, error) {
+	return s.users.GetAllUsers()
 }
 
-func CreateUserByAdminService(req CreateUserRequest) (*User, error) {
+func (s *UserService) CreateUserByAdminService(req CreateUserRequest) (*User, error) {
     if req.Role != "printing" && req.Role != "plant" {
         return nil, errors.New("invalid role type")
     }
 
-    existing, err := GetUserByEmail(req.Email)
+    existing, err := s.users.GetUserByEmail(req.Email)
     if err != nil {
         return nil, err
     }
@@ -235,58 +239,81 @@ func CreateUserByAdminService(req CreateUserRequest) (*User, error) {
         ProfileURL:  "",
     }
 
-    if err := InsertUser(user); err != nil {
+    if err := s.users.InsertUser(user); err != nil {
         return nil, fmt.Errorf("failed to create user: %w", err)
     }
 
     return user, nil
 }
 
+// CreateAdminService creates a new admin account. Admins cannot be created
+// over HTTP; this is used by the enerzyctl CLI.
+func (s *UserService) CreateAdminService(email string) (*User, error) {
+    if email == "" {
+        return nil, errors.New("email is required")
+    }
 
+    existing, err := s.users.GetUserByEmail(email)
+    if err != nil {
+        return nil, err
+    }
+    if existing != nil {
+        return nil, fmt.Errorf("user with email %s already exists", email)
+    }
 
-func SubmitEnquiryService(req SubmitEnquiryRequest) error {
-    if req.Name == "" || req.Phone == "" || req.City == "" {
-        return errors.New("missing required enquiry fields")
+    user := &User{
+        UserID: uuid.New().String(),
+        Email:  email,
+        Role:   "admin",
+    }
+    if err := s.users.InsertUser(user); err != nil {
+        return nil, fmt.Errorf("failed to create admin: %w", err)
     }
+    return user, nil
+}
 
-    adminEmail := "enerzyflow@gmail.com" 
+// PromoteToAdminService gives an existing user the admin role.
+func (s *UserService) PromoteToAdminService(email string) (*User, error) {
+    u, err := s.users.GetUserByEmail(email)
+    if err != nil {
+        return nil, err
+    }
+    if u == nil {
+        return nil, fmt.Errorf("user with email %s not found", email)
+    }
+    if u.Role == "admin" {
+        return u, nil
+    }
 
-    htmlBody := fmt.Sprintf(`
-        <h3>New Enquiry Received</h3>
-        <p><b>Name:</b> %s</p>
-        <p><b>Phone:</b> %s</p>
-        <p><b>City:</b> %s</p>
-        <p><b>Logistic Supports:</b> %s</p>
-    `,
-        req.Name,
-        req.Phone,
-        req.City,
-        req.LogisticSupports,
-    )
-
-    textBody := fmt.Sprintf(
-        "New Enquiry\nName: %s\nPhone: %s\nCity: %s\nLogistic Supports: %s",
-        req.Name,
-        req.Phone,
-        req.City,
-        req.LogisticSupports,
-    )
-
-    return sendEnquiryEmail(adminEmail, htmlBody, textBody)
+    if err := s.users.UpdateUserRole(u.UserID, "admin"); err != nil {
+        return nil, err
+    }
+    u.Role = "admin"
+    return u, nil
 }
 
+func (s *UserService) SubmitEnquiryService(req SubmitEnquiryRequest) error {
+    if req.Name == "" || req.Phone == "" || req.City == "" {
+        return errors.New("missing required enquiry fields")
+    }
 
-func sendEnquiryEmail(toEmail, htmlBody, textBody string) error {
-    client := resend.NewClient(os.Getenv("RESEND_API_KEY"))
+    adminEmail := "enerzyflow@gmail.com" 
 
-    params := &resend.SendEmailRequest{
-        From:    "EnerzyFlow <no-reply@enerzyflow.com>",
-        To:      []string{toEmail},
-        Subject: "New Enquiry Submitted",
-        Html:    htmlBody,
-        Text:    textBody,
+    msg, err := mailer.Render(mailer.TemplateEnquiry, mailer.DefaultLocale, mailer.EnquiryData{
+        Name:             req.Name,
+        Phone:            req.Phone,
+        City:             req.City,
+        LogisticSupports: req.LogisticSupports,
+    })
+    if err != nil {
+        return err
     }
 
-    _, err := client.Emails.Send(params)
-    return err
+    return sendEnquiryEmail(adminEmail, msg)
+}
+
+
+func sendEnquiryEmail(toEmail string, msg mailer.Message) error {
+    msg.To = []string{toEmail}
+    return outbox.Enqueue(msg)
 }
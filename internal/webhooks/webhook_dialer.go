@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var errBlockedDestination = errors.New("webhook URL must not point at a loopback, private or link-local address")
+
+// sharedAddressSpace is the carrier-grade NAT range, which some clouds also
+// use for metadata services.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedIP reports whether ip belongs to this host or an internal network,
+// which subscribers must not be able to reach through webhook deliveries.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		sharedAddressSpace.Contains(ip)
+}
+
+// checkHost rejects hosts that are obviously internal when a subscription is
+// saved. Names are only resolved when dialing, see dialControl.
+func checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return errBlockedDestination
+	}
+	if ip := net.ParseIP(host); ip != nil && blockedIP(ip) {
+		return errBlockedDestination
+	}
+	return nil
+}
+
+// dialControl runs after the name is resolved and before each connection is
+// made, including those for redirects, so a hostname that resolves, or is
+// later re-pointed, to an internal address is refused as well.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blockedIP(ip) {
+		return fmt.Errorf("dial %s: %w", address, errBlockedDestination)
+	}
+	return nil
+}
+
+func newHTTPClient() *http.Client {
+	dialer := &net.Dialer{Timeout: requestTimeout, KeepAlive: 30 * time.Second, Control: dialControl}
+	return &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			// no proxy: the address check must apply to the endpoint itself
+			Proxy: nil,
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialer.DialContext(ctx, network, addr)
+			},
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: requestTimeout,
+		},
+	}
+}
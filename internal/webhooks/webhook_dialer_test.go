@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateURLRejectsInternalHosts(t *testing.T) {
+	for _, raw := range []string{
+		"http://localhost:8080/hook",
+		"http://api.localhost/hook",
+		"http://127.0.0.1/hook",
+		"http://10.1.2.3/hook",
+		"http://192.168.0.10/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://100.100.100.200/",
+		"http://[::1]/hook",
+		"http://[fe80::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		if err := validateURL(raw); !errors.Is(err, errBlockedDestination) {
+			t.Errorf("validateURL(%s) = %v, want errBlockedDestination", raw, err)
+		}
+	}
+	for _, raw := range []string{"https://erp.example.com/hooks", "http://203.0.113.7:8443/in"} {
+		if err := validateURL(raw); err != nil {
+			t.Errorf("validateURL(%s) = %v", raw, err)
+		}
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	resp, err := newHTTPClient().Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	if !errors.Is(err, errBlockedDestination) {
+		t.Fatalf("error = %v, want errBlockedDestination", err)
+	}
+}
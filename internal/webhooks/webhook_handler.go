@@ -0,0 +1,141 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CreateSubscriptionHandler(c *gin.Context) {
+	var req CreateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	s, err := CreateSubscriptionService(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "webhook subscription created; store the secret, it is not shown again",
+		"subscription": s,
+	})
+}
+
+func ListSubscriptionsHandler(c *gin.Context) {
+	subs, err := ListSubscriptionsService()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscriptions": subs, "events": Events})
+}
+
+func GetSubscriptionHandler(c *gin.Context) {
+	s, err := GetSubscriptionService(c.Param("id"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscription": s})
+}
+
+func UpdateSubscriptionHandler(c *gin.Context) {
+	var req UpdateSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body: " + err.Error()})
+		return
+	}
+
+	s, err := UpdateSubscriptionService(c.Param("id"), req)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook subscription updated", "subscription": s})
+}
+
+func DeleteSubscriptionHandler(c *gin.Context) {
+	if err := DeleteSubscriptionService(c.Param("id")); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "webhook subscription deleted"})
+}
+
+func ListDeliveriesHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	deliveries, total, err := ListDeliveriesService(c.Param("id"), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deliveries": deliveries,
+		"count":      total,
+	})
+}
+
+func GetDeliveryHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	d, err := GetDelivery(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if d == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": ErrDeliveryNotFound.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"delivery": d})
+}
+
+func RedeliverHandler(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	if err := RedeliverService(id); err != nil {
+		respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "delivery queued for redelivery"})
+}
+
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound), errors.Is(err, ErrDeliveryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrNotRedeliverable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
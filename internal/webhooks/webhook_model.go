@@ -0,0 +1,62 @@
+package webhooks
+
+import "time"
+
+const (
+	StatusPending    = "pending"
+	StatusDelivering = "delivering"
+	StatusDelivered  = "delivered"
+	StatusDead       = "dead"
+)
+
+// AllEvents subscribes to every event type.
+const AllEvents = "*"
+
+type Subscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events"`
+	Description string    `json:"description,omitempty"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type Delivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Payload is the JSON body POSTed to subscribers.
+type Payload struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type CreateSubscriptionRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Secret      string   `json:"secret"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+}
+
+type UpdateSubscriptionRequest struct {
+	URL         *string   `json:"url"`
+	Secret      *string   `json:"secret"`
+	Events      *[]string `json:"events"`
+	Description *string   `json:"description"`
+	Active      *bool     `json:"active"`
+}
@@ -0,0 +1,226 @@
+package webhooks
+
+import (
+	"database/sql"
+	"enerzyflow_backend/internal/db"
+	"strings"
+	"time"
+)
+
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+const subscriptionColumns = `id, url, secret, events, COALESCE(description, ''), active, created_at, updated_at`
+
+func scanSubscription(row interface{ Scan(...interface{}) error }) (*Subscription, error) {
+	var s Subscription
+	var events string
+	if err := row.Scan(&s.ID, &s.URL, &s.Secret, &events, &s.Description, &s.Active, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return nil, err
+	}
+	s.Events = strings.Split(events, ",")
+	return &s, nil
+}
+
+func InsertSubscription(s *Subscription) error {
+	return db.DB.QueryRow(`
+		INSERT INTO webhook_subscriptions (id, url, secret, events, description, active, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING created_at, updated_at
+	`, s.ID, s.URL, s.Secret, strings.Join(s.Events, ","), s.Description, s.Active).Scan(&s.CreatedAt, &s.UpdatedAt)
+}
+
+func UpdateSubscription(s *Subscription) error {
+	return db.DB.QueryRow(`
+		UPDATE webhook_subscriptions
+		SET url = $2, secret = $3, events = $4, description = $5, active = $6, updated_at = NOW()
+		WHERE id = $1
+		RETURNING updated_at
+	`, s.ID, s.URL, s.Secret, strings.Join(s.Events, ","), s.Description, s.Active).Scan(&s.UpdatedAt)
+}
+
+func DeleteSubscription(id string) (bool, error) {
+	res, err := db.DB.Exec(`DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func GetSubscription(id string) (*Subscription, error) {
+	s, err := scanSubscription(db.DB.QueryRow(`SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+func ListSubscriptions() ([]Subscription, error) {
+	return querySubscriptions(db.DB, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions ORDER BY created_at DESC`)
+}
+
+func listActiveSubscriptions(q queryer) ([]Subscription, error) {
+	return querySubscriptions(q, `SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE active`)
+}
+
+func querySubscriptions(q queryer, query string) ([]Subscription, error) {
+	rows, err := q.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Subscription
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *s)
+	}
+	return out, rows.Err()
+}
+
+func insertDelivery(ex execer, subscriptionID, eventID, eventType string, payload []byte) error {
+	_, err := ex.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, 'pending', 0, NOW(), NOW(), NOW())
+	`, subscriptionID, eventID, eventType, string(payload))
+	return err
+}
+
+// claimedDelivery carries what the dispatcher needs to send one delivery.
+type claimedDelivery struct {
+	Delivery
+	URL    string
+	Secret string
+}
+
+// claimDue marks up to limit due deliveries as delivering and returns them
+// with their subscription's URL and secret. Deliveries of deleted or
+// deactivated subscriptions are left alone.
+func claimDue(limit int, lease time.Duration) ([]claimedDelivery, error) {
+	rows, err := db.DB.Query(`
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			INNER JOIN webhook_subscriptions s ON s.id = d.subscription_id AND s.active
+			WHERE (d.status = 'pending' AND d.next_attempt_at <= NOW())
+			   OR (d.status = 'delivering' AND d.locked_until < NOW())
+			ORDER BY d.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET status = 'delivering', locked_until = $2, updated_at = NOW()
+		FROM due, webhook_subscriptions s
+		WHERE d.id = due.id AND s.id = d.subscription_id
+		RETURNING d.id, d.subscription_id, d.event_id, d.event_type, d.payload, d.attempts, s.url, s.secret
+	`, limit, time.Now().Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []claimedDelivery
+	for rows.Next() {
+		var d claimedDelivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Attempts, &d.URL, &d.Secret); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func markDelivered(id int64, status int) error {
+	_, err := db.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, response_status = $2,
+		    last_error = NULL, locked_until = NULL, delivered_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, id, status)
+	return err
+}
+
+func markFailed(id int64, status *int, lastErr string, next time.Time, dead bool) error {
+	newStatus := StatusPending
+	if dead {
+		newStatus = StatusDead
+	}
+	_, err := db.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, response_status = $3, last_error = $4,
+		    next_attempt_at = $5, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`, id, newStatus, status, lastErr, next)
+	return err
+}
+
+func ListDeliveries(subscriptionID, status string, limit, offset int) ([]Delivery, int, error) {
+	rows, err := db.DB.Query(`
+		SELECT id, subscription_id, event_id, event_type, status, attempts, next_attempt_at, response_status,
+		       COALESCE(last_error, ''), created_at, delivered_at, COUNT(*) OVER() AS total_count
+		FROM webhook_deliveries
+		WHERE subscription_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, subscriptionID, status, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var (
+		out   []Delivery
+		total int
+	)
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt, &total); err != nil {
+			return nil, 0, err
+		}
+		out = append(out, d)
+	}
+	return out, total, rows.Err()
+}
+
+func GetDelivery(id int64) (*Delivery, error) {
+	var d Delivery
+	err := db.DB.QueryRow(`
+		SELECT id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, response_status,
+		       COALESCE(last_error, ''), created_at, delivered_at
+		FROM webhook_deliveries
+		WHERE id = $1
+	`, id).Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &d, nil
+}
+
+// requeueDelivery puts a delivered or dead delivery back into the queue with
+// a fresh attempt budget. The payload (and event id) stay the same so
+// receivers can deduplicate.
+func requeueDelivery(id int64) (bool, error) {
+	res, err := db.DB.Exec(`
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), locked_until = NULL, updated_at = NOW()
+		WHERE id = $1 AND status IN ('dead', 'delivered')
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
@@ -0,0 +1,398 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"enerzyflow_backend/internal/orders"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttempts     = 8
+	backoffBase     = 30 * time.Second
+	backoffMax      = time.Hour
+	claimBatch      = 20
+	deliveringLease = 2 * time.Minute
+	requestTimeout  = 10 * time.Second
+	// maxResponseBody is how much of a response is drained so that the
+	// connection can be reused; bodies are never stored.
+	maxResponseBody = 1024
+)
+
+var (
+	ErrNotRedeliverable     = errors.New("only delivered or dead deliveries can be redelivered")
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+)
+
+// Events lists the event types a subscription can filter on.
+var Events = []string{
+	orders.EventOrderCreated,
+	orders.EventStatusChanged,
+	orders.EventPaymentUpdated,
+	orders.EventInvoiceUploaded,
+	orders.EventAssignmentChanged,
+	orders.EventSLAEscalated,
+	orders.EventDeliveryRescheduled,
+}
+
+var httpClient = newHTTPClient()
+
+var wake = make(chan struct{}, 1)
+
+func notifyDispatcher() {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
+
+// Register subscribes webhooks to order events. Call once at startup.
+func Register() {
+	orders.RegisterEventHook(OnOrderEvent)
+	// deliveries queued by OnOrderEvent become visible when the change commits
+	orders.RegisterCommitHook(notifyDispatcher)
+}
+
+// OnOrderEvent queues one delivery per matching active subscription inside
+// the transaction that raised the event.
+func OnOrderEvent(tx *sql.Tx, ev orders.OrderEvent) error {
+	if !isWebhookEvent(ev.Type) {
+		return nil
+	}
+
+	subs, err := listActiveSubscriptions(tx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	eventID := uuid.New().String()
+	for _, s := range subs {
+		if !s.wants(ev.Type) {
+			continue
+		}
+		if payload == nil {
+			payload, err = json.Marshal(Payload{ID: eventID, Type: ev.Type, CreatedAt: ev.OccurredAt, Data: ev})
+			if err != nil {
+				return err
+			}
+		}
+		if err := insertDelivery(tx, s.ID, eventID, ev.Type, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isWebhookEvent(eventType string) bool {
+	for _, e := range Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Subscription) wants(eventType string) bool {
+	for _, e := range s.Events {
+		if e == AllEvents || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the X-Enerzyflow-Signature value for body sent at ts:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+func Sign(secret string, ts time.Time, body []byte) string {
+	t := strconv.FormatInt(ts.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func backoff(attempts int) time.Duration {
+	d := backoffBase
+	for i := 1; i < attempts && d < backoffMax; i++ {
+		d *= 2
+	}
+	if d > backoffMax {
+		d = backoffMax
+	}
+	return d
+}
+
+// StartDispatcher sends queued deliveries every interval (and immediately
+// after new ones are queued) until ctx is cancelled.
+func StartDispatcher(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			dispatchDue()
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-wake:
+			}
+		}
+	}()
+}
+
+func dispatchDue() {
+	for {
+		batch, err := claimDue(claimBatch, deliveringLease)
+		if err != nil {
+			log.Printf("webhooks: claim failed: %v", err)
+			return
+		}
+		for _, d := range batch {
+			deliver(d)
+		}
+		if len(batch) < claimBatch {
+			return
+		}
+	}
+}
+
+func deliver(d claimedDelivery) {
+	status, err := post(d)
+	if err == nil {
+		if err := markDelivered(d.ID, *status); err != nil {
+			log.Printf("webhooks: mark delivered %d: %v", d.ID, err)
+		}
+		return
+	}
+
+	attempts := d.Attempts + 1
+	dead := attempts >= maxAttempts
+	if dead {
+		log.Printf("webhooks: delivery %d dead-lettered after %d attempts: %v", d.ID, attempts, err)
+	} else {
+		log.Printf("webhooks: delivery %d attempt %d failed: %v", d.ID, attempts, err)
+	}
+	if err := markFailed(d.ID, status, err.Error(), time.Now().Add(backoff(attempts)), dead); err != nil {
+		log.Printf("webhooks: mark failed %d: %v", d.ID, err)
+	}
+}
+
+// post sends one delivery. Any 2xx response counts as delivered. Only the
+// status code is kept: the body could echo back whatever the endpoint can
+// reach.
+func post(d claimedDelivery) (*int, error) {
+	payload := []byte(d.Payload)
+	req, err := http.NewRequest(http.MethodPost, d.URL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "EnerzyFlow-Webhooks/1.0")
+	req.Header.Set("X-Enerzyflow-Event", d.EventType)
+	req.Header.Set("X-Enerzyflow-Event-Id", d.EventID)
+	req.Header.Set("X-Enerzyflow-Delivery", strconv.FormatInt(d.ID, 10))
+	req.Header.Set("X-Enerzyflow-Signature", Sign(d.Secret, time.Now(), payload))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	status := resp.StatusCode
+	if status < 200 || status > 299 {
+		return &status, fmt.Errorf("endpoint responded %d", status)
+	}
+	return &status, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("url must be an absolute http(s) URL")
+	}
+	return checkHost(u.Hostname())
+}
+
+func normalizeEvents(events []string) ([]string, error) {
+	if len(events) == 0 {
+		return nil, errors.New("at least one event is required")
+	}
+	seen := map[string]bool{}
+	var out []string
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if e != AllEvents && !isWebhookEvent(e) {
+			return nil, fmt.Errorf("unknown event '%s'", e)
+		}
+		if !seen[e] {
+			seen[e] = true
+			out = append(out, e)
+		}
+	}
+	return out, nil
+}
+
+// CreateSubscriptionService stores a new subscription. The returned value
+// includes the secret, which is not shown again.
+func CreateSubscriptionService(req CreateSubscriptionRequest) (*Subscription, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = newSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	s := &Subscription{
+		ID:          uuid.New().String(),
+		URL:         req.URL,
+		Secret:      secret,
+		Events:      events,
+		Description: req.Description,
+		Active:      true,
+	}
+	if err := InsertSubscription(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func UpdateSubscriptionService(id string, req UpdateSubscriptionRequest) (*Subscription, error) {
+	s, err := GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+
+	if req.URL != nil {
+		if err := validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		s.URL = *req.URL
+	}
+	if req.Events != nil {
+		if s.Events, err = normalizeEvents(*req.Events); err != nil {
+			return nil, err
+		}
+	}
+	if req.Secret != nil {
+		if *req.Secret == "" {
+			return nil, errors.New("secret cannot be empty")
+		}
+		s.Secret = *req.Secret
+	}
+	if req.Description != nil {
+		s.Description = *req.Description
+	}
+	if req.Active != nil {
+		s.Active = *req.Active
+	}
+
+	if err := UpdateSubscription(s); err != nil {
+		return nil, err
+	}
+	s.Secret = ""
+	if s.Active {
+		notifyDispatcher()
+	}
+	return s, nil
+}
+
+func ListSubscriptionsService() ([]Subscription, error) {
+	subs, err := ListSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+	return subs, nil
+}
+
+func GetSubscriptionService(id string) (*Subscription, error) {
+	s, err := GetSubscription(id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, ErrSubscriptionNotFound
+	}
+	s.Secret = ""
+	return s, nil
+}
+
+func DeleteSubscriptionService(id string) error {
+	ok, err := DeleteSubscription(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func ListDeliveriesService(subscriptionID, status string, limit, offset int) ([]Delivery, int, error) {
+	switch status {
+	case "", StatusPending, StatusDelivering, StatusDelivered, StatusDead:
+	default:
+		return nil, 0, errors.New("invalid status filter")
+	}
+	return ListDeliveries(subscriptionID, status, limit, offset)
+}
+
+func RedeliverService(id int64) error {
+	d, err := GetDelivery(id)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return ErrDeliveryNotFound
+	}
+	ok, err := requeueDelivery(id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotRedeliverable
+	}
+	notifyDispatcher()
+	return nil
+}
@@ -0,0 +1,839 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"enerzyflow_backend/internal/auth"
+	"enerzyflow_backend/internal/container"
+	"enerzyflow_backend/internal/db"
+	"enerzyflow_backend/internal/events"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/notifications"
+	"enerzyflow_backend/internal/testdb"
+	"enerzyflow_backend/internal/webhooks"
+	"enerzyflow_backend/routes"
+	"enerzyflow_backend/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	stop, err := testdb.Start()
+	if err != nil {
+		log.Fatalf("testdb: %v", err)
+	}
+
+	os.Setenv("JWT_SECRET", "integration-test-secret")
+	keys, err := utils.LoadKeySetFromEnv()
+	if err != nil {
+		log.Fatalf("jwt keys: %v", err)
+	}
+	utils.SetKeySet(keys)
+	utils.SetSessionValidator(auth.IsSessionActive)
+	mailer.SetDefault(mailer.NewLogMailer())
+	events.SetBus(events.NewMemoryBus())
+	auth.SetOTPStore(auth.NewMemoryOTPStore())
+	auth.SetThrottleStore(auth.NewMemoryThrottleStore())
+	notifications.Register()
+	webhooks.Register()
+
+	code := m.Run()
+	stop()
+	os.Exit(code)
+}
+
+// harness is one test's server over the fixture database.
+type harness struct {
+	t      *testing.T
+	app    *container.Container
+	srv    *httptest.Server
+	tokens map[string]string
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	testdb.Require(t)
+	if err := testdb.Reset(context.Background()); err != nil {
+		t.Fatalf("reset fixtures: %v", err)
+	}
+
+	app := container.New(db.DB)
+	auth.SetUserRepository(app.UserRepo)
+
+	r := gin.New()
+	routes.RegisterAllRoutes(r, app)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	h := &harness{t: t, app: app, srv: srv, tokens: map[string]string{}}
+	for _, id := range []string{testdb.AdminID, testdb.PrintingID, testdb.SecondPrintingID, testdb.PlantID, testdb.OwnerID, testdb.OtherID} {
+		u, err := app.UserRepo.GetUserByID(id)
+		if err != nil || u == nil {
+			t.Fatalf("load fixture user %s: %v", id, err)
+		}
+		tokens, err := auth.StartSession(u, "integration-test", "127.0.0.1")
+		if err != nil {
+			t.Fatalf("start session for %s: %v", u.Email, err)
+		}
+		h.tokens[id] = tokens.AccessToken
+	}
+	return h
+}
+
+// do sends body as JSON on behalf of userID and decodes the JSON response.
+func (h *harness) do(userID, method, path string, body interface{}) (int, map[string]interface{}) {
+	h.t.Helper()
+	status, _, out := h.doWithHeaders(userID, method, path, body, nil)
+	return status, out
+}
+
+func (h *harness) doWithHeaders(userID, method, path string, body interface{}, headers map[string]string) (int, http.Header, map[string]interface{}) {
+	h.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			h.t.Fatal(err)
+		}
+		reader = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, h.srv.URL+path, reader)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+h.tokens[userID])
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.srv.Client().Do(req)
+	if err != nil {
+		h.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	out := map[string]interface{}{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil && err != io.EOF {
+		h.t.Fatalf("%s %s: decode response: %v", method, path, err)
+	}
+	return resp.StatusCode, resp.Header, out
+}
+
+// mustDo is do, failing the test unless the response has status want.
+func (h *harness) mustDo(want int, userID, method, path string, body interface{}) map[string]interface{} {
+	h.t.Helper()
+	status, out := h.do(userID, method, path, body)
+	if status != want {
+		h.t.Fatalf("%s %s: got %d, want %d: %v", method, path, status, want, out)
+	}
+	return out
+}
+
+func (h *harness) setStatus(want int, userID, orderID, status, reason string) map[string]interface{} {
+	h.t.Helper()
+	return h.mustDo(want, userID, http.MethodPut, "/orders/"+orderID+"/status", gin.H{"status": status, "reason": reason})
+}
+
+func (h *harness) orderDetail(orderID string) map[string]interface{} {
+	h.t.Helper()
+	out := h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/orders/"+orderID+"/detail", nil)
+	detail, ok := out["order"].(map[string]interface{})
+	if !ok {
+		h.t.Fatalf("order detail: unexpected body %v", out)
+	}
+	return detail
+}
+
+func (h *harness) expectStatus(orderID, want string) {
+	h.t.Helper()
+	if got := h.orderDetail(orderID)["status"]; got != want {
+		h.t.Fatalf("order %s: status %v, want %s", orderID, got, want)
+	}
+}
+
+func (h *harness) queueContains(userID, orderID string) bool {
+	h.t.Helper()
+	out := h.mustDo(http.StatusOK, userID, http.MethodGet, "/orders/get-all-orders?limit=50", nil)
+	list, _ := out["orders"].([]interface{})
+	for _, item := range list {
+		if o, ok := item.(map[string]interface{}); ok && o["order_id"] == orderID {
+			return true
+		}
+	}
+	return false
+}
+
+func TestOrderLifecycleAcrossRoles(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderPaymentUploaded
+
+	if h.queueContains(testdb.PrintingID, order) {
+		t.Fatal("printing sees an order whose payment is not verified")
+	}
+	h.setStatus(http.StatusBadRequest, testdb.AdminID, order, "completed", "")
+
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, "/orders/"+order+"/payment", gin.H{"status": "payment_verified"})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, "/orders/"+order+"/label", gin.H{
+		"no_of_sheets":     42,
+		"cutting_type":     "die_cut",
+		"labels_per_sheet": 12,
+	})
+	if !h.queueContains(testdb.PrintingID, order) {
+		t.Fatal("verified order with label details is missing from the printing queue")
+	}
+	if h.queueContains(testdb.PlantID, order) {
+		t.Fatal("plant sees an order before printing finished")
+	}
+
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "accepted", "")
+	h.expectStatus(order, "printing")
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, order, "dispatched", "")
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "ready_for_plant", "")
+	h.expectStatus(order, "ready_for_plant")
+
+	if !h.queueContains(testdb.PlantID, order) {
+		t.Fatal("order ready for plant is missing from the plant queue")
+	}
+	h.setStatus(http.StatusOK, testdb.PlantID, order, "plant_processing", "")
+	h.expectStatus(order, "plant_processing")
+	h.setStatus(http.StatusOK, testdb.PlantID, order, "dispatched", "")
+	h.expectStatus(order, "dispatched")
+
+	h.setStatus(http.StatusBadRequest, testdb.AdminID, order, "declined", "too late")
+	h.setStatus(http.StatusOK, testdb.AdminID, order, "completed", "")
+	h.expectStatus(order, "completed")
+	h.setStatus(http.StatusBadRequest, testdb.AdminID, order, "dispatched", "")
+
+	out := h.mustDo(http.StatusOK, testdb.OwnerID, http.MethodGet, "/orders/"+order+"/tracking", nil)
+	history, _ := out["history"].([]interface{})
+	want := []string{"placed", "payment_verified", "printing", "ready_for_plant", "plant_processing", "dispatched", "completed"}
+	if len(history) != len(want) {
+		t.Fatalf("tracking: got %d entries, want %d: %v", len(history), len(want), history)
+	}
+	for i, entry := range history {
+		if got := entry.(map[string]interface{})["status"]; got != want[i] {
+			t.Fatalf("tracking[%d]: got %v, want %s", i, got, want[i])
+		}
+	}
+
+	assignments, _ := h.orderDetail(order)["assignments"].([]interface{})
+	if len(assignments) != 2 {
+		t.Fatalf("assignments: got %d, want 2 (printing and plant)", len(assignments))
+	}
+
+	out = h.mustDo(http.StatusOK, testdb.OwnerID, http.MethodGet, "/notifications?unread=true", nil)
+	if unread, _ := out["unread"].(float64); unread == 0 {
+		t.Fatal("owner has no unread notifications after the lifecycle")
+	}
+}
+
+func TestPrintingDeclineRequiresReason(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, order, "declined", "")
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "declined", "artwork is blurry")
+
+	detail := h.orderDetail(order)
+	if detail["status"] != "declined" || detail["decline_reason"] != "artwork is blurry" {
+		t.Fatalf("declined order: status %v, reason %v", detail["status"], detail["decline_reason"])
+	}
+	if h.queueContains(testdb.PrintingID, order) {
+		t.Fatal("unassigned declined order still shows in the printing queue")
+	}
+}
+
+func TestAdminDeclineIsTerminal(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderPaymentPending
+
+	h.setStatus(http.StatusBadRequest, testdb.AdminID, order, "declined", `  ""  `)
+	h.setStatus(http.StatusOK, testdb.AdminID, order, "declined", ` "duplicate order" `)
+
+	if reason := h.orderDetail(order)["decline_reason"]; reason != "duplicate order" {
+		t.Fatalf("decline reason %q, want it trimmed", reason)
+	}
+	h.setStatus(http.StatusBadRequest, testdb.AdminID, order, "completed", "")
+}
+
+func TestRolesCannotSkipTheirStage(t *testing.T) {
+	h := newHarness(t)
+
+	h.setStatus(http.StatusBadRequest, testdb.PlantID, testdb.OrderReadyForPrinting, "plant_processing", "")
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, testdb.OrderPaymentPending, "accepted", "")
+	h.setStatus(http.StatusBadRequest, testdb.OtherID, testdb.OrderReadyForPrinting, "completed", "")
+	h.expectStatus(testdb.OrderReadyForPrinting, "placed")
+
+	h.mustDo(http.StatusForbidden, testdb.PrintingID, http.MethodPut, "/orders/"+testdb.OrderPaymentUploaded+"/payment", gin.H{"status": "payment_verified"})
+}
+
+func TestAllowedTransitions(t *testing.T) {
+	h := newHarness(t)
+	path := "/orders/" + testdb.OrderReadyForPrinting + "/allowed-transitions"
+
+	actions := func(userID string) map[string]bool {
+		out := h.mustDo(http.StatusOK, userID, http.MethodGet, path, nil)
+		list, _ := out["transitions"].([]interface{})
+		got := map[string]bool{}
+		for _, item := range list {
+			tr := item.(map[string]interface{})
+			got[tr["action"].(string)] = tr["reason_required"].(bool)
+		}
+		return got
+	}
+
+	printing := actions(testdb.PrintingID)
+	if len(printing) != 2 || printing["accepted"] || !printing["declined"] {
+		t.Fatalf("printing transitions: %v", printing)
+	}
+	if plant := actions(testdb.PlantID); len(plant) != 0 {
+		t.Fatalf("plant transitions on a placed order: %v", plant)
+	}
+	if owner := actions(testdb.OtherID); len(owner) != 0 {
+		t.Fatalf("owner transitions: %v", owner)
+	}
+	h.mustDo(http.StatusNotFound, testdb.OwnerID, http.MethodGet, path, nil)
+
+	h.setStatus(http.StatusOK, testdb.PrintingID, testdb.OrderReadyForPrinting, "accepted", "")
+	if printing := actions(testdb.PrintingID); len(printing) != 1 || printing["ready_for_plant"] {
+		t.Fatalf("printing transitions after accepting: %v", printing)
+	}
+}
+
+func TestStatusUpdateUsesIfMatch(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+	path := "/orders/" + order + "/status"
+
+	status, header, detail := h.doWithHeaders(testdb.AdminID, http.MethodGet, "/orders/"+order+"/detail", nil, nil)
+	etag := header.Get("ETag")
+	if status != http.StatusOK || etag == "" {
+		t.Fatalf("detail: status %d, ETag %q", status, etag)
+	}
+	version := detail["order"].(map[string]interface{})["version"]
+
+	status, header, out := h.doWithHeaders(testdb.PrintingID, http.MethodPut, path, gin.H{"status": "accepted"}, map[string]string{"If-Match": `"999"`})
+	if status != http.StatusConflict || header.Get("ETag") != etag || out["version"] != version {
+		t.Fatalf("stale If-Match: status %d, ETag %q, body %v", status, header.Get("ETag"), out)
+	}
+	h.expectStatus(order, "placed")
+
+	status, header, out = h.doWithHeaders(testdb.PrintingID, http.MethodPut, path, gin.H{"status": "accepted"}, map[string]string{"If-Match": etag})
+	next := header.Get("ETag")
+	if status != http.StatusOK || next == "" || next == etag {
+		t.Fatalf("current If-Match: status %d, ETag %q, body %v", status, next, out)
+	}
+
+	status, _, _ = h.doWithHeaders(testdb.PrintingID, http.MethodPut, path, gin.H{"status": "ready_for_plant"}, map[string]string{"If-Match": "not-a-version"})
+	if status != http.StatusBadRequest {
+		t.Fatalf("malformed If-Match: status %d", status)
+	}
+	h.doWithHeaders(testdb.PrintingID, http.MethodPut, path, gin.H{"status": "ready_for_plant"}, map[string]string{"If-Match": etag})
+	h.expectStatus(order, "printing")
+	status, _, out = h.doWithHeaders(testdb.PrintingID, http.MethodPut, path, gin.H{"status": "ready_for_plant"}, map[string]string{"If-Match": next})
+	if status != http.StatusOK {
+		t.Fatalf("second transition: status %d, body %v", status, out)
+	}
+}
+
+func TestConcurrentAcceptAssignsOnce(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+
+	const attempts = 5
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		statuses []int
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, _ := h.do(testdb.PrintingID, http.MethodPut, "/orders/"+order+"/status", gin.H{"status": "accepted"})
+			mu.Lock()
+			statuses = append(statuses, status)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	ok := 0
+	for _, status := range statuses {
+		if status == http.StatusOK {
+			ok++
+		}
+	}
+	if ok != 1 {
+		t.Fatalf("%d of %d concurrent accepts succeeded: %v", ok, attempts, statuses)
+	}
+	assignments, _ := h.orderDetail(order)["assignments"].([]interface{})
+	if len(assignments) != 1 {
+		t.Fatalf("assignments: got %d, want 1", len(assignments))
+	}
+}
+
+// claim calls POST /work/claim for userID and returns the status code and
+// the claimed order, if any.
+func (h *harness) claim(userID string) (int, string) {
+	h.t.Helper()
+	status, out := h.do(userID, http.MethodPost, "/work/claim", nil)
+	claim, _ := out["claim"].(map[string]interface{})
+	orderID, _ := claim["order_id"].(string)
+	return status, orderID
+}
+
+// verifyForPrinting puts OrderPaymentUploaded into the printing pool next
+// to OrderReadyForPrinting, which was verified earlier.
+func (h *harness) verifyForPrinting() string {
+	h.t.Helper()
+	order := testdb.OrderPaymentUploaded
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, "/orders/"+order+"/payment", gin.H{"status": "payment_verified"})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, "/orders/"+order+"/label", gin.H{
+		"no_of_sheets":     10,
+		"cutting_type":     "die_cut",
+		"labels_per_sheet": 8,
+	})
+	return order
+}
+
+func TestWorkClaimLeasesOrder(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+
+	if status, got := h.claim(testdb.PrintingID); status != http.StatusOK || got != order {
+		t.Fatalf("first claim: status %d, order %q", status, got)
+	}
+	if status, got := h.claim(testdb.PrintingID); status != http.StatusOK || got != order {
+		t.Fatalf("repeated claim: status %d, order %q, want the same lease", status, got)
+	}
+	if status, got := h.claim(testdb.SecondPrintingID); status != http.StatusNotFound {
+		t.Fatalf("claim on an empty pool: status %d, order %q", status, got)
+	}
+	h.mustDo(http.StatusNotFound, testdb.PlantID, http.MethodPost, "/work/claim", nil)
+	h.mustDo(http.StatusForbidden, testdb.OwnerID, http.MethodPost, "/work/claim", nil)
+
+	h.setStatus(http.StatusBadRequest, testdb.SecondPrintingID, order, "accepted", "")
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "accepted", "")
+	if status, _ := h.claim(testdb.PrintingID); status != http.StatusNotFound {
+		t.Fatalf("claim after accepting: status %d, want the pool to be empty", status)
+	}
+}
+
+func TestWorkClaimLeaseExpires(t *testing.T) {
+	t.Setenv("WORK_LEASE_TTL", "200ms")
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+
+	if status, got := h.claim(testdb.PrintingID); status != http.StatusOK || got != order {
+		t.Fatalf("claim: status %d, order %q", status, got)
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if status, got := h.claim(testdb.SecondPrintingID); status != http.StatusOK || got != order {
+		t.Fatalf("claim after the lease expired: status %d, order %q", status, got)
+	}
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, order, "accepted", "")
+	h.setStatus(http.StatusOK, testdb.SecondPrintingID, order, "accepted", "")
+}
+
+func TestWorkClaimPriorityAndPins(t *testing.T) {
+	h := newHarness(t)
+	later := h.verifyForPrinting()
+	pinPath := "/admin/orders/" + later + "/pin"
+
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPut, pinPath, gin.H{"user_id": testdb.OwnerID})
+	h.mustDo(http.StatusForbidden, testdb.PrintingID, http.MethodPut, pinPath, gin.H{"user_id": testdb.PrintingID})
+	out := h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, pinPath, gin.H{"user_id": testdb.SecondPrintingID})
+	if pin, _ := out["pin"].(map[string]interface{}); pin["role"] != "printing" {
+		t.Fatalf("pin: %v", out)
+	}
+
+	if status, got := h.claim(testdb.PrintingID); status != http.StatusOK || got != testdb.OrderReadyForPrinting {
+		t.Fatalf("printing claim: status %d, order %q, want the earlier verified order", status, got)
+	}
+	if status, got := h.claim(testdb.SecondPrintingID); status != http.StatusOK || got != later {
+		t.Fatalf("pinned user's claim: status %d, order %q", status, got)
+	}
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, later, "accepted", "")
+
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodDelete, pinPath+"/printing", nil)
+	h.mustDo(http.StatusNotFound, testdb.AdminID, http.MethodDelete, pinPath+"/printing", nil)
+}
+
+func TestConcurrentClaimsGetDistinctOrders(t *testing.T) {
+	h := newHarness(t)
+	h.verifyForPrinting()
+
+	users := []string{testdb.PrintingID, testdb.SecondPrintingID}
+	claimed := make([]string, len(users))
+	var wg sync.WaitGroup
+	for i, userID := range users {
+		wg.Add(1)
+		go func(i int, userID string) {
+			defer wg.Done()
+			if status, got := h.claim(userID); status == http.StatusOK {
+				claimed[i] = got
+			}
+		}(i, userID)
+	}
+	wg.Wait()
+
+	if claimed[0] == "" || claimed[1] == "" || claimed[0] == claimed[1] {
+		t.Fatalf("concurrent claims: %v, want two different orders", claimed)
+	}
+}
+
+// workEveryDay makes every day a working day with the given cutoff, so
+// business-day deadlines are whole calendar days apart.
+func (h *harness) workEveryDay(cutoff string) {
+	h.t.Helper()
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, "/admin/calendars/default", gin.H{
+		"working_days": []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"},
+		"cutoff":       cutoff,
+	})
+}
+
+func TestAdminReassignExtendAndUnassign(t *testing.T) {
+	h := newHarness(t)
+	h.workEveryDay("17:00")
+	order := testdb.OrderReadyForPrinting
+	base := "/admin/orders/" + order + "/assignments/printing/"
+
+	h.mustDo(http.StatusConflict, testdb.AdminID, http.MethodPost, base+"reassign", gin.H{"user_id": testdb.SecondPrintingID, "reason": "machine down"})
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "accepted", "")
+
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPost, base+"reassign", gin.H{"user_id": testdb.SecondPrintingID})
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPost, base+"reassign", gin.H{"user_id": testdb.PlantID, "reason": "machine down"})
+	h.mustDo(http.StatusForbidden, testdb.PrintingID, http.MethodPost, base+"reassign", gin.H{"user_id": testdb.SecondPrintingID, "reason": "machine down"})
+	out := h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, base+"reassign", gin.H{"user_id": testdb.SecondPrintingID, "reason": "machine down"})
+	assignment := out["assignment"].(map[string]interface{})
+	if assignment["user_id"] != testdb.SecondPrintingID {
+		t.Fatalf("reassigned to %v", assignment["user_id"])
+	}
+
+	h.mustDo(http.StatusBadRequest, testdb.PrintingID, http.MethodPost, "/orders/"+order+"/comment", gin.H{"comment": "still on it"})
+	h.setStatus(http.StatusBadRequest, testdb.PrintingID, order, "ready_for_plant", "")
+	h.mustDo(http.StatusOK, testdb.SecondPrintingID, http.MethodPost, "/orders/"+order+"/comment", gin.H{"comment": "taking over"})
+
+	before, _ := time.Parse(time.RFC3339, assignment["deadline"].(string))
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, base+"extend", gin.H{"days": 2, "reason": "ink delayed"})
+	after, _ := time.Parse(time.RFC3339, out["assignment"].(map[string]interface{})["deadline"].(string))
+	if got := after.Sub(before); got != 48*time.Hour {
+		t.Fatalf("deadline moved by %v, want 48h", got)
+	}
+
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPost, base+"unassign", gin.H{})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, base+"unassign", gin.H{"reason": "customer asked to wait"})
+	h.expectStatus(order, "placed")
+	if assignments, _ := h.orderDetail(order)["assignments"].([]interface{}); len(assignments) != 0 {
+		t.Fatalf("assignments after unassign: %v", assignments)
+	}
+	if status, got := h.claim(testdb.PrintingID); status != http.StatusOK || got != order {
+		t.Fatalf("claim after unassign: status %d, order %q", status, got)
+	}
+
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/orders/"+order+"/assignments/history", nil)
+	history, _ := out["history"].([]interface{})
+	want := []string{"assigned", "reassigned", "deadline_extended", "unassigned"}
+	if len(history) != len(want) {
+		t.Fatalf("assignment history: got %d entries, want %d: %v", len(history), len(want), history)
+	}
+	for i, entry := range history {
+		e := entry.(map[string]interface{})
+		if e["action"] != want[i] {
+			t.Fatalf("history[%d]: action %v, want %s", i, e["action"], want[i])
+		}
+	}
+	if e := history[1].(map[string]interface{}); e["from_user_id"] != testdb.PrintingID || e["to_user_id"] != testdb.SecondPrintingID || e["reason"] != "machine down" {
+		t.Fatalf("reassign entry: %v", e)
+	}
+}
+
+func TestSLABreachesAndEscalation(t *testing.T) {
+	t.Setenv("SLA_WARNING_WINDOW", "72h")
+	h := newHarness(t)
+	h.workEveryDay("23:59")
+	order := testdb.OrderReadyForPrinting
+
+	h.mustDo(http.StatusForbidden, testdb.PrintingID, http.MethodGet, "/admin/sla/breaches", nil)
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodGet, "/admin/sla/breaches?level=late", nil)
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "accepted", "")
+
+	out := h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/sla/breaches", nil)
+	breaches, _ := out["breaches"].([]interface{})
+	if len(breaches) != 1 {
+		t.Fatalf("breaches: got %d, want 1: %v", len(breaches), breaches)
+	}
+	b := breaches[0].(map[string]interface{})
+	if b["order_id"] != order || b["kind"] != "assignment_deadline" || b["level"] != "at_risk" ||
+		b["assignee_id"] != testdb.PrintingID || b["bucket"] != "due_soon" {
+		t.Fatalf("breach: %v", b)
+	}
+	for _, item := range out["buckets"].([]interface{}) {
+		bucket := item.(map[string]interface{})
+		want := 0.0
+		if bucket["name"] == "due_soon" {
+			want = 1
+		}
+		if bucket["count"] != want {
+			t.Fatalf("bucket %v: count %v, want %v", bucket["name"], bucket["count"], want)
+		}
+	}
+
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	// The printing deadline is 2 business days out, plant then gets 3 more
+	// and the order is due in 10 days.
+	now := time.Now()
+	deadline, _ := time.Parse(time.RFC3339, b["due_at"].(string))
+	expected, _ := time.Parse(time.RFC3339, h.orderDetail(order)["expected_delivery"].(string))
+	steps := []struct {
+		at   time.Time
+		want int
+	}{
+		{now, 1},                               // printing deadline at risk
+		{now.Add(time.Hour), 0},                // already escalated
+		{deadline.Add(time.Hour), 1},           // printing deadline missed
+		{expected.Add(-2 * 24 * time.Hour), 1}, // delivery at risk
+		{expected.Add(time.Hour), 1},           // delivery missed
+	}
+	for _, step := range steps {
+		n, err := h.app.Orders.EscalateSLAService(step.at)
+		if err != nil {
+			t.Fatalf("escalate at %v: %v", step.at, err)
+		}
+		if n != step.want {
+			t.Fatalf("escalate at %v: raised %d, want %d", step.at, n, step.want)
+		}
+	}
+
+	var levels []string
+	for len(levels) < 4 {
+		select {
+		case msg := <-sub.C:
+			if msg.Topic != "order.sla_escalated" {
+				continue
+			}
+			var ev struct {
+				Action  string `json:"action"`
+				SLAKind string `json:"sla_kind"`
+			}
+			if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+				t.Fatal(err)
+			}
+			levels = append(levels, ev.SLAKind+"/"+ev.Action)
+		case <-time.After(2 * time.Second):
+			t.Fatalf("sla events: got %v, want 4", levels)
+		}
+	}
+	want := []string{"assignment_deadline/at_risk", "assignment_deadline/breached", "expected_delivery/at_risk", "expected_delivery/breached"}
+	for i := range want {
+		if levels[i] != want[i] {
+			t.Fatalf("sla events: got %v, want %v", levels, want)
+		}
+	}
+
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/notifications?limit=50", nil)
+	escalations := 0
+	for _, item := range out["notifications"].([]interface{}) {
+		if item.(map[string]interface{})["type"] == "sla_escalation" {
+			escalations++
+		}
+	}
+	if escalations != 4 {
+		t.Fatalf("admin inbox: %d sla escalations, want 4", escalations)
+	}
+}
+
+func TestWorkCalendarAndStalledReschedule(t *testing.T) {
+	h := newHarness(t)
+	order := testdb.OrderReadyForPrinting
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	today := time.Now().In(ist)
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, ist)
+	tomorrow := today.AddDate(0, 0, 1).Format("2006-01-02")
+
+	h.mustDo(http.StatusForbidden, testdb.PrintingID, http.MethodGet, "/admin/holidays", nil)
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPost, "/admin/holidays", gin.H{"date": "tomorrow", "name": "Diwali"})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPost, "/admin/holidays", gin.H{"date": tomorrow, "name": "Diwali"})
+	out := h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/holidays?year="+tomorrow[:4], nil)
+	if holidays, _ := out["holidays"].([]interface{}); len(holidays) != 1 || holidays[0].(map[string]interface{})["date"] != tomorrow {
+		t.Fatalf("holidays: %v", out["holidays"])
+	}
+
+	every := gin.H{"working_days": []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}, "cutoff": "23:59"}
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPut, "/admin/calendars/"+testdb.OwnerID, every)
+	h.mustDo(http.StatusBadRequest, testdb.AdminID, http.MethodPut, "/admin/calendars/"+testdb.PrintingID, gin.H{"working_days": []string{"someday"}, "cutoff": "23:59"})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, "/admin/calendars/"+testdb.PrintingID, every)
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/calendars", nil)
+	calendars, _ := out["calendars"].([]interface{})
+	if len(calendars) != 2 || calendars[0].(map[string]interface{})["calendar_id"] != "default" ||
+		calendars[1].(map[string]interface{})["calendar_id"] != testdb.PrintingID {
+		t.Fatalf("calendars: %v", calendars)
+	}
+
+	// Printing works every day but the holiday, so its 2 days end the day
+	// after tomorrow at the cutoff.
+	h.setStatus(http.StatusOK, testdb.PrintingID, order, "accepted", "")
+	assignments, _ := h.orderDetail(order)["assignments"].([]interface{})
+	if len(assignments) != 1 {
+		t.Fatalf("assignments: %v", assignments)
+	}
+	deadline, _ := time.Parse(time.RFC3339, assignments[0].(map[string]interface{})["deadline"].(string))
+	if want := today.AddDate(0, 0, 3).Add(23*time.Hour + 59*time.Minute); !deadline.Equal(want) {
+		t.Fatalf("deadline %v, want %v", deadline.In(ist), want)
+	}
+
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	expected, _ := time.Parse(time.RFC3339, h.orderDetail(order)["expected_delivery"].(string))
+	if n, err := h.app.Orders.RescheduleStalledService(deadline.Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("reschedule before the deadline: moved %d, err %v", n, err)
+	}
+	// A day before delivery the late printing job plus plant cannot finish.
+	at := expected.Add(-24 * time.Hour)
+	if n, err := h.app.Orders.RescheduleStalledService(at); err != nil || n != 1 {
+		t.Fatalf("reschedule stalled order: moved %d, err %v", n, err)
+	}
+	if n, err := h.app.Orders.RescheduleStalledService(at); err != nil || n != 0 {
+		t.Fatalf("reschedule again: moved %d, err %v", n, err)
+	}
+	moved, _ := time.Parse(time.RFC3339, h.orderDetail(order)["expected_delivery"].(string))
+	if !moved.After(expected) {
+		t.Fatalf("expected delivery %v, want after %v", moved, expected)
+	}
+
+	for {
+		select {
+		case msg := <-sub.C:
+			if msg.Topic != "order.delivery_rescheduled" {
+				continue
+			}
+			var ev struct {
+				ExpectedDelivery         time.Time `json:"expected_delivery"`
+				PreviousExpectedDelivery time.Time `json:"previous_expected_delivery"`
+			}
+			if err := json.Unmarshal(msg.Payload, &ev); err != nil {
+				t.Fatal(err)
+			}
+			if !ev.ExpectedDelivery.Equal(moved) || !ev.PreviousExpectedDelivery.Equal(expected) {
+				t.Fatalf("delivery_rescheduled event: %+v", ev)
+			}
+			return
+		case <-time.After(2 * time.Second):
+			t.Fatal("no delivery_rescheduled event")
+		}
+	}
+}
+
+func TestDeliveryQuoteUsesCapacity(t *testing.T) {
+	h := newHarness(t)
+	quote := func(body gin.H) map[string]interface{} {
+		t.Helper()
+		return h.mustDo(http.StatusOK, testdb.OwnerID, http.MethodPost, "/orders/quote", body)["quote"].(map[string]interface{})
+	}
+
+	h.mustDo(http.StatusBadRequest, testdb.OwnerID, http.MethodPost, "/orders/quote", gin.H{"qty": 0})
+	if q := quote(gin.H{"qty": 500}); q["business_days"] != 10.0 {
+		t.Fatalf("quote without capacity: %v", q)
+	}
+
+	h.mustDo(http.StatusForbidden, testdb.OwnerID, http.MethodPut, "/work/capacity", gin.H{"daily_capacity": 100})
+	h.mustDo(http.StatusBadRequest, testdb.PrintingID, http.MethodPut, "/work/capacity", gin.H{"daily_capacity": -1})
+	h.mustDo(http.StatusOK, testdb.PrintingID, http.MethodPut, "/work/capacity", gin.H{"daily_capacity": 100})
+	h.mustDo(http.StatusOK, testdb.PlantID, http.MethodPut, "/work/capacity", gin.H{"daily_capacity": 400})
+	out := h.mustDo(http.StatusOK, testdb.PlantID, http.MethodGet, "/work/capacity", nil)
+	if c := out["capacity"].(map[string]interface{}); c["daily_capacity"] != 400.0 || c["unit"] != "bottles" {
+		t.Fatalf("plant capacity: %v", c)
+	}
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/capacity", nil)
+	if capacities, _ := out["capacities"].([]interface{}); len(capacities) != 2 {
+		t.Fatalf("capacities: %v", out["capacities"])
+	}
+
+	// Only the verified fixture order is queued: 300 bottles on 30 sheets.
+	cases := []struct {
+		body                gin.H
+		printingDays, total float64
+	}{
+		// 80 sheets print in a day; the plant then needs 2 days for 500 bottles.
+		{gin.H{"qty": 500, "labels_per_sheet": 10}, 1, 5},
+		// 167 sheets at 12 a sheet; the plant is held up by printing, not by its backlog.
+		{gin.H{"qty": 2000}, 2, 9},
+	}
+	for _, tc := range cases {
+		q := quote(tc.body)
+		stages := q["stages"].([]interface{})
+		printing := stages[0].(map[string]interface{})
+		if printing["backlog"] != 30.0 || printing["ready_after_days"] != tc.printingDays || q["business_days"] != tc.total {
+			t.Fatalf("quote %v: %v", tc.body, q)
+		}
+		if plant := stages[1].(map[string]interface{}); plant["backlog"] != 300.0 {
+			t.Fatalf("quote %v: plant stage %v", tc.body, plant)
+		}
+	}
+}
+
+func TestSKUCatalogGatesOrderCreation(t *testing.T) {
+	h := newHarness(t)
+	create := func(want int, body gin.H) map[string]interface{} {
+		t.Helper()
+		body["label_id"] = testdb.OwnerLabelID
+		return h.mustDo(want, testdb.OwnerID, http.MethodPost, "/orders/create", body)
+	}
+
+	h.mustDo(http.StatusForbidden, testdb.OwnerID, http.MethodPost, "/admin/skus", gin.H{"variant": "sport", "volume_ml": 1000, "cap_color": "green"})
+	h.mustDo(http.StatusConflict, testdb.AdminID, http.MethodPost, "/admin/skus", gin.H{"variant": " Classic", "volume_ml": 500, "cap_color": "BLUE"})
+	out := h.mustDo(http.StatusCreated, testdb.AdminID, http.MethodPost, "/admin/skus", gin.H{"variant": "Sport", "volume_ml": 1000, "cap_color": "Green", "moq": 1000})
+	if sku := out["sku"].(map[string]interface{}); sku["sku_id"] != "sport-1000ml-green" || sku["variant"] != "sport" || sku["active"] != true {
+		t.Fatalf("created sku: %v", sku)
+	}
+
+	create(http.StatusBadRequest, gin.H{"sku_id": "sport-1000ml-green", "qty": 500})
+	create(http.StatusBadRequest, gin.H{"sku_id": "sport-1000ml-green", "qty": 1000, "volume": 750})
+	create(http.StatusBadRequest, gin.H{"sku_id": "no-such-sku", "qty": 1000})
+	create(http.StatusBadRequest, gin.H{"variant": "classic", "volume": 750, "cap_color": "blue", "qty": 500})
+	out = create(http.StatusCreated, gin.H{"sku_id": "sport-1000ml-green", "qty": 1000})
+	if order := out["order"].(map[string]interface{}); order["variant"] != "sport" || order["volume"] != 1000.0 || order["cap_color"] != "green" {
+		t.Fatalf("order by sku_id: %v", order)
+	}
+	out = create(http.StatusCreated, gin.H{"variant": "Classic", "volume": 500, "cap_color": "blue", "qty": 500})
+	if order := out["order"].(map[string]interface{}); order["sku_id"] != "classic-500ml-blue" {
+		t.Fatalf("order by combination: %v", order)
+	}
+
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodPut, "/admin/skus/classic-500ml-blue", gin.H{"active": false})
+	create(http.StatusBadRequest, gin.H{"sku_id": "classic-500ml-blue", "qty": 500})
+	out = h.mustDo(http.StatusOK, testdb.OwnerID, http.MethodGet, "/catalog/skus", nil)
+	for _, item := range out["skus"].([]interface{}) {
+		if item.(map[string]interface{})["sku_id"] == "classic-500ml-blue" {
+			t.Fatalf("inactive sku listed in the catalog: %v", out["skus"])
+		}
+	}
+	out = h.mustDo(http.StatusOK, testdb.AdminID, http.MethodGet, "/admin/skus", nil)
+	if skus, _ := out["skus"].([]interface{}); len(skus) != 4 {
+		t.Fatalf("admin sku list: %v", out["skus"])
+	}
+
+	h.mustDo(http.StatusConflict, testdb.AdminID, http.MethodDelete, "/admin/skus/classic-500ml-blue", nil)
+	h.mustDo(http.StatusCreated, testdb.AdminID, http.MethodPost, "/admin/skus", gin.H{"variant": "sport", "volume_ml": 250, "cap_color": "black"})
+	h.mustDo(http.StatusOK, testdb.AdminID, http.MethodDelete, "/admin/skus/sport-250ml-black", nil)
+	h.mustDo(http.StatusNotFound, testdb.AdminID, http.MethodGet, "/admin/skus/sport-250ml-black", nil)
+}
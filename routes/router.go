@@ -2,57 +2,130 @@ package routes
 
 import (
 	"enerzyflow_backend/internal/auth"
-	"enerzyflow_backend/internal/orders"
-	"enerzyflow_backend/internal/users"
+	"enerzyflow_backend/internal/container"
+	"enerzyflow_backend/internal/mailer"
+	"enerzyflow_backend/internal/notifications"
+	"enerzyflow_backend/internal/outbox"
+	"enerzyflow_backend/internal/webhooks"
 	"enerzyflow_backend/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-func RegisterAllRoutes(r *gin.Engine) {
+func RegisterAllRoutes(r *gin.Engine, app *container.Container) {
 	r.GET("/", func(c *gin.Context) {
 		c.String(200, "Backend Running!")
 	})
 
+	r.GET("/.well-known/jwks.json", utils.JWKSHandler)
+
 	enquiryGroup := r.Group("/enquiry")
 	{
-		enquiryGroup.POST("/submit", users.SubmitEnquiryHandler)
+		enquiryGroup.POST("/submit", app.UserHandler.SubmitEnquiryHandler)
 	}
 
 	authGroup := r.Group("/auth")
 	{
 		authGroup.POST("/send-otp", auth.SendOTPHandler)
 		authGroup.POST("/verify-otp", auth.VerifyOTPHandler)
+		authGroup.POST("/refresh", auth.RefreshTokenHandler)
+		authGroup.POST("/logout", utils.AuthMiddleware(), auth.LogoutHandler)
+		authGroup.GET("/sessions", utils.AuthMiddleware(), auth.ListSessionsHandler)
+		authGroup.DELETE("/sessions/:id", utils.AuthMiddleware(), auth.RevokeSessionHandler)
 	}
 
 	userGroup := r.Group("/users", utils.AuthMiddleware())
 	{
-		userGroup.POST("/profile", users.SaveProfileHandler)
-		userGroup.GET("/profile", users.GetProfileHandler)
-		userGroup.GET("/all", utils.RoleMiddleware("admin"),users.GetAllUsersHandler)
-		userGroup.POST("/create",users.CreateUserByAdminHandler)
+		userGroup.POST("/profile", app.UserHandler.SaveProfileHandler)
+		userGroup.GET("/profile", app.UserHandler.GetProfileHandler)
+		userGroup.GET("/all", utils.RoleMiddleware("admin"),app.UserHandler.GetAllUsersHandler)
+		userGroup.POST("/create",app.UserHandler.CreateUserByAdminHandler)
+		userGroup.GET("/notification-preferences", notifications.GetPreferencesHandler)
+		userGroup.PUT("/notification-preferences", notifications.UpdatePreferencesHandler)
 		
 	}
 
 	orderGroup := r.Group("/orders", utils.AuthMiddleware())
 	{
-		orderGroup.POST("/create", orders.CreateOrderHandler)
-		orderGroup.GET("/get-all", orders.GetOrdersHandler)
-		orderGroup.GET("/:id", orders.GetOrderHandler)
-		orderGroup.POST("/:id/payment-screenshot",orders.UploadPaymentScreenshotHandler)
-		orderGroup.PUT("/:id/status", orders.UpdateOrderStatusHandler)
-		orderGroup.PUT("/:id/payment", utils.RoleMiddleware("admin"),orders.UpdatePaymentStatusHandler)
-		orderGroup.GET("/get-all-orders", orders.GetAllOrdersHandler)
-		orderGroup.GET("/:id/tracking", orders.GetOrderTrackingHandler)
-		orderGroup.POST("/:id/upload-invoice", utils.RoleMiddleware("admin"),orders.UploadInvoiceHandler)
-
-		orderGroup.POST("/:id/comment",orders.AddOrderCommentHandler)
-		orderGroup.GET("/:id/comment",orders.GetOrderCommentsHandler)
-
-		orderGroup.POST("/:id/label", utils.RoleMiddleware("admin"),orders.SaveOrderLabelDetailsHandler)
-		orderGroup.GET("/:id/label", orders.GetOrderLabelDetailsHandler)
-
-		orderGroup.GET("/:id/detail",utils.RoleMiddleware("admin"),orders.GetOrderDetailHandler)
+		orderGroup.POST("/create", app.OrderHandler.CreateOrderHandler)
+		orderGroup.POST("/quote", app.OrderHandler.QuoteDeliveryHandler)
+		orderGroup.GET("/get-all", app.OrderHandler.GetOrdersHandler)
+		orderGroup.GET("/stream", app.OrderHandler.StreamOrdersHandler)
+		orderGroup.GET("/:id", app.OrderHandler.GetOrderHandler)
+		orderGroup.POST("/:id/payment-screenshot",app.OrderHandler.UploadPaymentScreenshotHandler)
+		orderGroup.PUT("/:id/status", app.OrderHandler.UpdateOrderStatusHandler)
+		orderGroup.GET("/:id/allowed-transitions", app.OrderHandler.GetAllowedTransitionsHandler)
+		orderGroup.PUT("/:id/payment", utils.RoleMiddleware("admin"),app.OrderHandler.UpdatePaymentStatusHandler)
+		orderGroup.GET("/get-all-orders", app.OrderHandler.GetAllOrdersHandler)
+		orderGroup.GET("/:id/tracking", app.OrderHandler.GetOrderTrackingHandler)
+		orderGroup.POST("/:id/upload-invoice", utils.RoleMiddleware("admin"),app.OrderHandler.UploadInvoiceHandler)
+
+		orderGroup.POST("/:id/comment",app.OrderHandler.AddOrderCommentHandler)
+		orderGroup.GET("/:id/comment",app.OrderHandler.GetOrderCommentsHandler)
+
+		orderGroup.POST("/:id/label", utils.RoleMiddleware("admin"),app.OrderHandler.SaveOrderLabelDetailsHandler)
+		orderGroup.GET("/:id/label", app.OrderHandler.GetOrderLabelDetailsHandler)
+
+		orderGroup.GET("/:id/detail",utils.RoleMiddleware("admin"),app.OrderHandler.GetOrderDetailHandler)
+	}
+
+	catalogGroup := r.Group("/catalog", utils.AuthMiddleware())
+	{
+		catalogGroup.GET("/skus", app.CatalogHandler.ListActiveSKUsHandler)
+	}
+
+	workGroup := r.Group("/work", utils.AuthMiddleware())
+	{
+		workGroup.POST("/claim", app.OrderHandler.ClaimWorkHandler)
+		workGroup.GET("/capacity", app.OrderHandler.GetWorkCapacityHandler)
+		workGroup.PUT("/capacity", app.OrderHandler.SaveWorkCapacityHandler)
+	}
+
+	notificationGroup := r.Group("/notifications", utils.AuthMiddleware())
+	{
+		notificationGroup.GET("", notifications.ListNotificationsHandler)
+		notificationGroup.POST("/read-all", notifications.MarkAllReadHandler)
+		notificationGroup.POST("/:id/read", notifications.MarkReadHandler)
+	}
+
+	adminGroup := r.Group("/admin", utils.AuthMiddleware(), utils.RoleMiddleware("admin"))
+	{
+		adminGroup.GET("/emails", outbox.ListMessagesHandler)
+		adminGroup.GET("/emails/:id", outbox.GetMessageHandler)
+		adminGroup.POST("/emails/:id/resend", outbox.ResendMessageHandler)
+		adminGroup.GET("/email-templates", mailer.ListTemplatesHandler)
+		adminGroup.GET("/email-templates/:name/preview", mailer.PreviewTemplateHandler)
+
+		adminGroup.PUT("/orders/:id/pin", app.OrderHandler.PinOrderHandler)
+		adminGroup.DELETE("/orders/:id/pin/:role", app.OrderHandler.UnpinOrderHandler)
+		adminGroup.GET("/orders/:id/assignments/history", app.OrderHandler.GetAssignmentHistoryHandler)
+		adminGroup.POST("/orders/:id/assignments/:role/reassign", app.OrderHandler.ReassignOrderHandler)
+		adminGroup.POST("/orders/:id/assignments/:role/unassign", app.OrderHandler.UnassignOrderHandler)
+		adminGroup.POST("/orders/:id/assignments/:role/extend", app.OrderHandler.ExtendDeadlineHandler)
+		adminGroup.GET("/sla/breaches", app.OrderHandler.GetSLABreachesHandler)
+		adminGroup.GET("/capacity", app.OrderHandler.ListWorkCapacitiesHandler)
+
+		adminGroup.GET("/skus", app.CatalogHandler.ListSKUsHandler)
+		adminGroup.POST("/skus", app.CatalogHandler.CreateSKUHandler)
+		adminGroup.GET("/skus/:id", app.CatalogHandler.GetSKUHandler)
+		adminGroup.PUT("/skus/:id", app.CatalogHandler.UpdateSKUHandler)
+		adminGroup.DELETE("/skus/:id", app.CatalogHandler.DeleteSKUHandler)
+
+		adminGroup.GET("/holidays", app.CalendarHandler.ListHolidaysHandler)
+		adminGroup.POST("/holidays", app.CalendarHandler.AddHolidayHandler)
+		adminGroup.DELETE("/holidays/:date", app.CalendarHandler.DeleteHolidayHandler)
+		adminGroup.GET("/calendars", app.CalendarHandler.ListCalendarsHandler)
+		adminGroup.PUT("/calendars/:id", app.CalendarHandler.SaveCalendarHandler)
+		adminGroup.DELETE("/calendars/:id", app.CalendarHandler.DeleteCalendarHandler)
+
+		adminGroup.POST("/webhooks", webhooks.CreateSubscriptionHandler)
+		adminGroup.GET("/webhooks", webhooks.ListSubscriptionsHandler)
+		adminGroup.GET("/webhooks/:id", webhooks.GetSubscriptionHandler)
+		adminGroup.PUT("/webhooks/:id", webhooks.UpdateSubscriptionHandler)
+		adminGroup.DELETE("/webhooks/:id", webhooks.DeleteSubscriptionHandler)
+		adminGroup.GET("/webhooks/:id/deliveries", webhooks.ListDeliveriesHandler)
+		adminGroup.GET("/webhook-deliveries/:id", webhooks.GetDeliveryHandler)
+		adminGroup.POST("/webhook-deliveries/:id/redeliver", webhooks.RedeliverHandler)
 	}
 }
 
@@ -43,7 +43,10 @@ func UploadFileToCloud(file *multipart.FileHeader, folder, publicID string) (str
 	return uploadResult.SecureURL, nil
 }
 
+// IST is India Standard Time, the zone deadlines and business days are
+// counted in.
+var IST = time.FixedZone("IST", 5*60*60+30*60)
+
 func NowInIST() time.Time {
-	ist := time.FixedZone("IST", 5*60*60+30*60)
-	return time.Now().In(ist)
+	return time.Now().In(IST)
 }
\ No newline at end of file
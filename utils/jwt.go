@@ -3,6 +3,7 @@ package utils
 import (
 	"errors"
 	"net/http"
+	"os"
 	"strings"
 	"time"
 
@@ -11,112 +12,189 @@ import (
 	"github.com/google/uuid"
 )
 
-const Secretkey = "totallsecretkeylol"
-
-func GenerateTokens(email string, userid uuid.UUID, role string) (string, error) {
-    accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "Email":  email,
-        "userid": userid.String(),
-        "role":   role,
-        "exp":    time.Now().Add(24 * time.Hour).Unix(),
-    })
-    access, err := accessToken.SignedString([]byte(Secretkey))
-    if err != nil {
-        return "",  err
-    }
-
-    return access, nil
+const defaultAccessTokenTTL = 15 * time.Minute
+
+// AccessTokenTTL is the lifetime of access tokens, overridable with
+// ACCESS_TOKEN_TTL (e.g. "10m"). Clients renew them via /auth/refresh.
+func AccessTokenTTL() time.Duration {
+	if v := os.Getenv("ACCESS_TOKEN_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultAccessTokenTTL
+}
+
+// sessionValidator reports whether the session an access token was issued
+// for is still active. It is registered by the auth package at startup.
+var sessionValidator func(sessionID string) (bool, error)
+
+func SetSessionValidator(fn func(sessionID string) (bool, error)) {
+	sessionValidator = fn
+}
+
+func GenerateTokens(email string, userid uuid.UUID, role string, sessionID string) (string, error) {
+	if keySet == nil {
+		return "", errors.New("signing keys not configured")
+	}
+	key, err := keySet.Active()
+	if err != nil {
+		return "", err
+	}
+
+	accessToken := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+		"iss":    tokenIssuer(),
+		"Email":  email,
+		"userid": userid.String(),
+		"role":   role,
+		"sid":    sessionID,
+		"exp":    time.Now().Add(AccessTokenTTL()).Unix(),
+	})
+	accessToken.Header["kid"] = key.KID
+	access, err := accessToken.SignedString(key.signKey)
+	if err != nil {
+		return "", err
+	}
+
+	return access, nil
+}
+
+func tokenIssuer() string {
+	if v := os.Getenv("JWT_ISSUER"); v != "" {
+		return v
+	}
+	return "enerzyflow"
+}
+
+// lookupVerifyKey resolves the key from the token's kid header and rejects
+// tokens whose algorithm does not match the key they claim to be signed with.
+// Tokens without a kid are checked against the "default" key.
+func lookupVerifyKey(token *jwt.Token) (interface{}, error) {
+	if keySet == nil {
+		return nil, errors.New("signing keys not configured")
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = "default"
+	}
+	key, ok := keySet.Lookup(kid)
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+	return key.verifyKey, nil
 }
 
 func VerifyToken(token string) (error, uuid.UUID, bool, string) {
-    tokenparsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-        if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-            return nil, errors.New("unexpected signing method")
-        }
-        return []byte(Secretkey), nil
-    }, jwt.WithLeeway(5*time.Second), jwt.WithValidMethods([]string{"HS256"}))
-
-    if err != nil {
-        if errors.Is(err, jwt.ErrTokenExpired) {
-            return nil, uuid.Nil,  true, ""
-        }
-        return err, uuid.Nil,  false, ""
-    }
-
-    claims, ok := tokenparsed.Claims.(jwt.MapClaims)
-    if !ok || !tokenparsed.Valid {
-        return errors.New("invalid token claims"), uuid.Nil,  false, ""
-    }
-
-    useridStr, ok := claims["userid"].(string)
-    if !ok {
-        return errors.New("userid not found or invalid"), uuid.Nil,  false, ""
-    }
-    userid, err := uuid.Parse(useridStr)
-    if err != nil {
-        return errors.New("invalid userid format"), uuid.Nil,  false, ""
-    }
-
-    // // roleidStr, ok := claims["roleid"].(string)
-    // if !ok {
-    //     return errors.New("roleid not found or invalid"), uuid.Nil,false, ""
-    // }
-    // // roleid, err := uuid.Parse(roleidStr)
-    // if err != nil {
-    //     return errors.New("invalid roleid format"), uuid.Nil,  false, ""
-    // }
-
-    role, ok := claims["role"].(string)
-    if !ok {
-        return errors.New("role not found in token"), uuid.Nil, false, ""
-    }
-
-    expRaw, ok := claims["exp"].(float64)
-    if !ok {
-        return errors.New("invalid exp in token"), uuid.Nil, false, ""
-    }
-    expired := time.Now().Unix() > int64(expRaw)
-
-    return nil, userid, expired, role
+	err, userid, expired, role, _ := verifyTokenWithSession(token)
+	return err, userid, expired, role
+}
+
+func verifyTokenWithSession(token string) (error, uuid.UUID, bool, string, string) {
+	tokenparsed, err := jwt.Parse(token, lookupVerifyKey, jwt.WithLeeway(5*time.Second),
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}), jwt.WithIssuer(tokenIssuer()))
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, uuid.Nil, true, "", ""
+		}
+		return err, uuid.Nil, false, "", ""
+	}
+
+	claims, ok := tokenparsed.Claims.(jwt.MapClaims)
+	if !ok || !tokenparsed.Valid {
+		return errors.New("invalid token claims"), uuid.Nil, false, "", ""
+	}
+
+	useridStr, ok := claims["userid"].(string)
+	if !ok {
+		return errors.New("userid not found or invalid"), uuid.Nil, false, "", ""
+	}
+	userid, err := uuid.Parse(useridStr)
+	if err != nil {
+		return errors.New("invalid userid format"), uuid.Nil, false, "", ""
+	}
+
+	// // roleidStr, ok := claims["roleid"].(string)
+	// if !ok {
+	//     return errors.New("roleid not found or invalid"), uuid.Nil,false, ""
+	// }
+	// // roleid, err := uuid.Parse(roleidStr)
+	// if err != nil {
+	//     return errors.New("invalid roleid format"), uuid.Nil,  false, ""
+	// }
+
+	role, ok := claims["role"].(string)
+	if !ok {
+		return errors.New("role not found in token"), uuid.Nil, false, "", ""
+	}
+
+	sessionID, _ := claims["sid"].(string)
+
+	expRaw, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("invalid exp in token"), uuid.Nil, false, "", ""
+	}
+	expired := time.Now().Unix() > int64(expRaw)
+
+	return nil, userid, expired, role, sessionID
 }
 
 func AuthMiddleware() gin.HandlerFunc {
-    return func(c *gin.Context) {
-        authHeader := c.GetHeader("Authorization")
-        if authHeader == "" {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
-            return
-        }
-
-        tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-
-        err, userID, expired, role := VerifyToken(tokenStr)
-        // _ = roleId
-        if err != nil {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
-            return
-        }
-        if expired {
-            c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token expired"})
-            return
-        }
-        c.Set("role", role)
-        c.Set("user_id", userID)
-        c.Next()
-    }
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header missing"})
+			return
+		}
+
+		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+		err, userID, expired, role, sessionID := verifyTokenWithSession(tokenStr)
+		// _ = roleId
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
+			return
+		}
+		if expired {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Access token expired"})
+			return
+		}
+		if sessionValidator != nil {
+			if sessionID == "" {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid access token"})
+				return
+			}
+			active, err := sessionValidator(sessionID)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to validate session"})
+				return
+			}
+			if !active {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session revoked"})
+				return
+			}
+		}
+		c.Set("role", role)
+		c.Set("user_id", userID)
+		c.Set("session_id", sessionID)
+		c.Next()
+	}
 }
 
 func ExtractClaimsWithoutValidation(tokenStr string) (jwt.MapClaims, error) {
-    parsedToken, _, err := new(jwt.Parser).ParseUnverified(tokenStr, jwt.MapClaims{})
-    if err != nil {
-        return nil, err
-    }
-
-    claims, ok := parsedToken.Claims.(jwt.MapClaims)
-    if !ok {
-        return nil, errors.New("invalid token claims")
-    }
-    return claims, nil
+	parsedToken, _, err := new(jwt.Parser).ParseUnverified(tokenStr, jwt.MapClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	return claims, nil
 }
 
 func RoleMiddleware(requiredRole string) gin.HandlerFunc {
@@ -140,4 +218,4 @@ func RoleMiddleware(requiredRole string) gin.HandlerFunc {
 
 		c.Next()
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// SigningKey is one entry of the key set. Retired keys may carry only a
+// public key; they still verify tokens but are never used for signing.
+type SigningKey struct {
+	KID       string
+	Method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+type KeySet struct {
+	activeKID string
+	keys      map[string]*SigningKey
+}
+
+var keySet *KeySet
+
+func SetKeySet(ks *KeySet) {
+	keySet = ks
+}
+
+func (ks *KeySet) Active() (*SigningKey, error) {
+	k, ok := ks.keys[ks.activeKID]
+	if !ok || k.signKey == nil {
+		return nil, fmt.Errorf("no signing key for kid %q", ks.activeKID)
+	}
+	return k, nil
+}
+
+func (ks *KeySet) Lookup(kid string) (*SigningKey, bool) {
+	k, ok := ks.keys[kid]
+	return k, ok
+}
+
+// LoadKeySetFromEnv builds the key set from configuration.
+//
+// JWT_KEYS is a comma-separated list of kid:ALG:path entries, where ALG is
+// HS256, RS256 or EdDSA and path points to the shared secret (HS256) or a
+// PEM-encoded private or public key. JWT_ACTIVE_KID selects the signing key
+// and defaults to the first entry. When JWT_KEYS is empty, JWT_SECRET is used
+// as a single HS256 key with kid "default".
+func LoadKeySetFromEnv() (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*SigningKey)}
+
+	spec := strings.TrimSpace(os.Getenv("JWT_KEYS"))
+	if spec == "" {
+		secret := os.Getenv("JWT_SECRET")
+		if secret == "" {
+			return nil, errors.New("JWT_KEYS or JWT_SECRET must be set")
+		}
+		ks.keys["default"] = &SigningKey{KID: "default", Method: jwt.SigningMethodHS256, signKey: []byte(secret), verifyKey: []byte(secret)}
+		ks.activeKID = "default"
+		return ks, nil
+	}
+
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 3)
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("invalid JWT_KEYS entry %q, expected kid:ALG:path", entry)
+		}
+		kid, alg, path := parts[0], parts[1], parts[2]
+		if _, dup := ks.keys[kid]; dup {
+			return nil, fmt.Errorf("duplicate kid %q in JWT_KEYS", kid)
+		}
+
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read key %q: %w", kid, err)
+		}
+		key, err := parseSigningKey(kid, alg, raw)
+		if err != nil {
+			return nil, err
+		}
+		ks.keys[kid] = key
+		if ks.activeKID == "" {
+			ks.activeKID = kid
+		}
+	}
+
+	if kid := os.Getenv("JWT_ACTIVE_KID"); kid != "" {
+		ks.activeKID = kid
+	}
+	if _, err := ks.Active(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func parseSigningKey(kid, alg string, raw []byte) (*SigningKey, error) {
+	switch alg {
+	case "HS256":
+		secret := []byte(strings.TrimSpace(string(raw)))
+		if len(secret) == 0 {
+			return nil, fmt.Errorf("empty secret for kid %q", kid)
+		}
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodHS256, signKey: secret, verifyKey: secret}, nil
+
+	case "RS256":
+		if priv, err := jwt.ParseRSAPrivateKeyFromPEM(raw); err == nil {
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, signKey: priv, verifyKey: &priv.PublicKey}, nil
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse RS256 key %q: %w", kid, err)
+		}
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodRS256, verifyKey: pub}, nil
+
+	case "EdDSA":
+		if priv, err := jwt.ParseEdPrivateKeyFromPEM(raw); err == nil {
+			edPriv := priv.(ed25519.PrivateKey)
+			return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, signKey: edPriv, verifyKey: edPriv.Public()}, nil
+		}
+		pub, err := jwt.ParseEdPublicKeyFromPEM(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse EdDSA key %q: %w", kid, err)
+		}
+		return &SigningKey{KID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: pub}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q for kid %q", alg, kid)
+	}
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// PublicJWKs returns the asymmetric keys in JWK form. HS256 secrets are never
+// published.
+func (ks *KeySet) PublicJWKs() []jwk {
+	out := []jwk{}
+	for kid, k := range ks.keys {
+		switch pub := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			out = append(out, jwk{
+				Kty: "RSA", Kid: kid, Use: "sig", Alg: "RS256",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out = append(out, jwk{
+				Kty: "OKP", Kid: kid, Use: "sig", Alg: "EdDSA", Crv: "Ed25519",
+				X: base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return out
+}
+
+func JWKSHandler(c *gin.Context) {
+	if keySet == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "signing keys not configured"})
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, gin.H{"keys": keySet.PublicJWKs()})
+}